@@ -2,8 +2,13 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/google/go-querystring/query"
+
+	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/sealed"
 )
 
 type Options struct {
@@ -18,6 +23,23 @@ func BuildURL(baseUrl string, opts Options) string {
 	return fmt.Sprintf("%v?%v", baseUrl, v.Encode())
 }
 
+// BuildSealedURL returns a hook URL with the options sealed with the
+// first of `keys`, as in `CHATHOOKS_HOOK_KEYS`.
+func BuildSealedURL(baseUrl string, opts Options, keys []string) (string, error) {
+	ring, err := sealed.NewKeyring(keys)
+	if err != nil {
+		return "", err
+	}
+	id, err := ring.Seal(sealed.Hook{
+		InputType: opts.InputType,
+		Outputs:   []models.Output{{Type: opts.OutputType, URL: opts.URL}},
+		Token:     opts.Token})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(baseUrl, "/") + "/e/" + id, nil
+}
+
 func main() {
 	baseUrl := "https://12345678.ngrok.io/hook"
 	opts := Options{
@@ -26,5 +48,14 @@ func main() {
 		URL:        "https://hooks.glip.com/webhook/11112222-3333-4444-5555-666677778888",
 		Token:      "deadbeefdeadbeefdeadbeefdeadbeef",
 	}
-	fmt.Println(BuildURL(baseUrl, opts))
+	keys := os.Getenv("CHATHOOKS_HOOK_KEYS")
+	if len(strings.TrimSpace(keys)) == 0 {
+		fmt.Println(BuildURL(baseUrl, opts))
+		return
+	}
+	sealedURL, err := BuildSealedURL(baseUrl, opts, strings.Split(keys, ","))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(sealedURL)
 }
@@ -7,13 +7,26 @@ import (
 	"regexp"
 
 	"github.com/grokify/simplego/os/osutil"
+
+	"github.com/grokify/chathooks/pkg/handlers"
+	_ "github.com/grokify/chathooks/pkg/handlers/all"
 )
 
 const (
 	HandlersDir = "github.com/grokify/chathooks/docs/handlers"
-	Examples    = "aha,appsignal,apteligent,circleci,codeship,confluence,datadog,deskdotcom,enchant,gosquared,heroku,librato,magnumci,marketo,opsgenie,papertrail,pingdom,raygun,runscope,semaphore,statuspage,travisci,userlike,victorops"
 )
 
+// Examples returns the keys of registered handlers with examples.
+func Examples() []string {
+	keys := []string{}
+	for _, info := range handlers.Registered() {
+		if info.ExampleMessage != nil {
+			keys = append(keys, info.Key)
+		}
+	}
+	return keys
+}
+
 func AbsDirGopath(dir string) string {
 	return filepath.Join(os.Getenv("GOPATH"), "src", dir)
 }
@@ -12,7 +12,6 @@ import (
 	"github.com/jessevdk/go-flags"
 
 	"github.com/grokify/chathooks/pkg/config"
-	"github.com/grokify/chathooks/pkg/util"
 	cc "github.com/grokify/commonchat"
 	ccglip "github.com/grokify/commonchat/glip"
 	ccslack "github.com/grokify/commonchat/slack"
@@ -21,34 +20,8 @@ import (
 
 	"github.com/grokify/chathooks/examples"
 
-	"github.com/grokify/chathooks/pkg/handlers/aha"
-	"github.com/grokify/chathooks/pkg/handlers/appsignal"
-	"github.com/grokify/chathooks/pkg/handlers/apteligent"
-	"github.com/grokify/chathooks/pkg/handlers/bugsnag"
-	"github.com/grokify/chathooks/pkg/handlers/circleci"
-	"github.com/grokify/chathooks/pkg/handlers/codeship"
-	"github.com/grokify/chathooks/pkg/handlers/confluence"
-	"github.com/grokify/chathooks/pkg/handlers/datadog"
-	"github.com/grokify/chathooks/pkg/handlers/deskdotcom"
-	"github.com/grokify/chathooks/pkg/handlers/enchant"
-	"github.com/grokify/chathooks/pkg/handlers/gosquared"
-	"github.com/grokify/chathooks/pkg/handlers/gosquared2"
-	"github.com/grokify/chathooks/pkg/handlers/heroku"
-	"github.com/grokify/chathooks/pkg/handlers/librato"
-	"github.com/grokify/chathooks/pkg/handlers/magnumci"
-	"github.com/grokify/chathooks/pkg/handlers/marketo"
-	"github.com/grokify/chathooks/pkg/handlers/opsgenie"
-	"github.com/grokify/chathooks/pkg/handlers/papertrail"
-	"github.com/grokify/chathooks/pkg/handlers/pingdom"
-	"github.com/grokify/chathooks/pkg/handlers/raygun"
-	"github.com/grokify/chathooks/pkg/handlers/runscope"
-	"github.com/grokify/chathooks/pkg/handlers/semaphore"
-	"github.com/grokify/chathooks/pkg/handlers/slack"
-	"github.com/grokify/chathooks/pkg/handlers/statuspage"
-	"github.com/grokify/chathooks/pkg/handlers/travisci"
-	"github.com/grokify/chathooks/pkg/handlers/userlike"
-	"github.com/grokify/chathooks/pkg/handlers/victorops"
-	"github.com/grokify/chathooks/pkg/handlers/wootric"
+	"github.com/grokify/chathooks/pkg/handlers"
+	_ "github.com/grokify/chathooks/pkg/handlers/all"
 )
 
 type cliOptions struct {
@@ -149,129 +122,22 @@ func SendMessageAdapterHandler(cfg config.Configuration, opts cliOptions) error
 		return errors.New("Invalid Adapter")
 	}
 
-	exampleData, err := util.NewExampleData()
-	if err != nil {
-		return errs.Wrap(err, fmt.Sprintf("Invalid Example Data: %v\n", err))
+	info, ok := handlers.LookupHandlerInfo(service)
+	if !ok || info.ExampleMessage == nil {
+		return fmt.Errorf("Unknown webhook source [%s]", service)
 	}
+	exampleData := handlers.NewExampleData()
 	fmtutil.PrintJSON(exampleData)
 
-	switch service {
-	case "aha":
-		source := exampleData.Data[aha.HandlerKey]
-		for _, eventSlug := range source.EventSlugs {
-			sender.SendCcMessage(aha.ExampleMessage(cfg, exampleData, eventSlug))
-		}
-	case "appsignal":
-		source := exampleData.Data[appsignal.HandlerKey]
-		for _, eventSlug := range source.EventSlugs {
-			sender.SendCcMessage(appsignal.ExampleMessage(cfg, exampleData, eventSlug))
-		}
-	case "apteligent":
-		source := exampleData.Data[apteligent.HandlerKey]
-		for _, eventSlug := range source.EventSlugs {
-			sender.SendCcMessage(apteligent.ExampleMessage(cfg, exampleData, eventSlug))
-		}
-	case "bugsnag":
-		//sender.SendCcMessage(bugsnag.ExampleMessage(cfg, exampleData))
-		source := exampleData.Data[bugsnag.HandlerKey]
-		for _, eventSlug := range source.EventSlugs {
-			sender.SendCcMessage(bugsnag.ExampleMessage(cfg, exampleData, eventSlug))
-		}
-	case "circleci":
-		sender.SendCcMessage(circleci.ExampleMessage(cfg, exampleData))
-	case "codeship":
-		sender.SendCcMessage(codeship.ExampleMessage(cfg, exampleData))
-	case "confluence":
-		source := exampleData.Data[confluence.HandlerKey]
-		for _, eventSlug := range source.EventSlugs {
-			sender.SendCcMessage(confluence.ExampleMessage(cfg, exampleData, eventSlug))
-		}
-	case "datadog":
-		sender.SendCcMessage(datadog.ExampleMessage(cfg, exampleData))
-	case "deskdotcom":
-		source := exampleData.Data[deskdotcom.HandlerKey]
-		for _, eventSlug := range source.EventSlugs {
-			sender.SendCcMessage(deskdotcom.ExampleMessage(cfg, exampleData, eventSlug))
-		}
-	case "enchant":
-		sender.SendCcMessage(enchant.ExampleMessage(cfg, exampleData))
-	case "gosquared":
-		source := exampleData.Data[gosquared.HandlerKey]
-		for _, eventSlug := range source.EventSlugs {
-			sender.SendCcMessage(gosquared.ExampleMessage(cfg, exampleData, eventSlug))
-		}
-	case "gosquared2":
-		source := exampleData.Data[gosquared.HandlerKey]
-		for _, eventSlug := range source.EventSlugs {
-			sender.SendCcMessage(gosquared2.ExampleMessage(cfg, exampleData, eventSlug))
-		}
-	case "heroku":
-		sender.SendCcMessage(heroku.ExampleMessage(cfg, exampleData))
-	case "librato":
-		source := exampleData.Data[librato.HandlerKey]
-		for _, eventSlug := range source.EventSlugs {
-			sender.SendCcMessage(librato.ExampleMessage(cfg, exampleData, eventSlug))
-		}
-	case "magnumci":
-		sender.SendCcMessage(magnumci.ExampleMessage(cfg, exampleData))
-	case "marketo":
-		source := exampleData.Data[marketo.HandlerKey]
-		for _, eventSlug := range source.EventSlugs {
-			sender.SendCcMessage(marketo.ExampleMessage(cfg, exampleData, eventSlug))
-		}
-	case "opsgenie":
-		source := exampleData.Data[opsgenie.HandlerKey]
-		for i, eventSlug := range source.EventSlugs {
-			sender.SendCcMessage(opsgenie.ExampleMessage(cfg, exampleData, eventSlug))
-			if i == 8 {
-				time.Sleep(2000 * time.Millisecond)
-			}
-		}
-	case "papertrail":
-		source := exampleData.Data[papertrail.HandlerKey]
-		for _, eventSlug := range source.EventSlugs {
-			sender.SendCcMessage(papertrail.ExampleMessage(cfg, exampleData, eventSlug))
-		}
-	case "pingdom":
-		source := exampleData.Data[pingdom.HandlerKey]
-		for _, eventSlug := range source.EventSlugs {
-			sender.SendCcMessage(pingdom.ExampleMessage(cfg, exampleData, eventSlug))
-		}
-	case "raygun":
-		sender.SendCcMessage(raygun.ExampleMessage(cfg, exampleData))
-	case "runscope":
-		sender.SendCcMessage(runscope.ExampleMessage(cfg, exampleData))
-	case "semaphore":
-		source := exampleData.Data[semaphore.HandlerKey]
-		for _, eventSlug := range source.EventSlugs {
-			sender.SendCcMessage(semaphore.ExampleMessage(cfg, exampleData, eventSlug))
-		}
-	case "slack":
-		source := exampleData.Data[slack.HandlerKey]
-		for _, eventSlug := range source.EventSlugs {
-			sender.SendCcMessage(slack.ExampleMessage(cfg, exampleData, eventSlug))
-		}
-	case "statuspage":
-		source := exampleData.Data[statuspage.HandlerKey]
-		for _, eventSlug := range source.EventSlugs {
-			sender.SendCcMessage(statuspage.ExampleMessage(cfg, exampleData, eventSlug))
-		}
-	case "travisci":
-		sender.SendCcMessage(travisci.ExampleMessage(cfg, exampleData))
-	case "userlike":
-		source := exampleData.Data[userlike.HandlerKey]
-		for _, eventSlug := range source.EventSlugs {
-			sender.SendCcMessage(userlike.ExampleMessage(cfg, exampleData, eventSlug))
-		}
-	case "victorops":
-		sender.SendCcMessage(victorops.ExampleMessage(cfg, exampleData))
-	case "wootric":
-		source := exampleData.Data[wootric.HandlerKey]
-		for _, eventSlug := range source.EventSlugs {
-			sender.SendCcMessage(wootric.ExampleMessage(cfg, exampleData, eventSlug))
+	if len(info.ExampleSlugs) == 0 {
+		sender.SendCcMessage(info.ExampleMessage(cfg, exampleData, ""))
+	}
+	for i, eventSlug := range info.ExampleSlugs {
+		// Pause between batches to stay under webhook rate limits.
+		if i > 0 && i%8 == 0 {
+			time.Sleep(2000 * time.Millisecond)
 		}
-	default:
-		return errors.New(fmt.Sprintf("Unknown webhook source [%s]\n", service))
+		sender.SendCcMessage(info.ExampleMessage(cfg, exampleData, eventSlug))
 	}
 	return nil
 }
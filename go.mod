@@ -1,11 +1,9 @@
 module github.com/grokify/chathooks
 
-// +heroku goVersion go1.15
-go 1.15
+// +heroku goVersion go1.23
+go 1.23.0
 
 require (
-	github.com/andybalholm/brotli v1.0.3 // indirect
-	github.com/apex/gateway v1.1.2
 	github.com/aws/aws-lambda-go v1.24.0
 	github.com/buaazp/fasthttprouter v0.1.2-0.20190109152524-979d6e516ec3
 	github.com/caarlos0/env v3.5.0+incompatible
@@ -14,15 +12,53 @@ require (
 	github.com/grokify/simplego v0.27.1
 	github.com/jessevdk/go-flags v1.5.0
 	github.com/joho/godotenv v1.3.1-0.20190204044109-5c0e6c6ab1a0
-	github.com/klauspost/compress v1.13.0 // indirect
 	github.com/microcosm-cc/bluemonday v1.0.9
 	github.com/pkg/errors v0.9.1
 	github.com/rs/zerolog v1.22.0
 	github.com/tidwall/gjson v1.8.0
-	github.com/tidwall/pretty v1.1.1 // indirect
-	github.com/valyala/bytebufferpool v1.0.1-0.20180905182247-cdfbe9377474 // indirect
 	github.com/valyala/fasthttp v1.26.0
 	github.com/valyala/quicktemplate v1.6.3
-	golang.org/x/net v0.0.0-20210525063256-abc453219eb5 // indirect
-	golang.org/x/sys v0.0.0-20210603125802-9665404d3644 // indirect
+	go.opentelemetry.io/otel v1.34.0
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace v1.34.0
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp v1.34.0
+	go.opentelemetry.io/otel/exporters/stdout/stdouttrace v1.34.0
+	go.opentelemetry.io/otel/sdk v1.34.0
+	go.opentelemetry.io/otel/trace v1.34.0
+	go.opentelemetry.io/proto/otlp v1.5.0
+	golang.org/x/oauth2 v0.30.0
+	google.golang.org/protobuf v1.36.6
+)
+
+require (
+	github.com/andybalholm/brotli v1.0.3 // indirect
+	github.com/apex/gateway v1.1.2 // indirect
+	github.com/aymerick/douceur v0.2.0 // indirect
+	github.com/buger/jsonparser v1.1.1 // indirect
+	github.com/cenkalti/backoff/v4 v4.3.0 // indirect
+	github.com/derekstavis/go-qs v0.0.0-20180720192143-9eef69e6c4e7 // indirect
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/golang/protobuf v1.5.4 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/gorilla/css v1.0.0 // indirect
+	github.com/gorilla/mux v1.8.0 // indirect
+	github.com/grokify/go-glip v0.1.0 // indirect
+	github.com/grpc-ecosystem/grpc-gateway/v2 v2.25.1 // indirect
+	github.com/huandu/xstrings v1.3.2 // indirect
+	github.com/json-iterator/go v1.1.11 // indirect
+	github.com/klauspost/compress v1.13.0 // indirect
+	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
+	github.com/modern-go/reflect2 v1.0.1 // indirect
+	github.com/tidwall/match v1.0.3 // indirect
+	github.com/tidwall/pretty v1.1.1 // indirect
+	github.com/valyala/bytebufferpool v1.0.1-0.20180905182247-cdfbe9377474 // indirect
+	github.com/valyala/fastjson v1.6.3 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	go.opentelemetry.io/otel/metric v1.34.0 // indirect
+	golang.org/x/net v0.40.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+	golang.org/x/text v0.25.0 // indirect
+	google.golang.org/genproto/googleapis/api v0.0.0-20250528174236-200df99c418a // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250528174236-200df99c418a // indirect
+	google.golang.org/grpc v1.72.1 // indirect
 )
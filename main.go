@@ -4,10 +4,11 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
-	"github.com/grokify/simplego/config"
 	"github.com/grokify/simplego/net/http/httpsimple"
 
+	"github.com/grokify/chathooks/pkg/config"
 	"github.com/grokify/chathooks/pkg/service"
 )
 
@@ -23,13 +24,25 @@ tokens as a comma delimited string.
 func portAddress(port int) string { return ":" + strconv.Itoa(port) }
 
 func main() {
-	if err := config.LoadDotEnvSkipEmpty(
-		os.Getenv("ENV_PATH"), "./.env"); err != nil {
+	envFiles := config.NewEnvFiles(os.Getenv("ENV_PATH"), "./.env")
+	if err := envFiles.Load(); err != nil {
 		panic(err)
 	}
 
-	svc := service.NewService()
+	svc := service.NewService(envFiles)
+	go svc.Watch(nil)
 	fmt.Printf("Starting on port [%d] with engine [%s].\n",
 		svc.PortInt(), svc.HttpEngine())
-	httpsimple.Serve(svc)
+	switch strings.ToLower(strings.TrimSpace(svc.HttpEngine())) {
+	case httpsimple.EngineAwsLambda:
+		service.ServeAwsLambda(svc)
+	case service.EngineGCF:
+		service.ServeGCF(svc)
+	case service.EngineAzureFunctions:
+		service.ServeAzureFunctions(svc)
+	case httpsimple.EngineFastHttp:
+		service.ServeFastHttp(svc)
+	default:
+		service.ServeNetHttp(svc)
+	}
 }
@@ -1,50 +1,228 @@
 package adapters
 
 import (
+	"context"
+	"net/http"
+
 	cc "github.com/grokify/commonchat"
 	"github.com/rs/zerolog/log"
 	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/ratelimit"
+	"github.com/grokify/chathooks/pkg/redact"
+	"github.com/grokify/chathooks/pkg/state"
+	"github.com/grokify/chathooks/pkg/tracing"
 )
 
 var (
 	ShowDisplayName = false
+
+	rateLimitedInfo = models.NewStatusInfo(models.StatusRateLimited, []byte("E_OUTPUT_RATE_LIMITED"))
 )
 
+// AdapterSet sends messages to chat services. Output names refer to
+// `Destinations` or, failing that, to `Adapters`. `OutputLimits` rate
+// limits each output by webhook URL or name. With `Threads`, messages
+// with a correlation ID are threaded on adapters that implement
+// `Threader`. With `Outbound`, output URLs are checked before sending.
 type AdapterSet struct {
-	Adapters map[string]cc.Adapter
+	Adapters     map[string]cc.Adapter
+	Destinations *DestinationSet
+	OutputLimits *ratelimit.OutputLimits
+	Limiter      *ratelimit.Limiter
+	Threads      state.Store
+	Outbound     *OutboundPolicy
+}
+
+// ContextSender is implemented by adapters that send the trace
+// context of `ctx` with their requests. An empty `target` sends to the
+// adapter default.
+type ContextSender interface {
+	SendWebhookContext(ctx context.Context, target string, ccMsg cc.Message) (*fasthttp.Request, *fasthttp.Response, error)
 }
 
 func NewAdapterSet() AdapterSet {
-	return AdapterSet{Adapters: map[string]cc.Adapter{}}
+	return AdapterSet{
+		Adapters:     map[string]cc.Adapter{},
+		Destinations: NewDestinationSet(),
+		Limiter:      ratelimit.NewLimiter(nil)}
 }
 
+// SendWebhooks sends the canonical message of `hookData` to its
+// outputs. It is traced as a child of `hookData.Context`, with a
+// client span per output sent to.
 func (set *AdapterSet) SendWebhooks(hookData models.HookData) []models.ErrorInfo {
+	ctx, span := tracing.Start(hookData.Context, "AdapterSet.SendWebhooks",
+		trace.WithAttributes(tracing.AttrInputType.String(hookData.InputType)))
+	defer span.End()
+	errs := set.sendWebhooks(ctx, hookData)
+	tracing.SetStatusCode(span, models.GetMaxStatusCode(errs...), 500)
+	return errs
+}
+
+func (set *AdapterSet) sendWebhooks(ctx context.Context, hookData models.HookData) []models.ErrorInfo {
 	errs := []models.ErrorInfo{}
 	if len(hookData.OutputType) > 0 && len(hookData.OutputURL) > 0 {
 		if adapter, ok := set.Adapters[hookData.OutputType]; ok {
-			var msg interface{}
-			req, res, err := adapter.SendWebhook(hookData.OutputURL, hookData.CanonicalMessage, &msg)
-			log.Debug().
-				Str("output_type", hookData.OutputType).
-				Int("status_code", res.StatusCode()).
-				Str("output_url", hookData.OutputURL).
-				Str("body", string(res.Body())).
-				Msg("ADAPTER_API_REQ_RES_INFO")
-			errs = set.procResponse(errs, req, res, err)
+			output := models.Output{Type: hookData.OutputType, URL: hookData.OutputURL}
+			if set.allow(hookData.OutputURL, output) {
+				errs = set.send(ctx, errs, adapter, output, hookData.CanonicalMessage, hookData.CorrelationID)
+			} else {
+				errs = append(errs, rateLimitedInfo)
+			}
+		}
+	}
+	for _, name := range hookData.OutputNames {
+		output := models.Output{Name: name}
+		if _, ok := set.Destinations.Get(name); !ok {
+			if _, ok := set.Adapters[name]; !ok {
+				continue
+			}
+		}
+		if set.allow(name, output) {
+			errs = set.sendOutput(ctx, errs, output, hookData.CanonicalMessage, hookData.CorrelationID)
+		} else {
+			errs = append(errs, rateLimitedInfo)
+		}
+	}
+	for _, output := range hookData.Outputs {
+		if !set.allow(outputKey(output), output) {
+			errs = append(errs, rateLimitedInfo)
+			continue
+		}
+		errs = set.sendOutput(ctx, errs, output, hookData.CanonicalMessage, hookData.CorrelationID)
+	}
+	return errs
+}
+
+// outputKey is the rate limit key for an output.
+func outputKey(output models.Output) string {
+	if len(output.Name) > 0 {
+		return output.Name
+	}
+	return outputTarget(output)
+}
+
+// allow takes a rate limit token for an output, if it is limited.
+func (set *AdapterSet) allow(key string, output models.Output) bool {
+	limit := set.OutputLimits.Lookup(key)
+	if limit == nil || set.Limiter == nil {
+		return true
+	}
+	return set.Limiter.Allow(key, limit, "", output)
+}
+
+// FlushOverflows sends an "N more events suppressed" summary to
+// each rate limited output that has a token again.
+func (set *AdapterSet) FlushOverflows() {
+	if set.Limiter == nil {
+		return
+	}
+	for _, overflow := range set.Limiter.Overflows() {
+		output, ok := overflow.Pending.(models.Output)
+		if !ok {
+			continue
+		}
+		ccMsg := cc.NewMessage()
+		ccMsg.Activity = "Rate limited"
+		ccMsg.Text = ratelimit.SuppressedText(overflow.Suppressed)
+		for _, errInfo := range set.sendOutput(context.Background(), []models.ErrorInfo{}, output, ccMsg, "") {
+			if errInfo.StatusCode >= 300 {
+				log.Warn().
+					Str("output_type", output.Type).
+					Int("http_status", errInfo.StatusCode).
+					Str("body", string(errInfo.Body)).
+					Msg("rate limit summary delivery failed")
+			}
 		}
 	}
-	for _, namedAdapter := range hookData.OutputNames {
-		if adapter, ok := set.Adapters[namedAdapter]; ok {
-			var msg interface{}
-			req, res, err := adapter.SendMessage(hookData.CanonicalMessage, &msg)
-			errs = set.procResponse(errs, req, res, err)
+}
+
+func (set *AdapterSet) sendOutput(ctx context.Context, errs []models.ErrorInfo, output models.Output, ccMsg cc.Message, correlationID string) []models.ErrorInfo {
+	if len(output.Name) > 0 {
+		if dest, ok := set.Destinations.Get(output.Name); ok {
+			if len(output.Thread) > 0 {
+				dest.Thread = output.Thread
+			}
+			dest.Name = ""
+			output = dest
+		} else if adapter, ok := set.Adapters[output.Name]; ok {
+			return set.send(ctx, errs, adapter, output, ccMsg, correlationID)
+		} else {
+			return append(errs, models.ErrorInfo{
+				StatusCode: 404,
+				Body:       []byte("E_OUTPUT_ADAPTER_NOT_FOUND [" + output.Name + "]")})
 		}
 	}
+	adapter, ok := set.Adapters[output.Type]
+	if !ok || len(outputTarget(output)) == 0 {
+		return append(errs, models.ErrorInfo{
+			StatusCode: 400,
+			Body:       []byte("E_OUTPUT_NOT_VALID [" + output.Type + "]")})
+	}
+	return set.send(ctx, errs, adapter, output, ccMsg, correlationID)
+}
+
+// send delivers to one output in a client span.
+func (set *AdapterSet) send(ctx context.Context, errs []models.ErrorInfo, adapter cc.Adapter, output models.Output, ccMsg cc.Message, correlationID string) []models.ErrorInfo {
+	outputType := output.Type
+	if len(outputType) == 0 {
+		outputType = output.Name
+	}
+	ctx, span := tracing.Start(ctx, "send "+outputType,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(tracing.AttrOutputType.String(outputType)))
+	defer span.End()
+	if len(output.Name) > 0 {
+		span.SetAttributes(tracing.AttrOutputName.String(output.Name))
+	}
+	sent := len(errs)
+	errs = set.deliver(ctx, errs, adapter, output, ccMsg, correlationID)
+	statusCode := http.StatusOK
+	if len(errs) > sent {
+		statusCode = models.GetMaxStatusCode(errs[sent:]...)
+	}
+	tracing.SetStatusCode(span, statusCode, 400)
 	return errs
 }
 
+// deliver sends to one output. Named outputs use the adapter default
+// destination. Adapters implementing `ContextSender` send the trace
+// context of `ctx`.
+func (set *AdapterSet) deliver(ctx context.Context, errs []models.ErrorInfo, adapter cc.Adapter, output models.Output, ccMsg cc.Message, correlationID string) []models.ErrorInfo {
+	if len(output.Name) == 0 {
+		if info := set.Outbound.Check(output.Type, outputTarget(output)); info != nil {
+			return append(errs, *info)
+		}
+	}
+	if threader, ok := adapter.(Threader); ok && len(correlationID) > 0 && set.Threads != nil {
+		return set.sendThreaded(ctx, errs, threader, output, ccMsg, correlationID)
+	}
+	var msg interface{}
+	var req *fasthttp.Request
+	var res *fasthttp.Response
+	var err error
+	target := outputTarget(output)
+	if len(output.Name) > 0 {
+		target = ""
+	}
+	if sender, ok := adapter.(ContextSender); ok {
+		req, res, err = sender.SendWebhookContext(ctx, target, ccMsg)
+	} else if len(output.Name) > 0 {
+		req, res, err = adapter.SendMessage(ccMsg, &msg)
+	} else {
+		req, res, err = adapter.SendWebhook(target, ccMsg, &msg)
+	}
+	log.Debug().
+		Str("output_type", output.Type).
+		Int("status_code", res.StatusCode()).
+		Str("output_url", redact.Target(output.URL)).
+		Msg("ADAPTER_API_REQ_RES_INFO")
+	return set.procResponse(errs, req, res, err)
+}
+
 func (set *AdapterSet) procResponse(errs []models.ErrorInfo, req *fasthttp.Request, res *fasthttp.Response, err error) []models.ErrorInfo {
 	if err != nil {
 		errs = append(errs, models.ErrorInfo{StatusCode: 500, Body: []byte(err.Error())})
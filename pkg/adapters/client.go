@@ -0,0 +1,43 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	hum "github.com/grokify/simplego/net/httputilmore"
+	"github.com/valyala/fasthttp"
+
+	"github.com/grokify/chathooks/pkg/tracing"
+)
+
+// ClientTimeout bounds requests made by the API adapters.
+var ClientTimeout = 30 * time.Second
+
+// doJSON sends `body` as JSON with the trace context of `ctx`. The
+// caller releases the request and response, e.g. with `procResponse`.
+func doJSON(ctx context.Context, client *fasthttp.Client, method, url, bearerToken string, body interface{}) (*fasthttp.Request, *fasthttp.Response, error) {
+	req := fasthttp.AcquireRequest()
+	res := fasthttp.AcquireResponse()
+	req.Header.SetMethod(method)
+	req.Header.SetRequestURI(url)
+	tracing.InjectFastHTTP(ctx, &req.Header)
+	if len(bearerToken) > 0 {
+		req.Header.Set("Authorization", "Bearer "+bearerToken)
+	}
+	if body != nil {
+		bytes, err := json.Marshal(body)
+		if err != nil {
+			return req, res, err
+		}
+		req.Header.Set(hum.HeaderContentType, hum.ContentTypeAppJsonUtf8)
+		req.SetBody(bytes)
+	}
+	return req, res, client.DoTimeout(req, res, ClientTimeout)
+}
+
+// releaseAll releases a request and response from `doJSON`.
+func releaseAll(req *fasthttp.Request, res *fasthttp.Response) {
+	fasthttp.ReleaseRequest(req)
+	fasthttp.ReleaseResponse(res)
+}
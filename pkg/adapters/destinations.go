@@ -0,0 +1,67 @@
+package adapters
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/grokify/chathooks/pkg/models"
+)
+
+// DestinationSet holds named outputs, e.g. `oncall`, that requests
+// and routes can use by name. It is safe for concurrent use.
+type DestinationSet struct {
+	mutex   sync.RWMutex
+	outputs map[string]models.Output
+}
+
+func NewDestinationSet() *DestinationSet {
+	return &DestinationSet{outputs: map[string]models.Output{}}
+}
+
+// Get returns the destination `name`. It is nil-safe.
+func (dests *DestinationSet) Get(name string) (models.Output, bool) {
+	if dests == nil {
+		return models.Output{}, false
+	}
+	dests.mutex.RLock()
+	defer dests.mutex.RUnlock()
+	output, ok := dests.outputs[name]
+	return output, ok
+}
+
+// Set adds or replaces a destination. It must have a name, a type
+// and a URL or channel.
+func (dests *DestinationSet) Set(output models.Output) error {
+	output.Name = strings.TrimSpace(output.Name)
+	if len(output.Name) == 0 {
+		return fmt.Errorf("E_DESTINATION_NO_NAME")
+	} else if len(output.Type) == 0 || len(outputTarget(output)) == 0 {
+		return fmt.Errorf("E_DESTINATION_NOT_VALID [%s]", output.Name)
+	}
+	dests.mutex.Lock()
+	defer dests.mutex.Unlock()
+	dests.outputs[output.Name] = output
+	return nil
+}
+
+func (dests *DestinationSet) Delete(name string) bool {
+	dests.mutex.Lock()
+	defer dests.mutex.Unlock()
+	_, ok := dests.outputs[name]
+	delete(dests.outputs, name)
+	return ok
+}
+
+// List returns the destinations sorted by name.
+func (dests *DestinationSet) List() []models.Output {
+	dests.mutex.RLock()
+	defer dests.mutex.RUnlock()
+	outputs := []models.Output{}
+	for _, output := range dests.outputs {
+		outputs = append(outputs, output)
+	}
+	sort.Slice(outputs, func(i, j int) bool { return outputs[i].Name < outputs[j].Name })
+	return outputs
+}
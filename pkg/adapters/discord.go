@@ -0,0 +1,136 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	cc "github.com/grokify/commonchat"
+	"github.com/valyala/fasthttp"
+)
+
+// DiscordAdapter posts to Discord webhooks. Follow-ups edit the
+// original message, as webhooks cannot reply in a thread.
+type DiscordAdapter struct {
+	WebhookURL string // default webhook URL
+	Client     fasthttp.Client
+}
+
+func NewDiscordAdapter(webhookURL string) (*DiscordAdapter, error) {
+	return &DiscordAdapter{WebhookURL: webhookURL}, nil
+}
+
+type discordMessage struct {
+	Content string         `json:"content,omitempty"`
+	Embeds  []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color,omitempty"`
+	Fields      []discordField `json:"fields,omitempty"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// convertDiscordMessage converts a message to a webhook body within
+// Discord's size limits.
+func convertDiscordMessage(ccMsg cc.Message) discordMessage {
+	msg := discordMessage{
+		Content: truncate(messageMarkdown(ccMsg, false), 2000),
+		Embeds:  []discordEmbed{}}
+	for _, att := range ccMsg.Attachments {
+		if len(msg.Embeds) == 10 {
+			break
+		}
+		embed := discordEmbed{
+			Title:       truncate(att.Title, 256),
+			Description: truncate(att.Text, 4096),
+			Color:       colorInt(att.Color)}
+		for _, field := range att.Fields {
+			if len(embed.Fields) == 25 || len(field.Title) == 0 || len(field.Value) == 0 {
+				continue
+			}
+			embed.Fields = append(embed.Fields, discordField{
+				Name:   truncate(field.Title, 256),
+				Value:  truncate(field.Value, 1024),
+				Inline: field.Short})
+		}
+		if len(embed.Title) > 0 || len(embed.Description) > 0 || len(embed.Fields) > 0 {
+			msg.Embeds = append(msg.Embeds, embed)
+		}
+	}
+	return msg
+}
+
+// discordURL adds `path` and `query` to a webhook URL, keeping its
+// query, e.g. `thread_id`.
+func discordURL(webhookURL, path string, query url.Values) (string, error) {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return "", err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	q := u.Query()
+	for key, values := range query {
+		q[key] = values
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func (adapter *DiscordAdapter) SendWebhook(webhookURL string, ccMsg cc.Message, discordmsg interface{}) (*fasthttp.Request, *fasthttp.Response, error) {
+	return adapter.SendWebhookContext(context.Background(), webhookURL, ccMsg)
+}
+
+func (adapter *DiscordAdapter) SendWebhookContext(ctx context.Context, webhookURL string, ccMsg cc.Message) (*fasthttp.Request, *fasthttp.Response, error) {
+	if len(webhookURL) == 0 {
+		webhookURL = adapter.WebhookURL
+	}
+	return doJSON(ctx, &adapter.Client, http.MethodPost, webhookURL, "", convertDiscordMessage(ccMsg))
+}
+
+func (adapter *DiscordAdapter) SendMessage(ccMsg cc.Message, discordmsg interface{}) (*fasthttp.Request, *fasthttp.Response, error) {
+	return adapter.SendWebhook(adapter.WebhookURL, ccMsg, discordmsg)
+}
+
+func (adapter *DiscordAdapter) WebhookUID(ctx *fasthttp.RequestCtx) (string, error) {
+	return fmt.Sprintf("%s", ctx.UserValue("webhookuid")), nil
+}
+
+// SendThreaded posts with `wait=true` to get the message ID, and
+// edits the parent for follow-ups.
+func (adapter *DiscordAdapter) SendThreaded(ctx context.Context, webhookURL string, ccMsg cc.Message, parent *MessageRef, mode string) (MessageRef, error) {
+	if len(webhookURL) == 0 {
+		webhookURL = adapter.WebhookURL
+	}
+	method, path, query := http.MethodPost, "", url.Values{"wait": []string{"true"}}
+	if parent != nil {
+		method, path, query = http.MethodPatch, "/messages/"+parent.ID, url.Values{}
+	}
+	apiURL, err := discordURL(webhookURL, path, query)
+	if err != nil {
+		return MessageRef{}, err
+	}
+	req, res, err := doJSON(ctx, &adapter.Client, method, apiURL, "", convertDiscordMessage(ccMsg))
+	defer releaseAll(req, res)
+	if err != nil {
+		return MessageRef{}, err
+	} else if res.StatusCode() > 299 {
+		return MessageRef{}, fmt.Errorf("E_DISCORD_API_STATUS [%d]", res.StatusCode())
+	}
+	posted := struct {
+		ID        string `json:"id"`
+		ChannelID string `json:"channel_id"`
+	}{}
+	err = json.Unmarshal(res.Body(), &posted)
+	return MessageRef{Channel: posted.ChannelID, ID: posted.ID}, err
+}
@@ -0,0 +1,88 @@
+package adapters
+
+import (
+	"html"
+	"strconv"
+	"strings"
+
+	cc "github.com/grokify/commonchat"
+)
+
+// textBlock is a line of a message rendered for platforms without a
+// `commonchat` converter.
+type textBlock struct {
+	Text   string
+	Strong bool
+}
+
+func messageBlocks(ccMsg cc.Message, withAttachments bool) []textBlock {
+	blocks := []textBlock{}
+	add := func(text string, strong bool) {
+		if text = strings.TrimSpace(text); len(text) > 0 {
+			blocks = append(blocks, textBlock{Text: text, Strong: strong})
+		}
+	}
+	add(ccMsg.Activity, true)
+	add(ccMsg.Title, false)
+	add(ccMsg.Text, false)
+	if !withAttachments {
+		return blocks
+	}
+	for _, att := range ccMsg.Attachments {
+		add(att.Title, true)
+		add(att.Text, false)
+		for _, field := range att.Fields {
+			if len(strings.TrimSpace(field.Value)) > 0 {
+				add(field.Title+": "+field.Value, false)
+			}
+		}
+	}
+	return blocks
+}
+
+// messageMarkdown renders a message as Markdown.
+func messageMarkdown(ccMsg cc.Message, withAttachments bool) string {
+	lines := []string{}
+	for _, block := range messageBlocks(ccMsg, withAttachments) {
+		if block.Strong {
+			lines = append(lines, "**"+block.Text+"**")
+		} else {
+			lines = append(lines, block.Text)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// messageHTML renders a message as escaped HTML.
+func messageHTML(ccMsg cc.Message) string {
+	lines := []string{}
+	for _, block := range messageBlocks(ccMsg, true) {
+		text := strings.Replace(html.EscapeString(block.Text), "\n", "<br>", -1)
+		if block.Strong {
+			text = "<b>" + text + "</b>"
+		}
+		lines = append(lines, text)
+	}
+	return strings.Join(lines, "<br>")
+}
+
+// colorInt parses a `#rrggbb` color, returning 0 if it is not one.
+func colorInt(color string) int {
+	color = strings.TrimPrefix(strings.TrimSpace(color), "#")
+	if len(color) != 6 {
+		return 0
+	}
+	i, err := strconv.ParseUint(color, 16, 32)
+	if err != nil {
+		return 0
+	}
+	return int(i)
+}
+
+// truncate limits `s` to `n` runes.
+func truncate(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n-3]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,201 @@
+package adapters
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	cc "github.com/grokify/commonchat"
+	ccglip "github.com/grokify/commonchat/glip"
+	ccslack "github.com/grokify/commonchat/slack"
+
+	"github.com/grokify/chathooks/pkg/models"
+)
+
+// DialTimeout bounds connecting to an output host.
+var DialTimeout = 10 * time.Second
+
+// DefaultOutputHosts are the host patterns each output type may send
+// to unless configured otherwise.
+var DefaultOutputHosts = map[string][]string{
+	"glip":     {"hooks.glip.com", "hooks.ringcentral.com", "hooks-glip.devtest.ringcentral.com"},
+	"slack":    {"hooks.slack.com"},
+	"discord":  {"discord.com", "discordapp.com", "*.discord.com"},
+	"slackbot": {"slack.com"},
+	"teams":    {"graph.microsoft.com"}}
+
+// blockedNetworks are loopback, private, link-local and other
+// non-public address ranges. NAT64 and 6to4 prefixes are blocked, as
+// they embed IPv4 addresses of any range.
+var blockedNetworks = parseCIDRs(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8",
+	"169.254.0.0/16", "172.16.0.0/12", "192.0.0.0/24", "192.168.0.0/16",
+	"198.18.0.0/15", "224.0.0.0/4", "240.0.0.0/4",
+	"::/128", "::1/128", "64:ff9b::/96", "100::/64", "2002::/16",
+	"fc00::/7", "fe80::/10", "ff00::/8")
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := []*net.IPNet{}
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets
+}
+
+// PublicIP returns whether `ip` is outside the blocked ranges.
+func PublicIP(ip net.IP) bool {
+	for _, ipNet := range blockedNetworks {
+		if ipNet.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// OutboundPolicy limits where adapters send to. Output URLs must
+// match a host pattern of their output type, and unless `AllowPrivate`
+// is set, hosts must resolve to public addresses only. Addresses are
+// checked again when connecting, so DNS changes cannot bypass it.
+type OutboundPolicy struct {
+	Hosts        map[string][]string // host patterns by output type
+	AllowPrivate bool
+	LookupIP     func(host string) ([]net.IP, error)
+}
+
+// NewOutboundPolicy returns the default policy updated with `hosts`
+// entries like `slack:hooks.slack.com`. Entries replace the default
+// patterns of their type. `*` allows any host and `*.example.com` any
+// subdomain.
+func NewOutboundPolicy(hosts []string, allowPrivate bool) (*OutboundPolicy, error) {
+	policy := &OutboundPolicy{
+		Hosts:        map[string][]string{},
+		AllowPrivate: allowPrivate,
+		LookupIP:     net.LookupIP}
+	configured := map[string][]string{}
+	for _, entry := range hosts {
+		entry = strings.TrimSpace(entry)
+		if len(entry) == 0 {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 || len(strings.TrimSpace(parts[0])) == 0 || len(strings.TrimSpace(parts[1])) == 0 {
+			return nil, fmt.Errorf("E_OUTPUT_HOST_NOT_VALID [%s]", entry)
+		}
+		outputType := strings.TrimSpace(parts[0])
+		configured[outputType] = append(configured[outputType], strings.ToLower(strings.TrimSpace(parts[1])))
+	}
+	for outputType, patterns := range DefaultOutputHosts {
+		policy.Hosts[outputType] = patterns
+	}
+	for outputType, patterns := range configured {
+		policy.Hosts[outputType] = patterns
+	}
+	return policy, nil
+}
+
+// MatchHost returns whether `host` matches `pattern`.
+func MatchHost(pattern, host string) bool {
+	if pattern == "*" || pattern == host {
+		return true
+	}
+	return strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:])
+}
+
+// AllowsHost returns whether outputs of `outputType` may send to
+// `host`.
+func (p *OutboundPolicy) AllowsHost(outputType, host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range p.Hosts[outputType] {
+		if MatchHost(pattern, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns a 403 `ErrorInfo` if an output may not send to
+// `target`. Targets without a scheme, e.g. channel IDs and Glip webhook
+// IDs, are sent to the adapter's own API host and are not checked. It
+// is nil-safe.
+func (p *OutboundPolicy) Check(outputType, target string) *models.ErrorInfo {
+	if p == nil || !strings.Contains(target, "://") {
+		return nil
+	}
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || len(u.Hostname()) == 0 {
+		return &models.ErrorInfo{
+			StatusCode: http.StatusForbidden,
+			Body:       []byte("E_OUTPUT_URL_NOT_ALLOWED [" + outputType + "]")}
+	}
+	host := strings.ToLower(u.Hostname())
+	if !p.AllowsHost(outputType, host) {
+		return &models.ErrorInfo{
+			StatusCode: http.StatusForbidden,
+			Body:       []byte("E_OUTPUT_HOST_NOT_ALLOWED [" + outputType + "] [" + host + "]")}
+	}
+	if _, err := p.resolve(host); err != nil {
+		return &models.ErrorInfo{StatusCode: http.StatusForbidden, Body: []byte(err.Error())}
+	}
+	return nil
+}
+
+// resolve returns the addresses of `host`, or an error if any of them
+// is not public.
+func (p *OutboundPolicy) resolve(host string) ([]net.IP, error) {
+	ips, err := p.LookupIP(host)
+	if err != nil || len(ips) == 0 {
+		return nil, fmt.Errorf("E_OUTPUT_HOST_NOT_RESOLVED [%s]", host)
+	}
+	if !p.AllowPrivate {
+		for _, ip := range ips {
+			if !PublicIP(ip) {
+				return nil, fmt.Errorf("E_OUTPUT_ADDRESS_NOT_ALLOWED [%s]", host)
+			}
+		}
+	}
+	return ips, nil
+}
+
+// Dial connects to `addr` for a `fasthttp.Client`, checking the
+// resolved addresses first. IPv4 addresses are preferred.
+func (p *OutboundPolicy) Dial(addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := p.resolve(host)
+	if err != nil {
+		return nil, err
+	}
+	ip := ips[0]
+	for _, candidate := range ips {
+		if candidate.To4() != nil {
+			ip = candidate
+			break
+		}
+	}
+	return net.DialTimeout("tcp", net.JoinHostPort(ip.String(), port), DialTimeout)
+}
+
+// Guard makes the clients of the known adapters connect with `Dial`.
+func (p *OutboundPolicy) Guard(adapter cc.Adapter) {
+	switch a := adapter.(type) {
+	case *ccglip.GlipAdapter:
+		a.GlipClient.FastClient.Dial = p.Dial
+	case *ccslack.SlackAdapter:
+		a.SlackClient.FastClient.Dial = p.Dial
+	case *DiscordAdapter:
+		a.Client.Dial = p.Dial
+	case *SlackBotAdapter:
+		a.Client.Dial = p.Dial
+	case *TeamsAdapter:
+		a.Client.Dial = p.Dial
+	}
+}
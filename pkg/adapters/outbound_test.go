@@ -0,0 +1,111 @@
+package adapters
+
+import (
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grokify/chathooks/pkg/models"
+)
+
+var testIPs = map[string][]net.IP{
+	"hooks.slack.com":    {net.ParseIP("54.1.2.3")},
+	"hooks.glip.com":     {net.ParseIP("52.1.2.3"), net.ParseIP("2600:1f18::1")},
+	"internal.slack.com": {net.ParseIP("10.0.0.5")},
+	"rebind.example.com": {net.ParseIP("93.184.216.34"), net.ParseIP("127.0.0.1")},
+	"chat.example.com":   {net.ParseIP("192.168.1.20")}}
+
+func testLookupIP(host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	} else if ips, ok := testIPs[host]; ok {
+		return ips, nil
+	}
+	return nil, fmt.Errorf("no such host")
+}
+
+var OutboundCheckTests = []struct {
+	hosts        []string
+	allowPrivate bool
+	outputType   string
+	target       string
+	wantErr      string
+}{
+	{nil, false, "slack", "https://hooks.slack.com/services/T1/B1/x", ""},
+	{nil, false, "glip", "https://HOOKS.glip.com/webhook/abc", ""},
+	{nil, false, "glip", "11112222-3333-4444-5555-666677778888", ""},
+	{nil, false, "slackbot", "C0123456789", ""},
+	{nil, false, "slack", "https://hooks.glip.com/webhook/abc", "E_OUTPUT_HOST_NOT_ALLOWED [slack] [hooks.glip.com]"},
+	{nil, false, "slack", "http://169.254.169.254/latest/meta-data", "E_OUTPUT_HOST_NOT_ALLOWED [slack] [169.254.169.254]"},
+	{nil, false, "slack", "file:///etc/passwd", "E_OUTPUT_URL_NOT_ALLOWED [slack]"},
+	{nil, false, "custom", "https://hooks.slack.com/services/x", "E_OUTPUT_HOST_NOT_ALLOWED [custom] [hooks.slack.com]"},
+	{[]string{"slack:*.slack.com"}, false, "slack", "https://internal.slack.com/x", "E_OUTPUT_ADDRESS_NOT_ALLOWED [internal.slack.com]"},
+	{[]string{"slack:*"}, false, "slack", "https://rebind.example.com/x", "E_OUTPUT_ADDRESS_NOT_ALLOWED [rebind.example.com]"},
+	{[]string{"slack:*"}, false, "slack", "http://[::1]:8080/x", "E_OUTPUT_ADDRESS_NOT_ALLOWED [::1]"},
+	{[]string{"slack:*"}, false, "slack", "http://[64:ff9b::a9fe:a9fe]/x", "E_OUTPUT_ADDRESS_NOT_ALLOWED [64:ff9b::a9fe:a9fe]"},
+	{[]string{"slack:*"}, false, "slack", "http://[2002:a00:1::1]/x", "E_OUTPUT_ADDRESS_NOT_ALLOWED [2002:a00:1::1]"},
+	{[]string{"slack:*"}, false, "slack", "http://[100::1]/x", "E_OUTPUT_ADDRESS_NOT_ALLOWED [100::1]"},
+	{[]string{"slack:*"}, false, "slack", "https://missing.example.com/x", "E_OUTPUT_HOST_NOT_RESOLVED [missing.example.com]"},
+	{[]string{"slack:chat.example.com"}, true, "slack", "https://chat.example.com/hooks/x", ""},
+	{[]string{"slack:chat.example.com"}, true, "slack", "https://hooks.slack.com/services/x", "E_OUTPUT_HOST_NOT_ALLOWED [slack] [hooks.slack.com]"},
+	{[]string{"slack:chat.example.com"}, true, "glip", "https://hooks.glip.com/webhook/abc", ""}}
+
+func TestOutboundPolicyCheck(t *testing.T) {
+	for _, tt := range OutboundCheckTests {
+		policy, err := NewOutboundPolicy(tt.hosts, tt.allowPrivate)
+		if err != nil {
+			t.Fatalf("NewOutboundPolicy(%v): want no error, got %v", tt.hosts, err)
+		}
+		policy.LookupIP = testLookupIP
+		info := policy.Check(tt.outputType, tt.target)
+		if len(tt.wantErr) == 0 && info != nil {
+			t.Errorf("OutboundPolicy.Check(%v, %v): want no error, got %v", tt.outputType, tt.target, string(info.Body))
+		} else if len(tt.wantErr) > 0 && (info == nil || info.StatusCode != 403 || string(info.Body) != tt.wantErr) {
+			t.Errorf("OutboundPolicy.Check(%v, %v): want 403 %v, got %v", tt.outputType, tt.target, tt.wantErr, info)
+		}
+	}
+
+	if _, err := NewOutboundPolicy([]string{"hooks.slack.com"}, false); err == nil {
+		t.Errorf("NewOutboundPolicy(hooks.slack.com): want error, got none")
+	}
+}
+
+func TestSendWebhooksOutbound(t *testing.T) {
+	ts := &testServer{body: `{"id":"42"}`}
+	server := httptest.NewServer(ts)
+	defer server.Close()
+
+	set := NewAdapterSet()
+	adapter, _ := NewDiscordAdapter("")
+	set.Adapters["discord"] = adapter
+	policy, err := NewOutboundPolicy([]string{"discord:127.0.0.1"}, false)
+	if err != nil {
+		t.Fatalf("NewOutboundPolicy(): want no error, got %v", err)
+	}
+	set.Outbound = policy
+	policy.Guard(adapter)
+
+	errs := set.SendWebhooks(models.HookData{
+		OutputType:       "discord",
+		OutputURL:        server.URL + "/api/webhooks/1/abc",
+		CanonicalMessage: testMessage("blocked")})
+	if len(errs) != 1 || errs[0].StatusCode != 403 || len(ts.requests) > 0 {
+		t.Errorf("AdapterSet.SendWebhooks(loopback): want 403, got %v %v", errs, ts.requests)
+	}
+
+	policy.AllowPrivate = true
+	errs = set.SendWebhooks(models.HookData{
+		OutputType:       "discord",
+		OutputURL:        server.URL + "/api/webhooks/1/abc",
+		CanonicalMessage: testMessage("allowed")})
+	if len(errs) > 0 || len(ts.requests) != 1 {
+		t.Errorf("AdapterSet.SendWebhooks(allowPrivate): want 1 request, got %v %v", errs, ts.requests)
+	}
+
+	policy.AllowPrivate = false
+	if _, err := policy.Dial(strings.TrimPrefix(server.URL, "http://")); err == nil {
+		t.Errorf("OutboundPolicy.Dial(loopback): want error, got none")
+	}
+}
@@ -0,0 +1,116 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	cc "github.com/grokify/commonchat"
+	ccslack "github.com/grokify/commonchat/slack"
+	"github.com/valyala/fasthttp"
+)
+
+// SlackAPIURL is the Slack Web API base URL.
+var SlackAPIURL = "https://slack.com/api/"
+
+// SlackBotAdapter posts with a Slack bot token using `chat.postMessage`
+// so follow-ups can be threaded with `thread_ts` or edited with
+// `chat.update`. The webhook URL argument is a channel ID.
+type SlackBotAdapter struct {
+	Token   string
+	Channel string // default channel
+	Client  fasthttp.Client
+}
+
+func NewSlackBotAdapter(token, channel string) (*SlackBotAdapter, error) {
+	if len(strings.TrimSpace(token)) == 0 {
+		return nil, fmt.Errorf("E_SLACK_BOT_TOKEN_MISSING")
+	}
+	return &SlackBotAdapter{Token: token, Channel: channel}, nil
+}
+
+type slackAPIResponse struct {
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+	Channel string `json:"channel,omitempty"`
+	TS      string `json:"ts,omitempty"`
+}
+
+// post calls a Slack API method. Slack reports errors in the body,
+// which are returned as `err`.
+func (adapter *SlackBotAdapter) post(ctx context.Context, method string, body map[string]interface{}) (*fasthttp.Request, *fasthttp.Response, slackAPIResponse, error) {
+	apiRes := slackAPIResponse{}
+	req, res, err := doJSON(ctx, &adapter.Client, http.MethodPost, SlackAPIURL+method, adapter.Token, body)
+	if err != nil {
+		return req, res, apiRes, err
+	} else if res.StatusCode() > 299 {
+		return req, res, apiRes, fmt.Errorf("E_SLACK_API_STATUS [%d]", res.StatusCode())
+	} else if err := json.Unmarshal(res.Body(), &apiRes); err != nil {
+		return req, res, apiRes, err
+	} else if !apiRes.OK {
+		return req, res, apiRes, fmt.Errorf("E_SLACK_API [%s]", apiRes.Error)
+	}
+	return req, res, apiRes, nil
+}
+
+// payload converts a message to a `chat.postMessage` body.
+func (adapter *SlackBotAdapter) payload(channel string, ccMsg cc.Message) (map[string]interface{}, error) {
+	body := map[string]interface{}{}
+	bytes, err := json.Marshal(ccslack.ConvertCommonMessage(ccMsg))
+	if err != nil {
+		return body, err
+	}
+	if err := json.Unmarshal(bytes, &body); err != nil {
+		return body, err
+	}
+	if len(channel) == 0 {
+		channel = adapter.Channel
+	}
+	body["channel"] = channel
+	return body, nil
+}
+
+func (adapter *SlackBotAdapter) SendWebhook(channel string, ccMsg cc.Message, slackmsg interface{}) (*fasthttp.Request, *fasthttp.Response, error) {
+	return adapter.SendWebhookContext(context.Background(), channel, ccMsg)
+}
+
+func (adapter *SlackBotAdapter) SendWebhookContext(ctx context.Context, channel string, ccMsg cc.Message) (*fasthttp.Request, *fasthttp.Response, error) {
+	body, err := adapter.payload(channel, ccMsg)
+	if err != nil {
+		return fasthttp.AcquireRequest(), fasthttp.AcquireResponse(), err
+	}
+	req, res, _, err := adapter.post(ctx, "chat.postMessage", body)
+	return req, res, err
+}
+
+func (adapter *SlackBotAdapter) SendMessage(ccMsg cc.Message, slackmsg interface{}) (*fasthttp.Request, *fasthttp.Response, error) {
+	return adapter.SendWebhook(adapter.Channel, ccMsg, slackmsg)
+}
+
+func (adapter *SlackBotAdapter) WebhookUID(ctx *fasthttp.RequestCtx) (string, error) {
+	return fmt.Sprintf("%s", ctx.UserValue("webhookuid")), nil
+}
+
+// SendThreaded replies in the parent's thread by default, or edits
+// the parent with `update`.
+func (adapter *SlackBotAdapter) SendThreaded(ctx context.Context, channel string, ccMsg cc.Message, parent *MessageRef, mode string) (MessageRef, error) {
+	body, err := adapter.payload(channel, ccMsg)
+	if err != nil {
+		return MessageRef{}, err
+	}
+	method := "chat.postMessage"
+	if parent != nil {
+		body["channel"] = parent.Channel
+		if mode == ThreadUpdate {
+			method = "chat.update"
+			body["ts"] = parent.ID
+		} else {
+			body["thread_ts"] = parent.ID
+		}
+	}
+	req, res, apiRes, err := adapter.post(ctx, method, body)
+	releaseAll(req, res)
+	return MessageRef{Channel: apiRes.Channel, ID: apiRes.TS}, err
+}
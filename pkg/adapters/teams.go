@@ -0,0 +1,174 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	cc "github.com/grokify/commonchat"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// GraphAPIURL is the Microsoft Graph API base URL.
+var GraphAPIURL = "https://graph.microsoft.com/v1.0/"
+
+// AzureADURL is the Microsoft identity platform base URL.
+var AzureADURL = "https://login.microsoftonline.com/"
+
+// TeamsAdapter posts to Microsoft Teams channels with the Graph API,
+// which returns message IDs. Incoming webhooks do not. The webhook URL
+// argument is a `<team-id>/<channel-id>` pair. Follow-ups are posted
+// as replies by default, or edit the original with `update`.
+type TeamsAdapter struct {
+	Tokens  oauth2.TokenSource // Graph access tokens with ChannelMessage.Send
+	Channel string             // default `<team-id>/<channel-id>`
+	Client  fasthttp.Client
+}
+
+func NewTeamsAdapter(tokens oauth2.TokenSource, channel string) (*TeamsAdapter, error) {
+	if tokens == nil {
+		return nil, fmt.Errorf("E_TEAMS_GRAPH_TOKEN_MISSING")
+	}
+	return &TeamsAdapter{Tokens: tokens, Channel: channel}, nil
+}
+
+// TeamsCredentials are the Azure AD app registration used to get
+// Graph access tokens. Without a refresh token, tokens are requested
+// with the client credentials grant. With one, the refresh token grant
+// is used, for delegated permissions.
+type TeamsCredentials struct {
+	TenantID     string
+	ClientID     string
+	ClientSecret string
+	RefreshToken string
+}
+
+// TokenSource returns a token source that caches the access token and
+// gets a new one shortly before it expires.
+func (creds TeamsCredentials) TokenSource() (oauth2.TokenSource, error) {
+	if len(strings.TrimSpace(creds.TenantID)) == 0 ||
+		len(strings.TrimSpace(creds.ClientID)) == 0 ||
+		len(strings.TrimSpace(creds.ClientSecret)) == 0 {
+		return nil, fmt.Errorf("E_TEAMS_CREDENTIALS_MISSING")
+	}
+	tokenURL := AzureADURL + url.PathEscape(creds.TenantID) + "/oauth2/v2.0/token"
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Timeout: ClientTimeout})
+	if len(creds.RefreshToken) == 0 {
+		cfg := clientcredentials.Config{
+			ClientID:     creds.ClientID,
+			ClientSecret: creds.ClientSecret,
+			TokenURL:     tokenURL,
+			Scopes:       []string{"https://graph.microsoft.com/.default"}}
+		return cfg.TokenSource(ctx), nil
+	}
+	cfg := oauth2.Config{
+		ClientID:     creds.ClientID,
+		ClientSecret: creds.ClientSecret,
+		Endpoint:     oauth2.Endpoint{TokenURL: tokenURL, AuthStyle: oauth2.AuthStyleInParams},
+		Scopes:       []string{"https://graph.microsoft.com/.default", "offline_access"}}
+	return cfg.TokenSource(ctx, &oauth2.Token{RefreshToken: creds.RefreshToken}), nil
+}
+
+// accessToken returns the current Graph access token.
+func (adapter *TeamsAdapter) accessToken() (string, error) {
+	token, err := adapter.Tokens.Token()
+	if err != nil {
+		return "", fmt.Errorf("E_TEAMS_GRAPH_TOKEN [%s]", err.Error())
+	}
+	return token.AccessToken, nil
+}
+
+type teamsMessage struct {
+	Body teamsBody `json:"body"`
+}
+
+type teamsBody struct {
+	ContentType string `json:"contentType"`
+	Content     string `json:"content"`
+}
+
+// messagesURL returns the Graph URL for a channel's messages.
+func (adapter *TeamsAdapter) messagesURL(channel string) (string, error) {
+	if len(channel) == 0 {
+		channel = adapter.Channel
+	}
+	parts := strings.Split(channel, "/")
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return "", fmt.Errorf("E_TEAMS_CHANNEL_INVALID [%s]", channel)
+	}
+	return GraphAPIURL + "teams/" + url.PathEscape(parts[0]) +
+		"/channels/" + url.PathEscape(parts[1]) + "/messages", nil
+}
+
+func (adapter *TeamsAdapter) SendWebhook(channel string, ccMsg cc.Message, teamsmsg interface{}) (*fasthttp.Request, *fasthttp.Response, error) {
+	return adapter.SendWebhookContext(context.Background(), channel, ccMsg)
+}
+
+func (adapter *TeamsAdapter) SendWebhookContext(ctx context.Context, channel string, ccMsg cc.Message) (*fasthttp.Request, *fasthttp.Response, error) {
+	apiURL, err := adapter.messagesURL(channel)
+	if err != nil {
+		return fasthttp.AcquireRequest(), fasthttp.AcquireResponse(), err
+	}
+	token, err := adapter.accessToken()
+	if err != nil {
+		return fasthttp.AcquireRequest(), fasthttp.AcquireResponse(), err
+	}
+	return doJSON(ctx, &adapter.Client, http.MethodPost, apiURL, token,
+		teamsMessage{Body: teamsBody{ContentType: "html", Content: messageHTML(ccMsg)}})
+}
+
+func (adapter *TeamsAdapter) SendMessage(ccMsg cc.Message, teamsmsg interface{}) (*fasthttp.Request, *fasthttp.Response, error) {
+	return adapter.SendWebhook(adapter.Channel, ccMsg, teamsmsg)
+}
+
+func (adapter *TeamsAdapter) WebhookUID(ctx *fasthttp.RequestCtx) (string, error) {
+	return fmt.Sprintf("%s", ctx.UserValue("webhookuid")), nil
+}
+
+// SendThreaded posts a new message, or a reply to or edit of the
+// parent.
+func (adapter *TeamsAdapter) SendThreaded(ctx context.Context, channel string, ccMsg cc.Message, parent *MessageRef, mode string) (MessageRef, error) {
+	if parent != nil {
+		channel = parent.Channel
+	}
+	apiURL, err := adapter.messagesURL(channel)
+	if err != nil {
+		return MessageRef{}, err
+	}
+	token, err := adapter.accessToken()
+	if err != nil {
+		return MessageRef{}, err
+	}
+	method := http.MethodPost
+	if parent != nil {
+		apiURL += "/" + url.PathEscape(parent.ID)
+		if mode == ThreadUpdate {
+			method = http.MethodPatch
+		} else {
+			apiURL += "/replies"
+		}
+	}
+	req, res, err := doJSON(ctx, &adapter.Client, method, apiURL, token,
+		teamsMessage{Body: teamsBody{ContentType: "html", Content: messageHTML(ccMsg)}})
+	defer releaseAll(req, res)
+	if err != nil {
+		return MessageRef{}, err
+	} else if res.StatusCode() > 299 {
+		return MessageRef{}, fmt.Errorf("E_TEAMS_API_STATUS [%d]", res.StatusCode())
+	} else if method == http.MethodPatch {
+		return *parent, nil
+	}
+	posted := struct {
+		ID string `json:"id"`
+	}{}
+	err = json.Unmarshal(res.Body(), &posted)
+	if len(channel) == 0 {
+		channel = adapter.Channel
+	}
+	return MessageRef{Channel: channel, ID: posted.ID}, err
+}
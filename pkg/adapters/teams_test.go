@@ -0,0 +1,78 @@
+package adapters
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var teamsTokenTests = []struct {
+	refreshToken   string
+	expiresIn      int
+	wantGrantType  string
+	wantTokenCalls int
+}{
+	{"", 3600, "client_credentials", 1},
+	{"", 1, "client_credentials", 2},
+	{"refresh-1", 3600, "refresh_token", 1},
+	{"refresh-1", 1, "refresh_token", 2}}
+
+func TestTeamsCredentialsTokenSource(t *testing.T) {
+	for _, tt := range teamsTokenTests {
+		grantTypes := []string{}
+		refreshTokens := []string{}
+		auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.ParseForm()
+			grantTypes = append(grantTypes, r.PostForm.Get("grant_type"))
+			refreshTokens = append(refreshTokens, r.PostForm.Get("refresh_token"))
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"access_token":"access-%d","refresh_token":"refresh-%d","token_type":"Bearer","expires_in":%d}`,
+				len(grantTypes), len(grantTypes)+1, tt.expiresIn)
+		}))
+		ts := &testServer{body: `{"id":"42"}`}
+		authHeaders := []string{}
+		graph := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeaders = append(authHeaders, r.Header.Get("Authorization"))
+			ts.ServeHTTP(w, r)
+		}))
+		AzureADURL, GraphAPIURL = auth.URL+"/", graph.URL+"/"
+
+		tokens, err := TeamsCredentials{TenantID: "tenant", ClientID: "client", ClientSecret: "secret",
+			RefreshToken: tt.refreshToken}.TokenSource()
+		if err != nil {
+			t.Fatalf("TeamsCredentials.TokenSource(): want no error, got %v", err)
+		}
+		adapter, _ := NewTeamsAdapter(tokens, "team/channel")
+		for i := 0; i < 2; i++ {
+			if _, res, err := adapter.SendMessage(testMessage("down"), nil); err != nil || res.StatusCode() != 200 {
+				t.Errorf("TeamsAdapter.SendMessage(): want 200, got %v", err)
+			}
+		}
+		auth.Close()
+		graph.Close()
+
+		if len(grantTypes) != tt.wantTokenCalls || grantTypes[0] != tt.wantGrantType {
+			t.Errorf("TeamsCredentials.TokenSource(%v, %v): want %v %v token requests, got %v",
+				tt.refreshToken, tt.expiresIn, tt.wantTokenCalls, tt.wantGrantType, grantTypes)
+		}
+		if len(tt.refreshToken) > 0 && tt.wantTokenCalls == 2 && refreshTokens[1] != "refresh-2" {
+			t.Errorf("TeamsCredentials.TokenSource(%v): want rotated refresh token refresh-2, got %v",
+				tt.refreshToken, refreshTokens)
+		}
+		wantAuth := fmt.Sprintf("Bearer access-%d", tt.wantTokenCalls)
+		if len(authHeaders) != 2 || authHeaders[1] != wantAuth {
+			t.Errorf("TeamsAdapter.SendMessage(%v): want last Authorization %v, got %v",
+				tt.expiresIn, wantAuth, authHeaders)
+		}
+	}
+	AzureADURL, GraphAPIURL = "https://login.microsoftonline.com/", "https://graph.microsoft.com/v1.0/"
+}
+
+func TestTeamsCredentialsMissing(t *testing.T) {
+	_, err := TeamsCredentials{TenantID: "tenant", ClientID: "client"}.TokenSource()
+	if err == nil || !strings.Contains(err.Error(), "E_TEAMS_CREDENTIALS_MISSING") {
+		t.Errorf("TeamsCredentials.TokenSource(no secret): want E_TEAMS_CREDENTIALS_MISSING, got %v", err)
+	}
+}
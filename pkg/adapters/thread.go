@@ -0,0 +1,78 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	cc "github.com/grokify/commonchat"
+	"github.com/rs/zerolog/log"
+
+	"github.com/grokify/chathooks/pkg/models"
+)
+
+const (
+	ThreadReply  = "reply"  // post follow-ups as thread replies
+	ThreadUpdate = "update" // edit the original message in place
+)
+
+// ThreadTTL is how long a posted message is remembered after the
+// last event for its correlation key.
+var ThreadTTL = 7 * 24 * time.Hour
+
+// MessageRef identifies a posted message.
+type MessageRef struct {
+	Channel string `json:"channel,omitempty"`
+	ID      string `json:"id"`
+}
+
+// Threader is implemented by adapters whose platforms return message
+// IDs. `target` is the output URL or channel, or empty for the adapter
+// default. With a `parent`, the message is posted as a reply or edits
+// the parent, per `mode`; adapters use their default mode when `mode`
+// is empty or not supported. The trace context of `ctx` is sent with
+// the requests. It returns the message to thread follow-ups on.
+type Threader interface {
+	SendThreaded(ctx context.Context, target string, ccMsg cc.Message, parent *MessageRef, mode string) (MessageRef, error)
+}
+
+// sendThreaded sends with `threader`, threading on earlier messages
+// for `correlationID`.
+func (set *AdapterSet) sendThreaded(ctx context.Context, errs []models.ErrorInfo, threader Threader, output models.Output, ccMsg cc.Message, correlationID string) []models.ErrorInfo {
+	key := "thread|" + output.Type + "|" + outputKey(output) + "|" + correlationID
+	var parent *MessageRef
+	if bytes, ok, err := set.Threads.Get(key); err != nil {
+		log.Warn().Err(err).Msg("thread state read failed")
+	} else if ok {
+		ref := MessageRef{}
+		if err := json.Unmarshal(bytes, &ref); err == nil && len(ref.ID) > 0 {
+			parent = &ref
+		}
+	}
+
+	ref, err := threader.SendThreaded(ctx, outputTarget(output), ccMsg, parent, output.Thread)
+	if err != nil {
+		return append(errs, models.ErrorInfo{StatusCode: 502, Body: []byte(err.Error())})
+	}
+	if parent != nil {
+		ref = *parent
+	}
+	if len(ref.ID) > 0 {
+		bytes, err := json.Marshal(ref)
+		if err == nil {
+			err = set.Threads.Set(key, bytes, ThreadTTL)
+		}
+		if err != nil {
+			log.Warn().Err(err).Msg("thread state write failed")
+		}
+	}
+	return errs
+}
+
+// outputTarget is the URL or channel passed to an adapter.
+func outputTarget(output models.Output) string {
+	if len(output.URL) > 0 {
+		return output.URL
+	}
+	return output.Channel
+}
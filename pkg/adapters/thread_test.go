@@ -0,0 +1,117 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cc "github.com/grokify/commonchat"
+
+	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/state"
+)
+
+// testServer records requests and replies with `body`.
+type testServer struct {
+	requests []string
+	payloads []map[string]interface{}
+	body     string
+}
+
+func (ts *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ts.requests = append(ts.requests, r.Method+" "+r.URL.RequestURI())
+	payload := map[string]interface{}{}
+	bytes, _ := ioutil.ReadAll(r.Body)
+	json.Unmarshal(bytes, &payload)
+	ts.payloads = append(ts.payloads, payload)
+	w.Write([]byte(ts.body))
+}
+
+func testMessage(text string) cc.Message {
+	ccMsg := cc.NewMessage()
+	ccMsg.Activity = "Alert created"
+	ccMsg.Text = text
+	return ccMsg
+}
+
+func TestSendWebhooksDiscordThreaded(t *testing.T) {
+	ts := &testServer{body: `{"id":"42","channel_id":"7"}`}
+	server := httptest.NewServer(ts)
+	defer server.Close()
+
+	set := NewAdapterSet()
+	set.Adapters["discord"], _ = NewDiscordAdapter("")
+	set.Threads = state.NewMemoryStore(nil)
+	output := models.Output{Type: "discord", URL: server.URL + "/api/webhooks/1/abc"}
+
+	for _, correlationID := range []string{"opsgenie|a", "opsgenie|a", "opsgenie|b", ""} {
+		errs := set.SendWebhooks(models.HookData{
+			Outputs:          []models.Output{output},
+			CorrelationID:    correlationID,
+			CanonicalMessage: testMessage(correlationID)})
+		if len(errs) > 0 {
+			t.Errorf("AdapterSet.SendWebhooks(%v): want no errors, got %v", correlationID, errs)
+		}
+	}
+
+	want := []string{
+		"POST /api/webhooks/1/abc?wait=true",
+		"PATCH /api/webhooks/1/abc/messages/42",
+		"POST /api/webhooks/1/abc?wait=true",
+		"POST /api/webhooks/1/abc"}
+	if len(ts.requests) != len(want) {
+		t.Fatalf("Discord requests: want %v, got %v", want, ts.requests)
+	}
+	for i := range want {
+		if ts.requests[i] != want[i] {
+			t.Errorf("Discord request %v: want %v, got %v", i, want[i], ts.requests[i])
+		}
+	}
+	if content := ts.payloads[1]["content"]; content != "**Alert created**\nopsgenie|a" {
+		t.Errorf("Discord content: want %v, got %v", "**Alert created**\nopsgenie|a", content)
+	}
+}
+
+var SlackBotThreadTests = []struct {
+	mode       string
+	wantMethod string
+	wantKey    string
+}{
+	{"", "POST /chat.postMessage", "thread_ts"},
+	{ThreadReply, "POST /chat.postMessage", "thread_ts"},
+	{ThreadUpdate, "POST /chat.update", "ts"}}
+
+func TestSlackBotSendThreaded(t *testing.T) {
+	ts := &testServer{body: `{"ok":true,"channel":"C1","ts":"1600000000.000100"}`}
+	server := httptest.NewServer(ts)
+	defer server.Close()
+	SlackAPIURL = server.URL + "/"
+	defer func() { SlackAPIURL = "https://slack.com/api/" }()
+
+	adapter, err := NewSlackBotAdapter("xoxb-test", "C1")
+	if err != nil {
+		t.Fatalf("NewSlackBotAdapter(): want no error, got %v", err)
+	}
+	parent, err := adapter.SendThreaded(context.Background(), "", testMessage("created"), nil, "")
+	if err != nil || parent.ID != "1600000000.000100" || parent.Channel != "C1" {
+		t.Fatalf("SlackBotAdapter.SendThreaded(new): want C1 1600000000.000100, got %v %v", parent, err)
+	}
+	for _, tt := range SlackBotThreadTests {
+		if _, err := adapter.SendThreaded(context.Background(), "", testMessage("closed"), &parent, tt.mode); err != nil {
+			t.Errorf("SlackBotAdapter.SendThreaded(%v): want no error, got %v", tt.mode, err)
+		}
+		last := len(ts.requests) - 1
+		if ts.requests[last] != tt.wantMethod || ts.payloads[last][tt.wantKey] != parent.ID {
+			t.Errorf("SlackBotAdapter.SendThreaded(%v): want %v with %v, got %v %v",
+				tt.mode, tt.wantMethod, tt.wantKey, ts.requests[last], ts.payloads[last])
+		}
+	}
+
+	ts.body = `{"ok":false,"error":"channel_not_found"}`
+	if _, err := adapter.SendThreaded(context.Background(), "C2", testMessage("created"), nil, ""); err == nil {
+		t.Errorf("SlackBotAdapter.SendThreaded(ok=false): want error, got none")
+	}
+}
@@ -0,0 +1,410 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	hum "github.com/grokify/simplego/net/httputilmore"
+	"github.com/rs/zerolog/log"
+
+	"github.com/grokify/chathooks/pkg/adapters"
+	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/routes"
+	"github.com/grokify/chathooks/pkg/rules"
+)
+
+// BasePath is the path the admin API is served under.
+const BasePath = "/admin/api"
+
+// MaxBodySize limits admin request bodies.
+const MaxBodySize = 1 << 20
+
+// API is the admin REST API. Requests need an `Authorization: Bearer`
+// header with `Token`, and the API is disabled when `Token` is empty.
+// Changes apply immediately and, with `File`, are saved as a
+// `Snapshot`. `Statuses` are read-only JSON documents served at
+// `GET /admin/api/<name>`.
+type API struct {
+	Token        string
+	File         string
+	Routes       *routes.RouteSet
+	Tokens       *TokenSet
+	Destinations *adapters.DestinationSet
+	Statuses     map[string]func() interface{}
+	mutex        sync.Mutex
+}
+
+// apiError is a JSON error response.
+type apiError struct {
+	StatusCode int    `json:"-"`
+	Error      string `json:"error"`
+}
+
+func newAPIError(statusCode int, format string, a ...interface{}) *apiError {
+	return &apiError{StatusCode: statusCode, Error: fmt.Sprintf(format, a...)}
+}
+
+var (
+	errNotFound         = newAPIError(http.StatusNotFound, "E_NOT_FOUND")
+	errMethodNotAllowed = newAPIError(http.StatusMethodNotAllowed, "E_METHOD_NOT_ALLOWED")
+)
+
+func (api *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, BasePath), "/")
+	if status, ok := api.Statuses[path]; ok {
+		api.serveStatus(w, r, status)
+		return
+	}
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	if len(api.Token) == 0 {
+		writeJSON(w, http.StatusNotFound, newAPIError(http.StatusNotFound, "E_ADMIN_API_DISABLED"))
+		return
+	} else if path == "openapi.json" && r.Method == http.MethodGet {
+		w.Header().Set(hum.HeaderContentType, hum.ContentTypeAppJsonUtf8)
+		w.Write([]byte(OpenAPISpec))
+		return
+	}
+	if !authorized(r, api.Token) {
+		writeJSON(w, http.StatusUnauthorized, newAPIError(http.StatusUnauthorized, "E_UNAUTHORIZED"))
+		return
+	}
+	parts := strings.Split(path, "/")
+
+	var status int
+	var body interface{}
+	var apiErr *apiError
+	switch {
+	case parts[0] == "routes" && len(parts) <= 2:
+		status, body, apiErr = api.handleRoutes(r, parts[1:])
+	case parts[0] == "routes" && len(parts) <= 4 && parts[2] == "filters":
+		status, body, apiErr = api.handleFilters(r, parts[1], parts[3:])
+	case parts[0] == "tokens" && len(parts) == 1:
+		status, body, apiErr = api.handleTokens(r)
+	case parts[0] == "outputs" && len(parts) <= 2:
+		status, body, apiErr = api.handleOutputs(r, parts[1:])
+	default:
+		apiErr = errNotFound
+	}
+	if apiErr != nil {
+		writeJSON(w, apiErr.StatusCode, apiErr)
+		return
+	}
+	if r.Method != http.MethodGet {
+		if err := api.save(); err != nil {
+			log.Error().Err(err).Str("file", api.File).Msg("E_ADMIN_SAVE")
+			writeJSON(w, http.StatusInternalServerError,
+				newAPIError(http.StatusInternalServerError, "E_ADMIN_SAVE [%s]", err.Error()))
+			return
+		}
+		log.Info().
+			Str("method", r.Method).
+			Str("path", parts[0]).
+			Msg("admin configuration changed")
+	}
+	writeJSON(w, status, body)
+}
+
+// serveStatus serves a status document. The status function runs
+// without the API lock, as it may wait for a reload that calls `Use`.
+func (api *API) serveStatus(w http.ResponseWriter, r *http.Request, status func() interface{}) {
+	api.mutex.Lock()
+	token := api.Token
+	api.mutex.Unlock()
+	if len(token) == 0 {
+		writeJSON(w, http.StatusNotFound, newAPIError(http.StatusNotFound, "E_ADMIN_API_DISABLED"))
+	} else if !authorized(r, token) {
+		writeJSON(w, http.StatusUnauthorized, newAPIError(http.StatusUnauthorized, "E_UNAUTHORIZED"))
+	} else if r.Method != http.MethodGet {
+		writeJSON(w, errMethodNotAllowed.StatusCode, errMethodNotAllowed)
+	} else {
+		writeJSON(w, http.StatusOK, status())
+	}
+}
+
+func authorized(r *http.Request, token string) bool {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) == 1
+}
+
+// Use switches the API to reloaded sets. The saved snapshot, if any,
+// is applied to them first and `applied` is true. On error, the API
+// keeps its current sets.
+func (api *API) Use(token string, routeSet *routes.RouteSet, tokens *TokenSet, dests *adapters.DestinationSet) (applied bool, err error) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	if len(api.File) > 0 {
+		snap, ok, err := ReadSnapshotFile(api.File)
+		if err != nil {
+			return false, err
+		} else if ok {
+			if err := snap.Apply(routeSet, tokens, dests); err != nil {
+				return false, err
+			}
+			applied = true
+		}
+	}
+	api.Token = token
+	api.Routes = routeSet
+	api.Tokens = tokens
+	api.Destinations = dests
+	return applied, nil
+}
+
+// Snapshot returns the current configuration.
+func (api *API) Snapshot() Snapshot {
+	return Snapshot{
+		Routes:  api.Routes.List(),
+		Tokens:  api.Tokens.List(),
+		Outputs: api.Destinations.List()}
+}
+
+func (api *API) save() error {
+	if len(api.File) == 0 {
+		return nil
+	}
+	return WriteSnapshotFile(api.File, api.Snapshot())
+}
+
+func (api *API) handleRoutes(r *http.Request, parts []string) (int, interface{}, *apiError) {
+	if len(parts) == 0 || len(parts[0]) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			return http.StatusOK, api.Routes.List(), nil
+		case http.MethodPost:
+			route := &routes.Route{}
+			if apiErr := readJSON(r, route); apiErr != nil {
+				return 0, nil, apiErr
+			}
+			if _, ok := api.Routes.Lookup(strings.TrimSpace(route.Name), ""); ok {
+				return 0, nil, newAPIError(http.StatusConflict, "E_ROUTE_EXISTS [%s]", route.Name)
+			}
+			return api.addRoute(http.StatusCreated, route)
+		}
+		return 0, nil, errMethodNotAllowed
+	}
+	name := parts[0]
+	switch r.Method {
+	case http.MethodGet:
+		if route, ok := api.Routes.Lookup(name, ""); ok {
+			return http.StatusOK, route, nil
+		}
+		return 0, nil, errNotFound
+	case http.MethodPut:
+		route := &routes.Route{}
+		if apiErr := readJSON(r, route); apiErr != nil {
+			return 0, nil, apiErr
+		}
+		route.Name = name
+		return api.addRoute(http.StatusOK, route)
+	case http.MethodDelete:
+		if api.Routes.Remove(name) {
+			return http.StatusNoContent, nil, nil
+		}
+		return 0, nil, errNotFound
+	}
+	return 0, nil, errMethodNotAllowed
+}
+
+func (api *API) addRoute(status int, route *routes.Route) (int, interface{}, *apiError) {
+	if err := api.Routes.Add(route); err != nil {
+		return 0, nil, newAPIError(http.StatusBadRequest, "%s", err.Error())
+	}
+	return status, route, nil
+}
+
+// handleFilters edits the filter rules of a route. Rules are
+// addressed by name.
+func (api *API) handleFilters(r *http.Request, routeName string, parts []string) (int, interface{}, *apiError) {
+	current, ok := api.Routes.Lookup(routeName, "")
+	if !ok {
+		return 0, nil, newAPIError(http.StatusNotFound, "E_ROUTE_NOT_FOUND [%s]", routeName)
+	}
+	route, err := current.Clone()
+	if err != nil {
+		return 0, nil, newAPIError(http.StatusInternalServerError, "%s", err.Error())
+	}
+	index := -1
+	if len(parts) > 0 {
+		for i, rule := range route.Filters.Rules {
+			if rule.Name == parts[0] {
+				index = i
+			}
+		}
+	}
+
+	if len(parts) == 0 || len(parts[0]) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			return http.StatusOK, route.Filters.Rules, nil
+		case http.MethodPost:
+			rule := rules.FilterRule{}
+			if apiErr := readJSON(r, &rule); apiErr != nil {
+				return 0, nil, apiErr
+			} else if len(rule.Name) == 0 {
+				return 0, nil, newAPIError(http.StatusBadRequest, "E_FILTER_RULE_NO_NAME")
+			}
+			for _, try := range route.Filters.Rules {
+				if try.Name == rule.Name {
+					return 0, nil, newAPIError(http.StatusConflict, "E_FILTER_RULE_EXISTS [%s]", rule.Name)
+				}
+			}
+			route.Filters.Rules = append(route.Filters.Rules, rule)
+			return api.updateFilters(http.StatusCreated, route, rule)
+		}
+		return 0, nil, errMethodNotAllowed
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if index < 0 {
+			return 0, nil, errNotFound
+		}
+		return http.StatusOK, route.Filters.Rules[index], nil
+	case http.MethodPut:
+		rule := rules.FilterRule{}
+		if apiErr := readJSON(r, &rule); apiErr != nil {
+			return 0, nil, apiErr
+		}
+		rule.Name = parts[0]
+		if index < 0 {
+			route.Filters.Rules = append(route.Filters.Rules, rule)
+		} else {
+			route.Filters.Rules[index] = rule
+		}
+		return api.updateFilters(http.StatusOK, route, rule)
+	case http.MethodDelete:
+		if index < 0 {
+			return 0, nil, errNotFound
+		}
+		route.Filters.Rules = append(route.Filters.Rules[:index], route.Filters.Rules[index+1:]...)
+		status, _, apiErr := api.updateFilters(http.StatusNoContent, route, rules.FilterRule{})
+		return status, nil, apiErr
+	}
+	return 0, nil, errMethodNotAllowed
+}
+
+func (api *API) updateFilters(status int, route *routes.Route, rule rules.FilterRule) (int, interface{}, *apiError) {
+	if err := api.Routes.Add(route); err != nil {
+		return 0, nil, newAPIError(http.StatusBadRequest, "%s", err.Error())
+	}
+	return status, rule, nil
+}
+
+// handleTokens manages webhook tokens. Listed tokens are masked.
+// handleTokens takes the token to delete in the request body, so that
+// it is not written to access logs.
+func (api *API) handleTokens(r *http.Request) (int, interface{}, *apiError) {
+	req := struct {
+		Token string `json:"token"`
+	}{}
+	switch r.Method {
+	case http.MethodGet:
+		masked := []string{}
+		for _, token := range api.Tokens.List() {
+			masked = append(masked, MaskToken(token))
+		}
+		return http.StatusOK, masked, nil
+	case http.MethodPost:
+		if apiErr := readJSON(r, &req); apiErr != nil {
+			return 0, nil, apiErr
+		}
+		if err := api.Tokens.Add(req.Token); err != nil {
+			return 0, nil, newAPIError(http.StatusBadRequest, "%s", err.Error())
+		}
+		return http.StatusCreated, map[string]string{"token": MaskToken(strings.TrimSpace(req.Token))}, nil
+	case http.MethodDelete:
+		if apiErr := readJSON(r, &req); apiErr != nil {
+			return 0, nil, apiErr
+		}
+		if !api.Tokens.Has(req.Token) {
+			return 0, nil, errNotFound
+		} else if err := api.Tokens.Delete(req.Token); err != nil {
+			return 0, nil, newAPIError(http.StatusConflict, "%s", err.Error())
+		}
+		return http.StatusNoContent, nil, nil
+	}
+	return 0, nil, errMethodNotAllowed
+}
+
+func (api *API) handleOutputs(r *http.Request, parts []string) (int, interface{}, *apiError) {
+	if len(parts) == 0 || len(parts[0]) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			return http.StatusOK, api.Destinations.List(), nil
+		case http.MethodPost:
+			output := models.Output{}
+			if apiErr := readJSON(r, &output); apiErr != nil {
+				return 0, nil, apiErr
+			}
+			if _, ok := api.Destinations.Get(strings.TrimSpace(output.Name)); ok {
+				return 0, nil, newAPIError(http.StatusConflict, "E_DESTINATION_EXISTS [%s]", output.Name)
+			}
+			return api.setOutput(http.StatusCreated, output)
+		}
+		return 0, nil, errMethodNotAllowed
+	}
+	name := parts[0]
+	switch r.Method {
+	case http.MethodGet:
+		if output, ok := api.Destinations.Get(name); ok {
+			return http.StatusOK, output, nil
+		}
+		return 0, nil, errNotFound
+	case http.MethodPut:
+		output := models.Output{}
+		if apiErr := readJSON(r, &output); apiErr != nil {
+			return 0, nil, apiErr
+		}
+		output.Name = name
+		return api.setOutput(http.StatusOK, output)
+	case http.MethodDelete:
+		if api.Destinations.Delete(name) {
+			return http.StatusNoContent, nil, nil
+		}
+		return 0, nil, errNotFound
+	}
+	return 0, nil, errMethodNotAllowed
+}
+
+func (api *API) setOutput(status int, output models.Output) (int, interface{}, *apiError) {
+	if err := api.Destinations.Set(output); err != nil {
+		return 0, nil, newAPIError(http.StatusBadRequest, "%s", err.Error())
+	}
+	output, _ = api.Destinations.Get(strings.TrimSpace(output.Name))
+	return status, output, nil
+}
+
+func readJSON(r *http.Request, v interface{}) *apiError {
+	bytes, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, MaxBodySize))
+	if err != nil {
+		return newAPIError(http.StatusRequestEntityTooLarge, "E_BODY_TOO_LARGE")
+	}
+	if err := json.Unmarshal(bytes, v); err != nil {
+		return newAPIError(http.StatusBadRequest, "E_JSON_INVALID [%s]", err.Error())
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	if status == http.StatusNoContent {
+		w.WriteHeader(status)
+		return
+	}
+	bytes, err := json.Marshal(body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set(hum.HeaderContentType, hum.ContentTypeAppJsonUtf8)
+	w.WriteHeader(status)
+	w.Write(bytes)
+}
@@ -0,0 +1,169 @@
+package admin
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grokify/chathooks/pkg/adapters"
+	"github.com/grokify/chathooks/pkg/routes"
+)
+
+func newTestAPI(file string) *API {
+	return &API{
+		Token:        "admin-secret",
+		File:         file,
+		Routes:       routes.NewRouteSet(),
+		Tokens:       NewTokenSet([]string{"hook-token"}),
+		Destinations: adapters.NewDestinationSet()}
+}
+
+var APITests = []struct {
+	method     string
+	path       string
+	token      string
+	body       string
+	wantStatus int
+	wantBody   string
+}{
+	{"GET", "/admin/api/openapi.json", "", "", 200, `"openapi": "3.0.3"`},
+	{"GET", "/admin/api/routes", "", "", 401, "E_UNAUTHORIZED"},
+	{"GET", "/admin/api/routes", "wrong", "", 401, "E_UNAUTHORIZED"},
+	{"GET", "/admin/api/routes", "admin-secret", "", 200, "[]"},
+	{"POST", "/admin/api/routes", "admin-secret", `{"name":"ops","inputTypes":["pingdom"]}`, 201, `"name":"ops"`},
+	{"POST", "/admin/api/routes", "admin-secret", `{"name":"ops"}`, 409, "E_ROUTE_EXISTS"},
+	{"POST", "/admin/api/routes", "admin-secret", `{"name":""}`, 400, "E_ROUTE_NO_NAME"},
+	{"POST", "/admin/api/routes", "admin-secret", `{`, 400, "E_JSON_INVALID"},
+	{"PUT", "/admin/api/routes/dev", "admin-secret", `{"inputTypes":["travisci"]}`, 200, `"name":"dev"`},
+	{"GET", "/admin/api/routes/dev", "admin-secret", "", 200, `"travisci"`},
+	{"POST", "/admin/api/routes/ops/filters", "admin-secret", `{"name":"quiet","when":[{"path":"state","value":"UP"}],"action":"drop"}`, 201, `"name":"quiet"`},
+	{"POST", "/admin/api/routes/ops/filters", "admin-secret", `{"name":"bad","action":"explode"}`, 400, "E_FILTER_ACTION"},
+	{"GET", "/admin/api/routes/ops", "admin-secret", "", 200, `"quiet"`},
+	{"PUT", "/admin/api/routes/ops/filters/quiet", "admin-secret", `{"action":"allow"}`, 200, `"action":"allow"`},
+	{"DELETE", "/admin/api/routes/ops/filters/quiet", "admin-secret", "", 204, ""},
+	{"GET", "/admin/api/routes/ops/filters/quiet", "admin-secret", "", 404, "E_NOT_FOUND"},
+	{"GET", "/admin/api/routes/none/filters", "admin-secret", "", 404, "E_ROUTE_NOT_FOUND"},
+	{"DELETE", "/admin/api/routes/dev", "admin-secret", "", 204, ""},
+	{"DELETE", "/admin/api/routes/dev", "admin-secret", "", 404, "E_NOT_FOUND"},
+	{"GET", "/admin/api/tokens", "admin-secret", "", 200, `["hook******"]`},
+	{"POST", "/admin/api/tokens", "admin-secret", `{"token":"  "}`, 400, "E_TOKEN_EMPTY"},
+	{"POST", "/admin/api/tokens", "admin-secret", `{"token":"second"}`, 201, `"seco**"`},
+	{"DELETE", "/admin/api/tokens", "admin-secret", `{"token":"hook-token"}`, 204, ""},
+	{"DELETE", "/admin/api/tokens", "admin-secret", `{"token":"hook-token"}`, 404, "E_NOT_FOUND"},
+	{"DELETE", "/admin/api/tokens", "admin-secret", `{"token":"second"}`, 409, "E_TOKEN_LAST"},
+	{"DELETE", "/admin/api/tokens/second", "admin-secret", "", 404, "E_NOT_FOUND"},
+	{"POST", "/admin/api/outputs", "admin-secret", `{"name":"team","type":"slack","url":"https://hooks.slack.com/services/T/B/X"}`, 201, `"name":"team"`},
+	{"POST", "/admin/api/outputs", "admin-secret", `{"name":"team","type":"slack","url":"https://example.com"}`, 409, "E_DESTINATION_EXISTS"},
+	{"PUT", "/admin/api/outputs/team", "admin-secret", `{"type":"glip","url":"https://hooks.glip.com/webhook/1"}`, 200, `"type":"glip"`},
+	{"GET", "/admin/api/outputs/team", "admin-secret", "", 200, `"glip"`},
+	{"PATCH", "/admin/api/outputs/team", "admin-secret", "", 405, "E_METHOD_NOT_ALLOWED"},
+	{"GET", "/admin/api/unknown", "admin-secret", "", 404, "E_NOT_FOUND"}}
+
+func TestAPI(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chathooks-admin")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): want no error, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "admin.json")
+	api := newTestAPI(file)
+
+	for _, tt := range APITests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		if len(tt.token) > 0 {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, req)
+		if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantBody) {
+			t.Errorf("API.ServeHTTP(%v %v): want %v %v, got %v %v",
+				tt.method, tt.path, tt.wantStatus, tt.wantBody, rec.Code, rec.Body.String())
+		}
+	}
+
+	if !api.Tokens.Has("second") || api.Tokens.Has("hook-token") {
+		t.Errorf("API tokens: want [second], got %v", api.Tokens.List())
+	}
+	if route, ok := api.Routes.Lookup("", "pingdom"); !ok || route.Name != "ops" {
+		t.Errorf("API routes: want ops for pingdom, got %v", route)
+	}
+
+	snap, ok, err := ReadSnapshotFile(file)
+	if err != nil || !ok {
+		t.Fatalf("ReadSnapshotFile(): want snapshot, got %v %v", ok, err)
+	}
+	restored := newTestAPI("")
+	if err := snap.Apply(restored.Routes, restored.Tokens, restored.Destinations); err != nil {
+		t.Fatalf("Snapshot.Apply(): want no error, got %v", err)
+	}
+	want, _ := json.Marshal(api.Snapshot())
+	got, _ := json.Marshal(restored.Snapshot())
+	if string(got) != string(want) {
+		t.Errorf("Snapshot.Apply(): want %s, got %s", want, got)
+	}
+}
+
+func TestAPIDisabled(t *testing.T) {
+	api := newTestAPI("")
+	api.Token = ""
+	req := httptest.NewRequest(http.MethodGet, "/admin/api/routes", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("API.ServeHTTP(disabled): want %v, got %v", http.StatusNotFound, rec.Code)
+	}
+}
+
+var statusTests = []struct {
+	method     string
+	adminToken string
+	token      string
+	wantStatus int
+	wantBody   string
+}{
+	{"GET", "admin-secret", "admin-secret", 200, `{"ok":true}`},
+	{"GET", "admin-secret", "", 401, "E_UNAUTHORIZED"},
+	{"GET", "admin-secret", "hook-token", 401, "E_UNAUTHORIZED"},
+	{"POST", "admin-secret", "admin-secret", 405, "E_METHOD_NOT_ALLOWED"},
+	{"GET", "", "", 404, "E_ADMIN_API_DISABLED"}}
+
+func TestAPIStatuses(t *testing.T) {
+	api := newTestAPI("")
+	api.Statuses = map[string]func() interface{}{
+		"ratelimits": func() interface{} { return map[string]bool{"ok": true} }}
+	for _, tt := range statusTests {
+		api.Token = tt.adminToken
+		req := httptest.NewRequest(tt.method, "/admin/api/ratelimits", nil)
+		if len(tt.token) > 0 {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, req)
+		if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantBody) {
+			t.Errorf("API.ServeHTTP(%v ratelimits, %v): want %v %v, got %v %v",
+				tt.method, tt.token, tt.wantStatus, tt.wantBody, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestSnapshotApplyNoTokens(t *testing.T) {
+	api := newTestAPI("")
+	if err := (Snapshot{}).Apply(api.Routes, api.Tokens, api.Destinations); err != nil {
+		t.Fatalf("Snapshot.Apply(): want no error, got %v", err)
+	}
+	if !api.Tokens.Has("hook-token") {
+		t.Errorf("Snapshot.Apply(no tokens): want [hook-token], got %v", api.Tokens.List())
+	}
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	if !json.Valid([]byte(OpenAPISpec)) {
+		t.Errorf("OpenAPISpec: want valid JSON, got invalid")
+	}
+}
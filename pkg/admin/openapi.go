@@ -0,0 +1,85 @@
+package admin
+
+// OpenAPISpec describes the admin API. It is served without
+// authentication at `/admin/api/openapi.json`.
+const OpenAPISpec = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "Chathooks Admin API",
+    "version": "1.0.0",
+    "description": "Runtime configuration of routes, filter rules, webhook tokens and output destinations. Changes apply without a restart."
+  },
+  "servers": [{"url": "/admin/api"}],
+  "security": [{"bearerAuth": []}],
+  "paths": {
+    "/routes": {
+      "get": {"summary": "List routes", "responses": {"200": {"description": "Routes", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Route"}}}}}, "401": {"$ref": "#/components/responses/Error"}}},
+      "post": {"summary": "Create a route", "requestBody": {"$ref": "#/components/requestBodies/Route"}, "responses": {"201": {"$ref": "#/components/responses/Route"}, "400": {"$ref": "#/components/responses/Error"}, "409": {"$ref": "#/components/responses/Error"}}}
+    },
+    "/routes/{name}": {
+      "parameters": [{"$ref": "#/components/parameters/name"}],
+      "get": {"summary": "Get a route", "responses": {"200": {"$ref": "#/components/responses/Route"}, "404": {"$ref": "#/components/responses/Error"}}},
+      "put": {"summary": "Create or replace a route", "requestBody": {"$ref": "#/components/requestBodies/Route"}, "responses": {"200": {"$ref": "#/components/responses/Route"}, "400": {"$ref": "#/components/responses/Error"}}},
+      "delete": {"summary": "Delete a route", "responses": {"204": {"description": "Deleted"}, "404": {"$ref": "#/components/responses/Error"}}}
+    },
+    "/routes/{name}/filters": {
+      "parameters": [{"$ref": "#/components/parameters/name"}],
+      "get": {"summary": "List filter rules", "responses": {"200": {"description": "Filter rules", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/FilterRule"}}}}}, "404": {"$ref": "#/components/responses/Error"}}},
+      "post": {"summary": "Append a filter rule", "requestBody": {"$ref": "#/components/requestBodies/FilterRule"}, "responses": {"201": {"$ref": "#/components/responses/FilterRule"}, "400": {"$ref": "#/components/responses/Error"}, "409": {"$ref": "#/components/responses/Error"}}}
+    },
+    "/routes/{name}/filters/{rule}": {
+      "parameters": [{"$ref": "#/components/parameters/name"}, {"name": "rule", "in": "path", "required": true, "schema": {"type": "string"}}],
+      "get": {"summary": "Get a filter rule", "responses": {"200": {"$ref": "#/components/responses/FilterRule"}, "404": {"$ref": "#/components/responses/Error"}}},
+      "put": {"summary": "Replace or append a filter rule", "requestBody": {"$ref": "#/components/requestBodies/FilterRule"}, "responses": {"200": {"$ref": "#/components/responses/FilterRule"}, "400": {"$ref": "#/components/responses/Error"}}},
+      "delete": {"summary": "Delete a filter rule", "responses": {"204": {"description": "Deleted"}, "404": {"$ref": "#/components/responses/Error"}}}
+    },
+    "/tokens": {
+      "get": {"summary": "List masked webhook tokens", "responses": {"200": {"description": "Masked tokens", "content": {"application/json": {"schema": {"type": "array", "items": {"type": "string"}}}}}}},
+      "post": {"summary": "Add a webhook token", "requestBody": {"$ref": "#/components/requestBodies/Token"}, "responses": {"201": {"description": "Added"}, "400": {"$ref": "#/components/responses/Error"}}},
+      "delete": {"summary": "Delete a webhook token. The last token cannot be deleted.", "requestBody": {"$ref": "#/components/requestBodies/Token"}, "responses": {"204": {"description": "Deleted"}, "404": {"$ref": "#/components/responses/Error"}, "409": {"$ref": "#/components/responses/Error"}}}
+    },
+    "/outputs": {
+      "get": {"summary": "List output destinations", "responses": {"200": {"description": "Destinations", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Output"}}}}}}},
+      "post": {"summary": "Create an output destination", "requestBody": {"$ref": "#/components/requestBodies/Output"}, "responses": {"201": {"$ref": "#/components/responses/Output"}, "400": {"$ref": "#/components/responses/Error"}, "409": {"$ref": "#/components/responses/Error"}}}
+    },
+    "/outputs/{name}": {
+      "parameters": [{"$ref": "#/components/parameters/name"}],
+      "get": {"summary": "Get an output destination", "responses": {"200": {"$ref": "#/components/responses/Output"}, "404": {"$ref": "#/components/responses/Error"}}},
+      "put": {"summary": "Create or replace an output destination", "requestBody": {"$ref": "#/components/requestBodies/Output"}, "responses": {"200": {"$ref": "#/components/responses/Output"}, "400": {"$ref": "#/components/responses/Error"}}},
+      "delete": {"summary": "Delete an output destination", "responses": {"204": {"description": "Deleted"}, "404": {"$ref": "#/components/responses/Error"}}}
+    },
+    "/ratelimits": {
+      "get": {"summary": "Get the route and output rate limit state", "responses": {"200": {"description": "Rate limit state with masked output URLs", "content": {"application/json": {"schema": {"type": "object"}}}}, "401": {"$ref": "#/components/responses/Error"}}}
+    },
+    "/reload": {
+      "get": {"summary": "Get the configuration reload status", "responses": {"200": {"description": "Reload status", "content": {"application/json": {"schema": {"type": "object"}}}}, "401": {"$ref": "#/components/responses/Error"}}}
+    },
+    "/tenants": {
+      "get": {"summary": "List tenant quotas and request counts", "responses": {"200": {"description": "Tenant statuses with masked output URL patterns", "content": {"application/json": {"schema": {"type": "array", "items": {"type": "object"}}}}}, "401": {"$ref": "#/components/responses/Error"}}}
+    }
+  },
+  "components": {
+    "securitySchemes": {"bearerAuth": {"type": "http", "scheme": "bearer"}},
+    "parameters": {"name": {"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}},
+    "schemas": {
+      "Error": {"type": "object", "properties": {"error": {"type": "string", "example": "E_NOT_FOUND"}}},
+      "Output": {"type": "object", "required": ["name", "type"], "properties": {"name": {"type": "string"}, "type": {"type": "string", "example": "slack"}, "url": {"type": "string"}, "channel": {"type": "string"}, "thread": {"type": "string", "enum": ["reply", "update"]}}},
+      "Condition": {"type": "object", "properties": {"path": {"type": "string"}, "op": {"type": "string"}, "value": {}}},
+      "FilterRule": {"type": "object", "required": ["name", "action"], "properties": {"name": {"type": "string"}, "when": {"type": "array", "items": {"$ref": "#/components/schemas/Condition"}}, "action": {"type": "string", "enum": ["allow", "drop", "route"]}, "outputs": {"type": "array", "items": {"$ref": "#/components/schemas/Output"}}}},
+      "Route": {"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}, "inputTypes": {"type": "array", "items": {"type": "string"}}, "filters": {"type": "object", "properties": {"rules": {"type": "array", "items": {"$ref": "#/components/schemas/FilterRule"}}, "default": {"type": "string", "enum": ["allow", "drop"]}}}, "dedup": {"type": "object"}, "severity": {"type": "array", "items": {"type": "object"}}, "routing": {"type": "object"}, "digest": {"type": "object"}, "rateLimit": {"type": "object"}, "verboseResponse": {"type": "boolean"}, "maxBodySize": {"type": "integer", "minimum": 0}}}
+    },
+    "requestBodies": {
+      "Route": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Route"}}}},
+      "FilterRule": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/FilterRule"}}}},
+      "Output": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Output"}}}},
+      "Token": {"required": true, "content": {"application/json": {"schema": {"type": "object", "required": ["token"], "properties": {"token": {"type": "string"}}}}}}
+    },
+    "responses": {
+      "Error": {"description": "Error", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}},
+      "Route": {"description": "Route", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Route"}}}},
+      "FilterRule": {"description": "Filter rule", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/FilterRule"}}}},
+      "Output": {"description": "Output destination", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Output"}}}}
+    }
+  }
+}
+`
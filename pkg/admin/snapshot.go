@@ -0,0 +1,81 @@
+package admin
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/grokify/chathooks/pkg/adapters"
+	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/routes"
+	"github.com/grokify/chathooks/pkg/state"
+)
+
+// Snapshot is the runtime configuration managed by the admin API.
+// Once saved, it replaces the routes file and `CHATHOOKS_TOKENS` on
+// startup.
+type Snapshot struct {
+	Routes  []*routes.Route `json:"routes"`
+	Tokens  []string        `json:"tokens"`
+	Outputs []models.Output `json:"outputs"`
+}
+
+// ReadSnapshotFile reads a snapshot. It returns false if the file
+// does not exist.
+func ReadSnapshotFile(path string) (Snapshot, bool, error) {
+	snap := Snapshot{}
+	bytes, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return snap, false, nil
+	} else if err != nil {
+		return snap, false, err
+	}
+	return snap, true, json.Unmarshal(bytes, &snap)
+}
+
+// WriteSnapshotFile saves a snapshot atomically. It contains tokens
+// and webhook URLs, and like all `state.WriteFileAtomic` files is only
+// readable by the owner.
+func WriteSnapshotFile(path string, snap Snapshot) error {
+	bytes, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	return state.WriteFileAtomic(path, bytes)
+}
+
+// Apply replaces the configuration in the sets with the snapshot. The
+// snapshot is validated first, so nothing changes on error. Tokens are
+// kept when the snapshot has none, so that auth stays required.
+func (snap Snapshot) Apply(routeSet *routes.RouteSet, tokens *TokenSet, dests *adapters.DestinationSet) error {
+	for _, route := range snap.Routes {
+		if err := route.Compile(); err != nil {
+			return err
+		}
+	}
+	newDests := adapters.NewDestinationSet()
+	for _, output := range snap.Outputs {
+		if err := newDests.Set(output); err != nil {
+			return err
+		}
+	}
+
+	for _, route := range routeSet.List() {
+		routeSet.Remove(route.Name)
+	}
+	for _, route := range snap.Routes {
+		if err := routeSet.Add(route); err != nil {
+			return err
+		}
+	}
+	if len(snap.Tokens) > 0 {
+		tokens.Replace(snap.Tokens)
+	}
+	for _, output := range dests.List() {
+		dests.Delete(output.Name)
+	}
+	for _, output := range newDests.List() {
+		dests.Set(output)
+	}
+	return nil
+}
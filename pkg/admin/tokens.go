@@ -0,0 +1,90 @@
+package admin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/grokify/chathooks/pkg/redact"
+)
+
+// TokenSet holds the tokens accepted by the webhook endpoints. It is
+// safe for concurrent use.
+type TokenSet struct {
+	mutex  sync.RWMutex
+	tokens map[string]bool
+}
+
+func NewTokenSet(tokens []string) *TokenSet {
+	set := &TokenSet{tokens: map[string]bool{}}
+	for _, token := range tokens {
+		set.Add(token)
+	}
+	return set
+}
+
+func (set *TokenSet) Has(token string) bool {
+	set.mutex.RLock()
+	defer set.mutex.RUnlock()
+	return set.tokens[token]
+}
+
+func (set *TokenSet) Len() int {
+	set.mutex.RLock()
+	defer set.mutex.RUnlock()
+	return len(set.tokens)
+}
+
+// Add adds a token. Surrounding spaces are removed.
+func (set *TokenSet) Add(token string) error {
+	token = strings.TrimSpace(token)
+	if len(token) == 0 {
+		return fmt.Errorf("E_TOKEN_EMPTY")
+	}
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	set.tokens[token] = true
+	return nil
+}
+
+// Delete removes a token. The last token is kept so that the webhook
+// endpoints do not become unauthenticated.
+func (set *TokenSet) Delete(token string) error {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	if !set.tokens[token] {
+		return fmt.Errorf("E_TOKEN_NOT_FOUND")
+	} else if len(set.tokens) == 1 {
+		return fmt.Errorf("E_TOKEN_LAST")
+	}
+	delete(set.tokens, token)
+	return nil
+}
+
+// List returns the tokens sorted.
+func (set *TokenSet) List() []string {
+	set.mutex.RLock()
+	defer set.mutex.RUnlock()
+	tokens := []string{}
+	for token := range set.tokens {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+// Replace sets the tokens to `tokens`.
+func (set *TokenSet) Replace(tokens []string) {
+	set.mutex.Lock()
+	set.tokens = map[string]bool{}
+	set.mutex.Unlock()
+	for _, token := range tokens {
+		set.Add(token)
+	}
+}
+
+// MaskToken shows only the first four characters of a token.
+func MaskToken(token string) string {
+	return redact.Token(token)
+}
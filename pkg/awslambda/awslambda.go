@@ -0,0 +1,315 @@
+// Package awslambda serves an `http.Handler` on AWS Lambda. It accepts
+// API Gateway REST API (v1) and HTTP API (v2) events, Lambda Function
+// URL events and ALB target group events, and responds in the shape of
+// the request's event.
+package awslambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+// EventType is the type of a Lambda HTTP event.
+type EventType string
+
+const (
+	EventAPIGatewayV1 EventType = "apigateway_v1"
+	EventAPIGatewayV2 EventType = "apigateway_v2"
+	EventFunctionURL  EventType = "function_url"
+	EventALB          EventType = "alb"
+)
+
+type eventProbe struct {
+	Version        string `json:"version"`
+	HTTPMethod     string `json:"httpMethod"`
+	RequestContext struct {
+		DomainName string          `json:"domainName"`
+		ELB        json.RawMessage `json:"elb"`
+		HTTP       json.RawMessage `json:"http"`
+	} `json:"requestContext"`
+}
+
+// DetectEventType returns the type of a Lambda event. Function URL
+// events have the HTTP API v2 format and are told apart by their
+// `lambda-url` domain.
+func DetectEventType(payload []byte) (EventType, error) {
+	probe := eventProbe{}
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		return "", fmt.Errorf("E_LAMBDA_EVENT_NOT_VALID [%s]", err.Error())
+	}
+	switch {
+	case len(probe.RequestContext.ELB) > 0:
+		return EventALB, nil
+	case probe.Version == "2.0" || len(probe.RequestContext.HTTP) > 0:
+		if strings.Contains(probe.RequestContext.DomainName, ".lambda-url.") {
+			return EventFunctionURL, nil
+		}
+		return EventAPIGatewayV2, nil
+	case len(probe.HTTPMethod) > 0:
+		return EventAPIGatewayV1, nil
+	}
+	return "", fmt.Errorf("E_LAMBDA_EVENT_NOT_SUPPORTED")
+}
+
+// Request is an HTTP request built from a Lambda event.
+type Request struct {
+	EventType EventType
+	HTTP      *http.Request
+	// multiValue is set for ALB events from target groups with
+	// multi-value headers, which must be answered in kind.
+	multiValue bool
+}
+
+// NewRequest builds an HTTP request from a Lambda event. If the event
+// type is known but the request cannot be built, e.g. for a body that
+// is not valid base64, the request is returned without `HTTP` along
+// with the error.
+func NewRequest(ctx context.Context, payload []byte) (*Request, error) {
+	eventType, err := DetectEventType(payload)
+	if err != nil {
+		return nil, err
+	}
+	req := &Request{EventType: eventType}
+	switch eventType {
+	case EventAPIGatewayV1:
+		event := events.APIGatewayProxyRequest{}
+		if err = json.Unmarshal(payload, &event); err == nil {
+			req.HTTP, err = newRequestV1(ctx, event)
+		}
+	case EventALB:
+		event := events.ALBTargetGroupRequest{}
+		if err = json.Unmarshal(payload, &event); err == nil {
+			req.HTTP, err = newRequestALB(ctx, event)
+			req.multiValue = len(event.MultiValueHeaders) > 0
+		}
+	default:
+		event := events.APIGatewayV2HTTPRequest{}
+		if err = json.Unmarshal(payload, &event); err == nil {
+			req.HTTP, err = newRequestV2(ctx, event)
+		}
+	}
+	return req, err
+}
+
+func newRequestV1(ctx context.Context, event events.APIGatewayProxyRequest) (*http.Request, error) {
+	query := url.Values(event.MultiValueQueryStringParameters)
+	if len(query) == 0 {
+		query = url.Values{}
+		for key, val := range event.QueryStringParameters {
+			query.Set(key, val)
+		}
+	}
+	req, err := newHTTPRequest(ctx, event.HTTPMethod, event.Path, query.Encode(),
+		mergeHeaders(event.Headers, event.MultiValueHeaders), event.Body, event.IsBase64Encoded)
+	if err != nil {
+		return nil, err
+	}
+	req.RemoteAddr = event.RequestContext.Identity.SourceIP
+	return req, nil
+}
+
+func newRequestV2(ctx context.Context, event events.APIGatewayV2HTTPRequest) (*http.Request, error) {
+	path := event.RawPath
+	if len(path) == 0 {
+		path = event.RequestContext.HTTP.Path
+	}
+	// Paths of named stages start with the stage, e.g. `/prod/hook`.
+	if stage := event.RequestContext.Stage; len(stage) > 0 && stage != "$default" {
+		if path == "/"+stage {
+			path = "/"
+		} else if strings.HasPrefix(path, "/"+stage+"/") {
+			path = strings.TrimPrefix(path, "/"+stage)
+		}
+	}
+	headers := mergeHeaders(event.Headers, nil)
+	if len(event.Cookies) > 0 {
+		headers.Set("Cookie", strings.Join(event.Cookies, "; "))
+	}
+	req, err := newHTTPRequest(ctx, event.RequestContext.HTTP.Method, path, event.RawQueryString,
+		headers, event.Body, event.IsBase64Encoded)
+	if err != nil {
+		return nil, err
+	}
+	req.RemoteAddr = event.RequestContext.HTTP.SourceIP
+	return req, nil
+}
+
+// newRequestALB builds a request from an ALB event. ALB passes query
+// parameters as received, i.e. still URL encoded. The client address
+// is the last `X-Forwarded-For` entry.
+func newRequestALB(ctx context.Context, event events.ALBTargetGroupRequest) (*http.Request, error) {
+	params := []string{}
+	for key, vals := range event.MultiValueQueryStringParameters {
+		for _, val := range vals {
+			params = append(params, key+"="+val)
+		}
+	}
+	for key, val := range event.QueryStringParameters {
+		params = append(params, key+"="+val)
+	}
+	sort.Strings(params)
+	req, err := newHTTPRequest(ctx, event.HTTPMethod, event.Path, strings.Join(params, "&"),
+		mergeHeaders(event.Headers, event.MultiValueHeaders), event.Body, event.IsBase64Encoded)
+	if err != nil {
+		return nil, err
+	}
+	if forwarded := strings.Split(req.Header.Get("X-Forwarded-For"), ","); len(forwarded) > 0 {
+		req.RemoteAddr = strings.TrimSpace(forwarded[len(forwarded)-1])
+	}
+	return req, nil
+}
+
+func mergeHeaders(single map[string]string, multi map[string][]string) http.Header {
+	headers := http.Header{}
+	for key, vals := range multi {
+		for _, val := range vals {
+			headers.Add(key, val)
+		}
+	}
+	if len(headers) == 0 {
+		for key, val := range single {
+			headers.Set(key, val)
+		}
+	}
+	return headers
+}
+
+func newHTTPRequest(ctx context.Context, method, path, rawQuery string, headers http.Header, body string, isBase64Encoded bool) (*http.Request, error) {
+	bodyBytes := []byte(body)
+	if isBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, fmt.Errorf("E_LAMBDA_BODY_BASE64_NOT_VALID")
+		}
+		bodyBytes = decoded
+	}
+	if len(path) == 0 {
+		path = "/"
+	}
+	u := &url.URL{Path: path, RawQuery: rawQuery}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = headers
+	req.Host = headers.Get("Host")
+	req.RequestURI = u.RequestURI()
+	return req, nil
+}
+
+// responseWriter buffers an `http.Handler` response.
+type responseWriter struct {
+	header     http.Header
+	body       bytes.Buffer
+	statusCode int
+}
+
+func (w *responseWriter) Header() http.Header { return w.header }
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.body.Write(b)
+}
+
+func (w *responseWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+	}
+}
+
+// textContentType returns whether a response body can be returned
+// without base64 encoding.
+func textContentType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, prefix := range []string{"text/", "application/json", "application/javascript", "application/xml", "application/x-www-form-urlencoded"} {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return len(contentType) == 0 || strings.Contains(contentType, "+json") || strings.Contains(contentType, "+xml")
+}
+
+// Serve handles `req` with `handler` and returns the response in the
+// shape of the request's event.
+func Serve(handler http.Handler, req *Request) interface{} {
+	w := &responseWriter{header: http.Header{}}
+	handler.ServeHTTP(w, req.HTTP)
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	return req.response(w.statusCode, w.header, w.body.Bytes())
+}
+
+func (req *Request) response(statusCode int, header http.Header, body []byte) interface{} {
+	isBase64 := !textContentType(header.Get("Content-Type"))
+	bodyString := string(body)
+	if isBase64 {
+		bodyString = base64.StdEncoding.EncodeToString(body)
+	}
+	headers := map[string]string{}
+	for key, vals := range header {
+		if key != "Set-Cookie" || req.EventType == EventAPIGatewayV1 || req.EventType == EventALB {
+			headers[key] = strings.Join(vals, ",")
+		}
+	}
+	switch req.EventType {
+	case EventAPIGatewayV2, EventFunctionURL:
+		return events.APIGatewayV2HTTPResponse{
+			StatusCode:      statusCode,
+			Headers:         headers,
+			Body:            bodyString,
+			IsBase64Encoded: isBase64,
+			Cookies:         header.Values("Set-Cookie")}
+	case EventALB:
+		res := events.ALBTargetGroupResponse{
+			StatusCode:        statusCode,
+			StatusDescription: fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+			Body:              bodyString,
+			IsBase64Encoded:   isBase64}
+		if req.multiValue {
+			res.MultiValueHeaders = header
+		} else {
+			res.Headers = headers
+		}
+		return res
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode:        statusCode,
+		Headers:           headers,
+		MultiValueHeaders: header,
+		Body:              bodyString,
+		IsBase64Encoded:   isBase64}
+}
+
+// NewHandler returns a Lambda handler serving `handler`. Events that
+// are not HTTP events are rejected with an error, and HTTP events that
+// are not valid with a 400 response.
+func NewHandler(handler http.Handler) func(context.Context, json.RawMessage) (interface{}, error) {
+	return func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		req, err := NewRequest(ctx, payload)
+		if req == nil {
+			return nil, err
+		} else if err != nil {
+			header := http.Header{"Content-Type": {"text/plain; charset=utf-8"}}
+			return req.response(http.StatusBadRequest, header, []byte(err.Error())), nil
+		}
+		return Serve(handler, req), nil
+	}
+}
+
+// Start runs `handler` on Lambda.
+func Start(handler http.Handler) {
+	lambda.Start(NewHandler(handler))
+}
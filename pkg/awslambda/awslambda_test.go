@@ -0,0 +1,153 @@
+package awslambda
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// echoHandler responds with the request as seen by an `http.Handler`.
+var echoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("Set-Cookie", "a=1")
+	w.Header().Add("Set-Cookie", "b=2")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(echo{
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Query:      r.URL.Query(),
+		Cookie:     r.Header.Get("Cookie"),
+		Host:       r.Host,
+		RemoteAddr: r.RemoteAddr,
+		Body:       string(body)})
+})
+
+type echo struct {
+	Method     string
+	Path       string
+	Query      map[string][]string
+	Cookie     string
+	Host       string
+	RemoteAddr string
+	Body       string
+}
+
+var handlerTests = []struct {
+	fixture    string
+	wantType   EventType
+	wantQuery  map[string][]string
+	wantCookie string
+}{
+	{"apigateway_v1.json", EventAPIGatewayV1, map[string][]string{"inputType": {"slack"}, "tag": {"a", "b"}}, ""},
+	{"apigateway_v2.json", EventAPIGatewayV2, map[string][]string{"inputType": {"slack"}, "tag": {"a", "b"}}, "session=abc; theme=dark"},
+	{"apigateway_v2_stage.json", EventAPIGatewayV2, map[string][]string{"inputType": {"slack"}, "tag": {"a", "b"}}, "session=abc; theme=dark"},
+	{"function_url.json", EventFunctionURL, map[string][]string{"inputType": {"slack"}, "tag": {"a", "b"}}, ""},
+	{"alb.json", EventALB, map[string][]string{"inputType": {"slack"}, "tag": {"a b"}}, ""},
+	{"alb_multi_value.json", EventALB, map[string][]string{"inputType": {"slack"}, "tag": {"a", "b"}}, ""}}
+
+func TestHandler(t *testing.T) {
+	handler := NewHandler(echoHandler)
+	for _, tt := range handlerTests {
+		payload, err := ioutil.ReadFile(filepath.Join("testdata", tt.fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := DetectEventType(payload); err != nil || got != tt.wantType {
+			t.Errorf("DetectEventType(%v): want %v, got %v (%v)", tt.fixture, tt.wantType, got, err)
+		}
+		res, err := handler(context.Background(), payload)
+		if err != nil {
+			t.Errorf("Handler(%v): want no error, got %v", tt.fixture, err)
+			continue
+		}
+		var statusCode int
+		var body string
+		var isBase64 bool
+		var cookies []string
+		switch tt.wantType {
+		case EventAPIGatewayV1:
+			r, ok := res.(events.APIGatewayProxyResponse)
+			if !ok {
+				t.Errorf("Handler(%v): want APIGatewayProxyResponse, got %T", tt.fixture, res)
+				continue
+			}
+			statusCode, body, isBase64, cookies = r.StatusCode, r.Body, r.IsBase64Encoded, r.MultiValueHeaders["Set-Cookie"]
+		case EventAPIGatewayV2, EventFunctionURL:
+			r, ok := res.(events.APIGatewayV2HTTPResponse)
+			if !ok {
+				t.Errorf("Handler(%v): want APIGatewayV2HTTPResponse, got %T", tt.fixture, res)
+				continue
+			}
+			if _, ok := r.Headers["Set-Cookie"]; ok {
+				t.Errorf("Handler(%v): want Set-Cookie in cookies only, got header", tt.fixture)
+			}
+			statusCode, body, isBase64, cookies = r.StatusCode, r.Body, r.IsBase64Encoded, r.Cookies
+		case EventALB:
+			r, ok := res.(events.ALBTargetGroupResponse)
+			if !ok {
+				t.Errorf("Handler(%v): want ALBTargetGroupResponse, got %T", tt.fixture, res)
+				continue
+			}
+			if r.StatusDescription != "202 Accepted" {
+				t.Errorf("Handler(%v): want status description 202 Accepted, got %v", tt.fixture, r.StatusDescription)
+			}
+			cookies = r.MultiValueHeaders["Set-Cookie"]
+			if len(r.MultiValueHeaders) == 0 {
+				cookies = []string{r.Headers["Set-Cookie"]}
+			}
+			statusCode, body, isBase64 = r.StatusCode, r.Body, r.IsBase64Encoded
+		}
+		if statusCode != http.StatusAccepted || isBase64 {
+			t.Errorf("Handler(%v): want 202 text, got %v base64 %v", tt.fixture, statusCode, isBase64)
+		}
+		if len(cookies) == 0 || cookies[0] == "" {
+			t.Errorf("Handler(%v): want Set-Cookie, got none", tt.fixture)
+		}
+		got := echo{}
+		if err := json.Unmarshal([]byte(body), &got); err != nil {
+			t.Errorf("Handler(%v): want JSON body, got %v", tt.fixture, body)
+			continue
+		}
+		if got.Method != http.MethodPost || got.Path != "/hook" || got.Body != `{"text":"hello"}` ||
+			got.RemoteAddr != "203.0.113.7" || got.Host == "" {
+			t.Errorf("Handler(%v): want POST /hook 203.0.113.7 hello, got %v %v %v %v", tt.fixture,
+				got.Method, got.Path, got.RemoteAddr, got.Body)
+		}
+		if !reflect.DeepEqual(got.Query, tt.wantQuery) || got.Cookie != tt.wantCookie {
+			t.Errorf("Handler(%v): want %v %v, got %v %v", tt.fixture, tt.wantQuery, tt.wantCookie, got.Query, got.Cookie)
+		}
+	}
+}
+
+var handlerErrorTests = []struct {
+	payload  string
+	wantCode int
+	wantErr  bool
+}{
+	{`{"version":"2.0","rawPath":"/hook","requestContext":{"http":{"method":"POST"}},"body":"%%%","isBase64Encoded":true}`, http.StatusBadRequest, false},
+	{`{"Records":[]}`, 0, true},
+	{`[`, 0, true}}
+
+func TestHandlerErrors(t *testing.T) {
+	handler := NewHandler(echoHandler)
+	for _, tt := range handlerErrorTests {
+		res, err := handler(context.Background(), json.RawMessage(tt.payload))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Handler(%v): want error, got %v", tt.payload, res)
+			}
+			continue
+		}
+		r, ok := res.(events.APIGatewayV2HTTPResponse)
+		if err != nil || !ok || r.StatusCode != tt.wantCode {
+			t.Errorf("Handler(%v): want %v, got %v (%v)", tt.payload, tt.wantCode, res, err)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Package azurefunc serves an `http.Handler` as an Azure Functions
+// custom handler. It accepts both forwarded HTTP requests, used when
+// `enableForwardingHttpRequest` is set in `host.json`, and invocation
+// requests, where the Functions host wraps the original request in a
+// JSON envelope.
+package azurefunc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	// DefaultRoutePrefix is the Functions host's default `routePrefix`.
+	DefaultRoutePrefix = "/api"
+	// OutputBinding is the HTTP output binding name expected in
+	// `function.json`.
+	OutputBinding = "res"
+
+	headerInvocationID = "X-Azure-Functions-InvocationId"
+)
+
+// Address returns the listen address from the host-provided
+// `FUNCTIONS_CUSTOMHANDLER_PORT` environment variable, else `port`.
+func Address(port int) string {
+	if hostPort := strings.TrimSpace(os.Getenv("FUNCTIONS_CUSTOMHANDLER_PORT")); len(hostPort) > 0 {
+		return ":" + hostPort
+	}
+	return fmt.Sprintf(":%d", port)
+}
+
+// InvocationRequest is the invocation request sent by the Functions
+// host. `Data` holds the trigger's input bindings by name.
+type InvocationRequest struct {
+	Data     map[string]json.RawMessage `json:"Data"`
+	Metadata map[string]json.RawMessage `json:"Metadata"`
+}
+
+// HTTPTrigger is the HTTP trigger input binding. `Url` is the original
+// request URL, including the route prefix and query string.
+type HTTPTrigger struct {
+	Url     string              `json:"Url"`
+	Method  string              `json:"Method"`
+	Query   map[string]string   `json:"Query"`
+	Headers map[string][]string `json:"Headers"`
+	Params  map[string]string   `json:"Params"`
+	Body    json.RawMessage     `json:"Body"`
+}
+
+// InvocationResponse is the invocation response returned to the
+// Functions host.
+type InvocationResponse struct {
+	Outputs     map[string]HTTPOutput `json:"Outputs"`
+	Logs        []string              `json:"Logs"`
+	ReturnValue interface{}           `json:"ReturnValue"`
+}
+
+// HTTPOutput is the HTTP output binding.
+type HTTPOutput struct {
+	StatusCode int               `json:"statusCode"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body"`
+}
+
+// NewHandler returns a custom handler for `handler`. `routePrefix` is
+// stripped from request paths. Requests under the prefix are handled as
+// forwarded requests; other requests from the Functions host are
+// handled as invocation requests. With an empty prefix, all requests
+// are handled as forwarded requests.
+func NewHandler(handler http.Handler, routePrefix string) http.Handler {
+	routePrefix = "/" + strings.Trim(routePrefix, "/")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, ok := routePath(r.URL.Path, routePrefix)
+		if !ok && len(r.Header.Get(headerInvocationID)) > 0 {
+			serveInvocation(handler, routePrefix, w, r)
+			return
+		}
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = path
+		r2.URL.RawPath = ""
+		r2.RequestURI = r2.URL.RequestURI()
+		setRemoteAddr(r2)
+		handler.ServeHTTP(w, r2)
+	})
+}
+
+func serveInvocation(handler http.Handler, routePrefix string, w http.ResponseWriter, r *http.Request) {
+	inv := InvocationRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&inv); err != nil {
+		http.Error(w, "E_AZURE_INVOCATION_NOT_VALID ["+err.Error()+"]", http.StatusBadRequest)
+		return
+	}
+	req, err := NewRequest(inv, routePrefix)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req = req.WithContext(r.Context())
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	out := HTTPOutput{
+		StatusCode: rec.Code,
+		Headers:    map[string]string{},
+		Body:       rec.Body.String()}
+	for key, vals := range rec.Header() {
+		out.Headers[key] = strings.Join(vals, ",")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(InvocationResponse{
+		Outputs: map[string]HTTPOutput{OutputBinding: out},
+		Logs:    []string{}})
+}
+
+// NewRequest builds the original HTTP request from an invocation
+// request. The HTTP trigger is the input binding with a `Url`, so any
+// binding name can be used.
+func NewRequest(inv InvocationRequest, routePrefix string) (*http.Request, error) {
+	var trigger *HTTPTrigger
+	for _, raw := range inv.Data {
+		try := HTTPTrigger{}
+		if err := json.Unmarshal(raw, &try); err == nil && len(try.Url) > 0 {
+			trigger = &try
+			break
+		}
+	}
+	if trigger == nil {
+		return nil, fmt.Errorf("E_AZURE_HTTP_TRIGGER_NOT_FOUND")
+	}
+	u, err := url.Parse(trigger.Url)
+	if err != nil {
+		return nil, fmt.Errorf("E_AZURE_URL_NOT_VALID [%s]", err.Error())
+	}
+	path, _ := routePath(u.Path, "/"+strings.Trim(routePrefix, "/"))
+	target := &url.URL{Path: path, RawQuery: u.RawQuery}
+
+	body := []byte(trigger.Body)
+	bodyString := ""
+	if err := json.Unmarshal(trigger.Body, &bodyString); err == nil {
+		body = []byte(bodyString)
+	} else if string(trigger.Body) == "null" {
+		body = []byte{}
+	}
+
+	req, err := http.NewRequest(trigger.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = http.Header{}
+	for key, vals := range trigger.Headers {
+		for _, val := range vals {
+			req.Header.Add(key, val)
+		}
+	}
+	req.Host = u.Host
+	req.RequestURI = target.RequestURI()
+	setRemoteAddr(req)
+	return req, nil
+}
+
+// setRemoteAddr sets the client address to the last `X-Forwarded-For`
+// entry, which is added by the Functions front end.
+func setRemoteAddr(r *http.Request) {
+	forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	if last := strings.TrimSpace(forwarded[len(forwarded)-1]); len(last) > 0 {
+		r.RemoteAddr = last
+	}
+}
+
+func routePath(path, routePrefix string) (string, bool) {
+	if routePrefix == "/" {
+		return path, true
+	} else if path == routePrefix {
+		return "/", true
+	} else if strings.HasPrefix(path, routePrefix+"/") {
+		return strings.TrimPrefix(path, routePrefix), true
+	}
+	return path, false
+}
@@ -0,0 +1,97 @@
+package azurefunc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// echoHandler responds with the request line, client address and body.
+var echoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte(strings.Join([]string{r.Method, r.URL.RequestURI(), r.RemoteAddr, string(body)}, " ")))
+})
+
+func TestInvocation(t *testing.T) {
+	payload, err := ioutil.ReadFile(filepath.Join("testdata", "invocation.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/hook", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(headerInvocationID, "0f2a8d8e-3a0b-4c35-9c1a-1c1bd7f5c8d1")
+	rec := httptest.NewRecorder()
+	NewHandler(echoHandler, DefaultRoutePrefix).ServeHTTP(rec, req)
+
+	res := InvocationResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("NewHandler(): want invocation response, got %v", rec.Body.String())
+	}
+	out, ok := res.Outputs[OutputBinding]
+	want := `POST /hook?inputType=slack&tag=a&tag=b 203.0.113.7:51234 {"text":"hello"}`
+	if !ok || out.StatusCode != http.StatusAccepted || out.Body != want || out.Headers["Content-Type"] != "text/plain" {
+		t.Errorf("NewHandler(): want 202 %v, got %v", want, rec.Body.String())
+	}
+}
+
+var forwardedTests = []struct {
+	prefix   string
+	target   string
+	invoked  bool
+	wantCode int
+	wantBody string
+}{
+	{"/api", "/api/hook?inputType=slack", false, http.StatusAccepted, "POST /hook?inputType=slack 203.0.113.7 hi"},
+	{"/api", "/api", false, http.StatusAccepted, "POST / 203.0.113.7 hi"},
+	{"", "/hook", true, http.StatusAccepted, "POST /hook 203.0.113.7 hi"},
+	{"/api", "/hook", false, http.StatusAccepted, "POST /hook 203.0.113.7 hi"},
+	{"/api", "/hook", true, http.StatusBadRequest, "E_AZURE_INVOCATION_NOT_VALID"}}
+
+func TestForwarded(t *testing.T) {
+	for _, tt := range forwardedTests {
+		req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader("hi"))
+		req.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.7")
+		if tt.invoked {
+			req.Header.Set(headerInvocationID, "0f2a8d8e-3a0b-4c35-9c1a-1c1bd7f5c8d1")
+		}
+		rec := httptest.NewRecorder()
+		NewHandler(echoHandler, tt.prefix).ServeHTTP(rec, req)
+		if rec.Code != tt.wantCode || !strings.HasPrefix(rec.Body.String(), tt.wantBody) {
+			t.Errorf("NewHandler(%v).ServeHTTP(%v): want %v %v, got %v %v",
+				tt.prefix, tt.target, tt.wantCode, tt.wantBody, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+var newRequestTests = []struct {
+	data    string
+	wantErr bool
+}{
+	{`{"req":{"Url":"http://localhost/api/hook","Method":"POST","Body":null}}`, false},
+	{`{"req":{"Url":"http://localhost/api/hook","Method":"POST","Body":{"text":"hi"}}}`, false},
+	{`{"other":{"Method":"POST"}}`, true},
+	{`{"req":{"Url":"http://[::1","Method":"POST"}}`, true}}
+
+func TestNewRequest(t *testing.T) {
+	for _, tt := range newRequestTests {
+		inv := InvocationRequest{}
+		json.Unmarshal([]byte(tt.data), &inv.Data)
+		req, err := NewRequest(inv, DefaultRoutePrefix)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("NewRequest(%v): want error, got none", tt.data)
+			}
+			continue
+		}
+		if err != nil || req.URL.Path != "/hook" {
+			t.Errorf("NewRequest(%v): want /hook, got %v", tt.data, err)
+		}
+	}
+}
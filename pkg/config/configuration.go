@@ -2,11 +2,11 @@ package config
 
 import (
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"net/url"
 	"os"
 	"path"
+	"time"
 
 	"github.com/caarlos0/env"
 	"github.com/rs/zerolog"
@@ -27,15 +27,45 @@ func DocsHandlersDir() string {
 
 // Configuration is the webhook proxy configuration struct.
 type Configuration struct {
-	Port           int      `env:"PORT" envDefault:"3000"`
-	Engine         string   `env:"CHATHOOKS_ENGINE" envDefault:"fasthttp"`
-	HomeUrl        string   `env:"CHATHOOKS_HOME_URL"`
-	WebhookUrl     string   `env:"CHATHOOKS_WEBHOOK_URL"`
-	Tokens         []string `env:"CHATHOOKS_TOKENS" envSeparator:","`
-	LogFormat      string   `env:"CHATHOOKS_LOG_FORMAT"`
-	EmojiURLFormat string
-	IconBaseURL    string
-	LogLevel       zerolog.Level
+	Port            int           `env:"PORT" envDefault:"3000"`
+	Engine          string        `env:"CHATHOOKS_ENGINE" envDefault:"fasthttp"`
+	HomeUrl         string        `env:"CHATHOOKS_HOME_URL"`
+	WebhookUrl      string        `env:"CHATHOOKS_WEBHOOK_URL"`
+	Tokens          []string      `env:"CHATHOOKS_TOKENS" envSeparator:","`
+	OutputHosts     []string      `env:"CHATHOOKS_OUTPUT_HOSTS" envSeparator:","`
+	OutputPrivate   bool          `env:"CHATHOOKS_OUTPUT_ALLOW_PRIVATE"`
+	LogFormat       string        `env:"CHATHOOKS_LOG_FORMAT"`
+	RedactFields    []string      `env:"CHATHOOKS_REDACT_FIELDS" envSeparator:","`
+	TemplatesFile   string        `env:"CHATHOOKS_TEMPLATES_FILE"`
+	RoutesFile      string        `env:"CHATHOOKS_ROUTES_FILE"`
+	TenantsFile     string        `env:"CHATHOOKS_TENANTS_FILE"`
+	StateFile       string        `env:"CHATHOOKS_STATE_FILE"`
+	SlackBotToken   string        `env:"CHATHOOKS_SLACK_BOT_TOKEN"`
+	SlackChannel    string        `env:"CHATHOOKS_SLACK_CHANNEL"`
+	TeamsToken      string        `env:"CHATHOOKS_TEAMS_TOKEN"`
+	TeamsTenantID   string        `env:"CHATHOOKS_TEAMS_TENANT_ID"`
+	TeamsClientID   string        `env:"CHATHOOKS_TEAMS_CLIENT_ID"`
+	TeamsSecret     string        `env:"CHATHOOKS_TEAMS_CLIENT_SECRET"`
+	TeamsRefresh    string        `env:"CHATHOOKS_TEAMS_REFRESH_TOKEN"`
+	TeamsChannel    string        `env:"CHATHOOKS_TEAMS_CHANNEL"`
+	AdminToken      string        `env:"CHATHOOKS_ADMIN_TOKEN"`
+	AdminFile       string        `env:"CHATHOOKS_ADMIN_FILE"`
+	HookKeys        []string      `env:"CHATHOOKS_HOOK_KEYS" envSeparator:","`
+	MaxBodySize     int64         `env:"CHATHOOKS_MAX_BODY_SIZE"`
+	ListenAddress   string        `env:"CHATHOOKS_LISTEN_ADDRESS"`
+	TLSCertFile     string        `env:"CHATHOOKS_TLS_CERT_FILE"`
+	TLSKeyFile      string        `env:"CHATHOOKS_TLS_KEY_FILE"`
+	TLSClientCAFile string        `env:"CHATHOOKS_TLS_CLIENT_CA_FILE"`
+	PathPrefix      string        `env:"CHATHOOKS_PATH_PREFIX"`
+	MaxInFlight     int           `env:"CHATHOOKS_MAX_IN_FLIGHT"`
+	ShutdownTimeout time.Duration `env:"CHATHOOKS_SHUTDOWN_TIMEOUT" envDefault:"25s"`
+	TraceExporter   string        `env:"CHATHOOKS_TRACE_EXPORTER"`
+	TraceEndpoint   string        `env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	TraceHeaders    []string      `env:"OTEL_EXPORTER_OTLP_HEADERS" envSeparator:","`
+	TraceService    string        `env:"OTEL_SERVICE_NAME" envDefault:"chathooks"`
+	EmojiURLFormat  string
+	IconBaseURL     string
+	LogLevel        zerolog.Level
 }
 
 func NewConfigurationEnv() (Configuration, error) {
@@ -59,9 +89,10 @@ func ReadConfigurationFile(filepath string) (Configuration, error) {
 	return configuration, err
 }
 
-// Address returns the port address as a string with a `:` prefix
+// Address returns the listen address. See `Listener`.
 func (c *Configuration) Address() string {
-	return fmt.Sprintf(":%d", c.Port)
+	_, address := c.Listener()
+	return address
 }
 
 func (c *Configuration) GetAppIconURL(appSlug string) (*url.URL, error) {
@@ -1,23 +1,57 @@
 package config
 
 import (
+	"strings"
 	"testing"
 )
 
 var ConfigurationTests = []struct {
-	v    int
-	want string
+	port        int
+	listen      string
+	wantNetwork string
+	want        string
 }{
-	{8080, ":8080"}}
+	{8080, "", "tcp", ":8080"},
+	{8080, "127.0.0.1", "tcp", "127.0.0.1:8080"},
+	{8080, "10.0.0.1:9000", "tcp", "10.0.0.1:9000"},
+	{8080, "::1", "tcp", "[::1]:8080"},
+	{8080, "[::1]", "tcp", "[::1]:8080"},
+	{8080, "unix:/var/run/chathooks.sock", "unix", "/var/run/chathooks.sock"}}
 
 func TestConfigurationAddress(t *testing.T) {
 	for _, tt := range ConfigurationTests {
 		cfg := Configuration{
-			Port: tt.v}
+			Port:          tt.port,
+			ListenAddress: tt.listen}
 
-		addr := cfg.Address()
-		if tt.want != addr {
-			t.Errorf("Configuration.Address(%v): want %v, got %v", tt.v, tt.want, addr)
+		network, addr := cfg.Listener()
+		if tt.wantNetwork != network || tt.want != addr || tt.want != cfg.Address() {
+			t.Errorf("Configuration.Listener(%v, %v): want %v %v, got %v %v", tt.port, tt.listen, tt.wantNetwork, tt.want, network, addr)
+		}
+	}
+}
+
+var TLSConfigTests = []struct {
+	cert    string
+	key     string
+	ca      string
+	wantErr string
+}{
+	{"", "", "", ""},
+	{"", "", "ca.pem", "E_TLS_CLIENT_CA_WITHOUT_CERT"},
+	{"cert.pem", "", "", "E_TLS_CERT_AND_KEY_REQUIRED"},
+	{"testdata/missing.pem", "testdata/missing.pem", "", "E_TLS_KEY_PAIR_NOT_VALID"}}
+
+func TestConfigurationTLSConfig(t *testing.T) {
+	for _, tt := range TLSConfigTests {
+		cfg := Configuration{TLSCertFile: tt.cert, TLSKeyFile: tt.key, TLSClientCAFile: tt.ca}
+		tlsConfig, err := cfg.TLSConfig()
+		if len(tt.wantErr) == 0 {
+			if err != nil || tlsConfig != nil {
+				t.Errorf("Configuration.TLSConfig(%v, %v, %v): want nil, got %v %v", tt.cert, tt.key, tt.ca, tlsConfig, err)
+			}
+		} else if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+			t.Errorf("Configuration.TLSConfig(%v, %v, %v): want %v, got %v", tt.cert, tt.key, tt.ca, tt.wantErr, err)
 		}
 	}
 }
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/joho/godotenv"
+)
+
+// EnvFiles loads `.env` files into the environment and can load them
+// again when they change. Like `godotenv.Load`, variables set in the
+// process environment take precedence, and earlier files take
+// precedence over later ones. Variables removed from the files are
+// unset on the next load.
+type EnvFiles struct {
+	Paths    []string
+	mutex    sync.Mutex
+	base     map[string]bool
+	loaded   map[string]string
+	previous map[string]string
+}
+
+// NewEnvFiles records the process environment. Empty paths are
+// skipped.
+func NewEnvFiles(paths ...string) *EnvFiles {
+	ef := &EnvFiles{base: map[string]bool{}, loaded: map[string]string{}, previous: map[string]string{}}
+	for _, path := range paths {
+		if path = strings.TrimSpace(path); len(path) > 0 {
+			ef.Paths = append(ef.Paths, path)
+		}
+	}
+	for _, kv := range os.Environ() {
+		ef.base[strings.SplitN(kv, "=", 2)[0]] = true
+	}
+	return ef
+}
+
+// Files returns the paths that exist and are not empty.
+func (ef *EnvFiles) Files() []string {
+	files := []string{}
+	seen := map[string]bool{}
+	for _, path := range ef.Paths {
+		if fi, err := os.Stat(path); err == nil && fi.Size() > 0 && !seen[path] {
+			files = append(files, path)
+			seen[path] = true
+		}
+	}
+	return files
+}
+
+// Load reads all files before changing the environment, so nothing
+// changes if a file cannot be parsed.
+func (ef *EnvFiles) Load() error {
+	vars := map[string]string{}
+	for _, path := range ef.Files() {
+		fileVars, err := godotenv.Read(path)
+		if err != nil {
+			return err
+		}
+		for key, value := range fileVars {
+			if _, ok := vars[key]; !ok {
+				vars[key] = value
+			}
+		}
+	}
+	for key := range vars {
+		if ef.base[key] {
+			delete(vars, key)
+		}
+	}
+	ef.mutex.Lock()
+	defer ef.mutex.Unlock()
+	ef.previous = ef.loaded
+	return ef.set(vars)
+}
+
+// Restore sets the environment back to before the last `Load`, e.g.
+// when the configuration it loaded is not valid.
+func (ef *EnvFiles) Restore() error {
+	ef.mutex.Lock()
+	defer ef.mutex.Unlock()
+	return ef.set(ef.previous)
+}
+
+// set replaces the variables loaded from the files with `vars`.
+func (ef *EnvFiles) set(vars map[string]string) error {
+	for key := range ef.loaded {
+		if _, ok := vars[key]; !ok {
+			os.Unsetenv(key)
+		}
+	}
+	ef.loaded = vars
+	for key, value := range vars {
+		if err := os.Setenv(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var EnvFilesTests = []struct {
+	contents string
+	wantOne  string
+	wantTwo  string
+	wantErr  bool
+}{
+	{"CHATHOOKS_TEST_ONE=a\nCHATHOOKS_TEST_TWO=b\n", "a", "base", false},
+	{"CHATHOOKS_TEST_ONE=c\n", "c", "base", false},
+	{"CHATHOOKS_TEST_ONE=e\nnot a variable\n", "c", "base", true},
+	{"CHATHOOKS_TEST_THREE=d\n", "", "base", false}}
+
+func TestEnvFilesLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chathooks-env")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): want no error, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, ".env")
+	os.Setenv("CHATHOOKS_TEST_TWO", "base")
+	defer os.Unsetenv("CHATHOOKS_TEST_TWO")
+	defer os.Unsetenv("CHATHOOKS_TEST_ONE")
+	defer os.Unsetenv("CHATHOOKS_TEST_THREE")
+
+	envFiles := NewEnvFiles("", path)
+	for _, tt := range EnvFilesTests {
+		if err := ioutil.WriteFile(path, []byte(tt.contents), 0600); err != nil {
+			t.Fatalf("ioutil.WriteFile(): want no error, got %v", err)
+		}
+		err := envFiles.Load()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("EnvFiles.Load(%q): want error %v, got %v", tt.contents, tt.wantErr, err)
+		}
+		if one := os.Getenv("CHATHOOKS_TEST_ONE"); one != tt.wantOne {
+			t.Errorf("EnvFiles.Load(%q): want CHATHOOKS_TEST_ONE %q, got %q", tt.contents, tt.wantOne, one)
+		}
+		if two := os.Getenv("CHATHOOKS_TEST_TWO"); two != tt.wantTwo {
+			t.Errorf("EnvFiles.Load(%q): want CHATHOOKS_TEST_TWO %q, got %q", tt.contents, tt.wantTwo, two)
+		}
+	}
+}
+
+func TestEnvFilesRestore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chathooks-env")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): want no error, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, ".env")
+	defer os.Unsetenv("CHATHOOKS_TEST_ONE")
+	defer os.Unsetenv("CHATHOOKS_TEST_THREE")
+
+	envFiles := NewEnvFiles(path)
+	ioutil.WriteFile(path, []byte("CHATHOOKS_TEST_ONE=a\n"), 0600)
+	envFiles.Load()
+	ioutil.WriteFile(path, []byte("CHATHOOKS_TEST_THREE=b\n"), 0600)
+	envFiles.Load()
+	if err := envFiles.Restore(); err != nil {
+		t.Fatalf("EnvFiles.Restore(): want no error, got %v", err)
+	}
+	one, three := os.Getenv("CHATHOOKS_TEST_ONE"), os.Getenv("CHATHOOKS_TEST_THREE")
+	if one != "a" || len(three) > 0 {
+		t.Errorf("EnvFiles.Restore(): want CHATHOOKS_TEST_ONE a and no CHATHOOKS_TEST_THREE, got %q %q", one, three)
+	}
+}
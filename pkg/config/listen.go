@@ -0,0 +1,72 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// UnixSocketPrefix marks a `CHATHOOKS_LISTEN_ADDRESS` as a Unix domain
+// socket path.
+const UnixSocketPrefix = "unix:"
+
+// Listener returns the network and address to listen on.
+// `CHATHOOKS_LISTEN_ADDRESS` may be a host, a host and port, or
+// `unix:` followed by a socket path. `PORT` is used when it is not set
+// or has no port.
+func (c *Configuration) Listener() (network, address string) {
+	listen := strings.TrimSpace(c.ListenAddress)
+	if strings.HasPrefix(listen, UnixSocketPrefix) {
+		return "unix", strings.TrimPrefix(listen, UnixSocketPrefix)
+	}
+	port := strconv.Itoa(c.Port)
+	if len(listen) == 0 {
+		return "tcp", ":" + port
+	}
+	if _, _, err := net.SplitHostPort(listen); err == nil {
+		return "tcp", listen
+	}
+	return "tcp", net.JoinHostPort(strings.Trim(listen, "[]"), port)
+}
+
+// TLSConfig returns the TLS configuration for `CHATHOOKS_TLS_CERT_FILE`
+// and `CHATHOOKS_TLS_KEY_FILE`, or nil when TLS is not configured. With
+// `CHATHOOKS_TLS_CLIENT_CA_FILE`, clients must present a certificate
+// signed by one of its CAs.
+func (c *Configuration) TLSConfig() (*tls.Config, error) {
+	certFile := strings.TrimSpace(c.TLSCertFile)
+	keyFile := strings.TrimSpace(c.TLSKeyFile)
+	caFile := strings.TrimSpace(c.TLSClientCAFile)
+	if len(certFile) == 0 && len(keyFile) == 0 {
+		if len(caFile) > 0 {
+			return nil, fmt.Errorf("E_TLS_CLIENT_CA_WITHOUT_CERT")
+		}
+		return nil, nil
+	} else if len(certFile) == 0 || len(keyFile) == 0 {
+		return nil, fmt.Errorf("E_TLS_CERT_AND_KEY_REQUIRED")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("E_TLS_KEY_PAIR_NOT_VALID [%s]", err.Error())
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12}
+	if len(caFile) > 0 {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("E_TLS_CLIENT_CA_READ [%s]", err.Error())
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("E_TLS_CLIENT_CA_NOT_VALID [%s]", caFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
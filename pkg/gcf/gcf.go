@@ -0,0 +1,49 @@
+// Package gcf serves an `http.Handler` as a Google Cloud Functions HTTP
+// function.
+package gcf
+
+import (
+	"net/http"
+	"os"
+	"strings"
+)
+
+// PathPrefix returns the prefix of request paths for the function.
+// Functions are served under their name, which is read from the
+// `K_SERVICE` environment variable.
+func PathPrefix() string {
+	if name := strings.Trim(os.Getenv("K_SERVICE"), "/ "); len(name) > 0 {
+		return "/" + name
+	}
+	return ""
+}
+
+// NewHandler returns a handler that strips `prefix` from request paths
+// before calling `handler`. Paths without the prefix are passed on
+// unchanged, so the handler works whether or not the platform has
+// already removed it.
+func NewHandler(handler http.Handler, prefix string) http.Handler {
+	prefix = "/" + strings.Trim(prefix, "/")
+	if prefix == "/" {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if path, ok := stripPrefix(r.URL.Path, prefix); ok {
+			r2 := r.Clone(r.Context())
+			r2.URL.Path = path
+			r2.URL.RawPath = ""
+			r2.RequestURI = r2.URL.RequestURI()
+			r = r2
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func stripPrefix(path, prefix string) (string, bool) {
+	if path == prefix {
+		return "/", true
+	} else if strings.HasPrefix(path, prefix+"/") {
+		return strings.TrimPrefix(path, prefix), true
+	}
+	return path, false
+}
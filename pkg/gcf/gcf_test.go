@@ -0,0 +1,40 @@
+package gcf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+var handlerTests = []struct {
+	prefix  string
+	target  string
+	wantURI string
+}{
+	{"chathooks", "/chathooks/hook?inputType=slack", "/hook?inputType=slack"},
+	{"/chathooks/", "/chathooks", "/"},
+	{"/chathooks", "/hook?inputType=slack", "/hook?inputType=slack"},
+	{"/chathooks", "/chathooksx/hook", "/chathooksx/hook"},
+	{"", "/chathooks/hook", "/chathooks/hook"}}
+
+func TestNewHandler(t *testing.T) {
+	for _, tt := range handlerTests {
+		gotURI := ""
+		handler := NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotURI = r.URL.RequestURI()
+		}), tt.prefix)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, tt.target, nil))
+		if gotURI != tt.wantURI {
+			t.Errorf("NewHandler(%v).ServeHTTP(%v): want %v, got %v", tt.prefix, tt.target, tt.wantURI, gotURI)
+		}
+	}
+}
+
+func TestPathPrefix(t *testing.T) {
+	os.Setenv("K_SERVICE", "chathooks")
+	defer os.Unsetenv("K_SERVICE")
+	if got := PathPrefix(); got != "/chathooks" {
+		t.Errorf("PathPrefix(): want /chathooks, got %v", got)
+	}
+}
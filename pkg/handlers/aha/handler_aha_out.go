@@ -27,6 +27,17 @@ func NewHandler() handlers.Handler {
 	return handlers.Handler{MessageBodyType: MessageBodyType, Normalize: Normalize}
 }
 
+func init() {
+	handlers.Register(handlers.HandlerInfo{
+		Key:              HandlerKey,
+		DisplayName:      DisplayName,
+		MessageDirection: MessageDirection,
+		MessageBodyType:  MessageBodyType,
+		DocumentationURL: DocumentationURL,
+		NewHandler:       NewHandler,
+		ExampleMessage:   ExampleMessage})
+}
+
 func Normalize(cfg config.Configuration, hReq handlers.HandlerRequest) (cc.Message, error) {
 	ccMsg := cc.NewMessage()
 	iconURL, err := cfg.GetAppIconURL(HandlerKey)
@@ -86,7 +97,6 @@ func AhaOutMessageFromBytes(bytes []byte) (AhaOutMessage, error) {
 		log.Warn().
 			Err(err).
 			Str("handler", HandlerKey).
-			Str("request_body", string(bytes)).
 			Msg(config.ErrorInputMessageParseFailed)
 	}
 	return resp, err
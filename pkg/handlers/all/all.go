@@ -0,0 +1,40 @@
+// Package all registers every built-in handler. Import it for its side
+// effects, e.g. `import _ "github.com/grokify/chathooks/pkg/handlers/all"`.
+package all
+
+import (
+	_ "github.com/grokify/chathooks/pkg/handlers/aha"
+	_ "github.com/grokify/chathooks/pkg/handlers/appsignal"
+	_ "github.com/grokify/chathooks/pkg/handlers/apteligent"
+	_ "github.com/grokify/chathooks/pkg/handlers/argocd"
+	_ "github.com/grokify/chathooks/pkg/handlers/bugsnag"
+	_ "github.com/grokify/chathooks/pkg/handlers/buildkite"
+	_ "github.com/grokify/chathooks/pkg/handlers/circleci"
+	_ "github.com/grokify/chathooks/pkg/handlers/cloudevents"
+	_ "github.com/grokify/chathooks/pkg/handlers/codeship"
+	_ "github.com/grokify/chathooks/pkg/handlers/confluence"
+	_ "github.com/grokify/chathooks/pkg/handlers/datadog"
+	_ "github.com/grokify/chathooks/pkg/handlers/deskdotcom"
+	_ "github.com/grokify/chathooks/pkg/handlers/enchant"
+	_ "github.com/grokify/chathooks/pkg/handlers/githubactions"
+	_ "github.com/grokify/chathooks/pkg/handlers/gosquared"
+	_ "github.com/grokify/chathooks/pkg/handlers/gosquared2"
+	_ "github.com/grokify/chathooks/pkg/handlers/heroku"
+	_ "github.com/grokify/chathooks/pkg/handlers/jenkins"
+	_ "github.com/grokify/chathooks/pkg/handlers/k8sevents"
+	_ "github.com/grokify/chathooks/pkg/handlers/librato"
+	_ "github.com/grokify/chathooks/pkg/handlers/magnumci"
+	_ "github.com/grokify/chathooks/pkg/handlers/marketo"
+	_ "github.com/grokify/chathooks/pkg/handlers/opsgenie"
+	_ "github.com/grokify/chathooks/pkg/handlers/papertrail"
+	_ "github.com/grokify/chathooks/pkg/handlers/pingdom"
+	_ "github.com/grokify/chathooks/pkg/handlers/raygun"
+	_ "github.com/grokify/chathooks/pkg/handlers/runscope"
+	_ "github.com/grokify/chathooks/pkg/handlers/semaphore"
+	_ "github.com/grokify/chathooks/pkg/handlers/slack"
+	_ "github.com/grokify/chathooks/pkg/handlers/statuspage"
+	_ "github.com/grokify/chathooks/pkg/handlers/travisci"
+	_ "github.com/grokify/chathooks/pkg/handlers/userlike"
+	_ "github.com/grokify/chathooks/pkg/handlers/victorops"
+	_ "github.com/grokify/chathooks/pkg/handlers/wootric"
+)
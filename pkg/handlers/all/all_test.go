@@ -0,0 +1,44 @@
+package all
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grokify/chathooks/pkg/handlers"
+	"github.com/grokify/chathooks/pkg/util"
+)
+
+// exampleDirs maps handlers that share another handler's examples.
+var exampleDirs = map[string]string{"gosquared2": "gosquared"}
+
+func TestRegistered(t *testing.T) {
+	infos := handlers.Registered()
+	if len(infos) == 0 {
+		t.Fatalf("handlers.Registered(): want handlers, got none")
+	}
+	for _, info := range infos {
+		if len(info.DisplayName) == 0 || info.NewHandler == nil || info.ExampleMessage == nil {
+			t.Errorf("handlers.Registered(%v): want DisplayName, NewHandler and ExampleMessage", info.Key)
+		}
+		if try, ok := handlers.LookupHandlerInfo(info.Key); !ok || try.Key != info.Key {
+			t.Errorf("handlers.LookupHandlerInfo(%v): want found, got %v", info.Key, ok)
+		}
+		dir := info.Key
+		if try, ok := exampleDirs[info.Key]; ok {
+			dir = try
+		}
+		ext := info.ExampleFileExtension
+		if len(ext) == 0 {
+			ext = util.DefaultExtension
+		}
+		for _, slug := range info.ExampleSlugs {
+			file := filepath.Join("..", "..", "..", "docs", "handlers", dir,
+				fmt.Sprintf("event-example_%s.%s", slug, ext))
+			if _, err := os.Stat(file); err != nil {
+				t.Errorf("handlers.Registered(%v): want example file %v, got %v", info.Key, file, err)
+			}
+		}
+	}
+}
@@ -36,6 +36,17 @@ func NewHandler() handlers.Handler {
 	return handlers.Handler{MessageBodyType: MessageBodyType, Normalize: Normalize}
 }
 
+func init() {
+	handlers.Register(handlers.HandlerInfo{
+		Key:              HandlerKey,
+		DisplayName:      DisplayName,
+		MessageDirection: MessageDirection,
+		MessageBodyType:  MessageBodyType,
+		ExampleSlugs:     []string{"marker", "exception", "performance"},
+		NewHandler:       NewHandler,
+		ExampleMessage:   ExampleMessage})
+}
+
 /*
 
 func (h Handler) HandlerKey() string {
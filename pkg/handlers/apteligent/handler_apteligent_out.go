@@ -23,6 +23,18 @@ func NewHandler() handlers.Handler {
 	return handlers.Handler{MessageBodyType: MessageBodyType, Normalize: Normalize}
 }
 
+func init() {
+	handlers.Register(handlers.HandlerInfo{
+		Key:              HandlerKey,
+		DisplayName:      DisplayName,
+		MessageDirection: MessageDirection,
+		MessageBodyType:  MessageBodyType,
+		ExampleSlugs:     []string{"alert", "alert-open", "alert-close"},
+		Fingerprint:      []string{"trigger_id", "state"},
+		NewHandler:       NewHandler,
+		ExampleMessage:   ExampleMessage})
+}
+
 /*
 // FastHttp request handler for Travis CI outbound webhook
 type Handler struct {
@@ -0,0 +1,267 @@
+package argocd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	cc "github.com/grokify/commonchat"
+	"github.com/grokify/simplego/html/htmlutil"
+	"github.com/rs/zerolog/log"
+
+	"github.com/grokify/chathooks/pkg/config"
+	"github.com/grokify/chathooks/pkg/handlers"
+	"github.com/grokify/chathooks/pkg/models"
+)
+
+const (
+	DisplayName      = "Argo CD"
+	HandlerKey       = "argocd"
+	MessageDirection = "out"
+	DocumentationURL = "https://argo-cd.readthedocs.io/en/stable/operator-manual/notifications/services/webhook/"
+	MessageBodyType  = models.JSON
+
+	TriggerSyncSucceeded  = "on-sync-succeeded"
+	TriggerSyncFailed     = "on-sync-failed"
+	TriggerHealthDegraded = "on-health-degraded"
+	TriggerCreated        = "on-created"
+	TriggerDeleted        = "on-deleted"
+)
+
+func NewHandler() handlers.Handler {
+	return handlers.Handler{MessageBodyType: MessageBodyType, Normalize: Normalize}
+}
+
+func init() {
+	handlers.Register(handlers.HandlerInfo{
+		Key:              HandlerKey,
+		DisplayName:      DisplayName,
+		MessageDirection: MessageDirection,
+		MessageBodyType:  MessageBodyType,
+		DocumentationURL: DocumentationURL,
+		ExampleSlugs:     []string{"sync-succeeded", "sync-failed", "health-degraded", "created", "deleted"},
+		NewHandler:       NewHandler,
+		ExampleMessage:   ExampleMessage})
+}
+
+var triggerActivities = map[string]string{
+	TriggerSyncSucceeded:  "Application synced",
+	TriggerSyncFailed:     "Application sync failed",
+	TriggerHealthDegraded: "Application health degraded",
+	TriggerCreated:        "Application created",
+	TriggerDeleted:        "Application deleted"}
+
+var triggerColors = map[string]string{
+	TriggerSyncSucceeded:  htmlutil.Color2GreenHex,
+	TriggerSyncFailed:     htmlutil.Color2RedHex,
+	TriggerHealthDegraded: htmlutil.Color2RedHex,
+	TriggerCreated:        htmlutil.Color2GreenHex,
+	TriggerDeleted:        htmlutil.Color2YellowHex}
+
+func Normalize(cfg config.Configuration, hReq handlers.HandlerRequest) (cc.Message, error) {
+	ccMsg := cc.NewMessage()
+	iconURL, err := cfg.GetAppIconURL(HandlerKey)
+	if err == nil {
+		ccMsg.IconURL = iconURL.String()
+	}
+
+	src, err := ArgocdOutMessageFromBytes(hReq.Body)
+	if err != nil {
+		return ccMsg, err
+	}
+
+	trigger := src.TriggerName()
+	if activity, ok := triggerActivities[trigger]; ok {
+		ccMsg.Activity = activity
+	} else {
+		ccMsg.Activity = "Application updated"
+	}
+
+	appName := src.App.Metadata.Name
+	if appURL := src.AppURL(); len(appURL) > 0 {
+		appName = fmt.Sprintf("[%s](%s)", appName, appURL)
+	}
+
+	switch trigger {
+	case TriggerSyncSucceeded:
+		ccMsg.Title = fmt.Sprintf("%s synced to **%s**", appName, src.App.Status.Sync.ShortRevision())
+	case TriggerSyncFailed:
+		ccMsg.Title = fmt.Sprintf("%s sync **%s**", appName,
+			strings.ToLower(src.App.Status.OperationState.Phase))
+	case TriggerHealthDegraded:
+		ccMsg.Title = fmt.Sprintf("%s health is **%s**", appName, src.App.Status.Health.Status)
+	case TriggerCreated:
+		ccMsg.Title = fmt.Sprintf("%s was created", appName)
+	case TriggerDeleted:
+		ccMsg.Title = fmt.Sprintf("%s was deleted", appName)
+	default:
+		ccMsg.Title = fmt.Sprintf("%s is **%s** and **%s**", appName,
+			src.App.Status.Sync.Status, src.App.Status.Health.Status)
+	}
+
+	attachment := cc.NewAttachment()
+	if color, ok := triggerColors[trigger]; ok {
+		attachment.Color = color
+	}
+
+	if msg := strings.TrimSpace(src.App.Status.OperationState.Message); len(msg) > 0 &&
+		(trigger == TriggerSyncFailed || trigger == TriggerSyncSucceeded) {
+		attachment.AddField(cc.Field{Title: "Message", Value: msg})
+	}
+	if len(src.App.Spec.Project) > 0 {
+		attachment.AddField(cc.Field{Title: "Project", Value: src.App.Spec.Project, Short: true})
+	}
+	if dest := src.App.Spec.Destination.Display(); len(dest) > 0 {
+		attachment.AddField(cc.Field{Title: "Destination", Value: dest, Short: true})
+	}
+	if len(src.App.Status.Sync.Status) > 0 {
+		attachment.AddField(cc.Field{Title: "Sync Status", Value: src.App.Status.Sync.Status, Short: true})
+	}
+	if len(src.App.Status.Health.Status) > 0 {
+		attachment.AddField(cc.Field{Title: "Health Status", Value: src.App.Status.Health.Status, Short: true})
+	}
+	if len(src.App.Spec.Source.RepoURL) > 0 {
+		repo := src.App.Spec.Source.RepoURL
+		if len(src.App.Spec.Source.TargetRevision) > 0 {
+			repo = fmt.Sprintf("%s@%s", repo, src.App.Spec.Source.TargetRevision)
+		}
+		attachment.AddField(cc.Field{Title: "Repository", Value: repo})
+	}
+
+	if len(attachment.Fields) > 0 {
+		ccMsg.AddAttachment(attachment)
+	}
+	return ccMsg, nil
+}
+
+// ArgocdOutMessage is the webhook body produced by the Argo CD
+// notifications webhook template documented in `docs/handlers/argocd`.
+type ArgocdOutMessage struct {
+	Trigger string            `json:"trigger,omitempty"`
+	App     ArgocdApplication `json:"app,omitempty"`
+	Context ArgocdContext     `json:"context,omitempty"`
+}
+
+func ArgocdOutMessageFromBytes(bytes []byte) (ArgocdOutMessage, error) {
+	msg := ArgocdOutMessage{}
+	err := json.Unmarshal(bytes, &msg)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("type", "message.json.unmarshal").
+			Str("handler", HandlerKey).
+			Msg(config.ErrorInputMessageParseFailed)
+	}
+	return msg, err
+}
+
+// TriggerName returns the notification trigger, inferring it from the
+// application status when the template does not supply one.
+func (msg *ArgocdOutMessage) TriggerName() string {
+	trigger := strings.ToLower(strings.TrimSpace(msg.Trigger))
+	if len(trigger) > 0 {
+		if !strings.HasPrefix(trigger, "on-") {
+			trigger = "on-" + trigger
+		}
+		return trigger
+	}
+	if len(msg.App.Metadata.DeletionTimestamp) > 0 {
+		return TriggerDeleted
+	}
+	switch msg.App.Status.OperationState.Phase {
+	case "Succeeded":
+		return TriggerSyncSucceeded
+	case "Error", "Failed":
+		return TriggerSyncFailed
+	}
+	if msg.App.Status.Health.Status == "Degraded" {
+		return TriggerHealthDegraded
+	}
+	return ""
+}
+
+func (msg *ArgocdOutMessage) AppURL() string {
+	baseURL := strings.TrimRight(strings.TrimSpace(msg.Context.ArgocdURL), "/")
+	if len(baseURL) == 0 || len(msg.App.Metadata.Name) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s/applications/%s", baseURL, msg.App.Metadata.Name)
+}
+
+type ArgocdContext struct {
+	ArgocdURL string `json:"argocdUrl,omitempty"`
+}
+
+type ArgocdApplication struct {
+	Metadata ArgocdMetadata `json:"metadata,omitempty"`
+	Spec     ArgocdSpec     `json:"spec,omitempty"`
+	Status   ArgocdStatus   `json:"status,omitempty"`
+}
+
+type ArgocdMetadata struct {
+	Name              string `json:"name,omitempty"`
+	Namespace         string `json:"namespace,omitempty"`
+	CreationTimestamp string `json:"creationTimestamp,omitempty"`
+	DeletionTimestamp string `json:"deletionTimestamp,omitempty"`
+}
+
+type ArgocdSpec struct {
+	Project     string            `json:"project,omitempty"`
+	Source      ArgocdSource      `json:"source,omitempty"`
+	Destination ArgocdDestination `json:"destination,omitempty"`
+}
+
+type ArgocdSource struct {
+	RepoURL        string `json:"repoURL,omitempty"`
+	Path           string `json:"path,omitempty"`
+	TargetRevision string `json:"targetRevision,omitempty"`
+}
+
+type ArgocdDestination struct {
+	Server    string `json:"server,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+func (dest *ArgocdDestination) Display() string {
+	cluster := dest.Name
+	if len(cluster) == 0 {
+		cluster = dest.Server
+	}
+	if len(dest.Namespace) == 0 {
+		return cluster
+	} else if len(cluster) == 0 {
+		return dest.Namespace
+	}
+	return fmt.Sprintf("%s/%s", cluster, dest.Namespace)
+}
+
+type ArgocdStatus struct {
+	Sync           ArgocdSyncStatus     `json:"sync,omitempty"`
+	Health         ArgocdHealthStatus   `json:"health,omitempty"`
+	OperationState ArgocdOperationState `json:"operationState,omitempty"`
+}
+
+type ArgocdSyncStatus struct {
+	Status   string `json:"status,omitempty"`
+	Revision string `json:"revision,omitempty"`
+}
+
+func (sync *ArgocdSyncStatus) ShortRevision() string {
+	if len(sync.Revision) < 8 {
+		return sync.Revision
+	}
+	return sync.Revision[0:7]
+}
+
+type ArgocdHealthStatus struct {
+	Status  string `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type ArgocdOperationState struct {
+	Phase      string `json:"phase,omitempty"`
+	Message    string `json:"message,omitempty"`
+	StartedAt  string `json:"startedAt,omitempty"`
+	FinishedAt string `json:"finishedAt,omitempty"`
+}
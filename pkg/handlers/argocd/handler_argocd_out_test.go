@@ -0,0 +1,29 @@
+package argocd
+
+import (
+	"testing"
+)
+
+var TriggerNameTests = []struct {
+	v    string
+	want string
+}{
+	{`{"trigger":"on-sync-failed"}`, TriggerSyncFailed},
+	{`{"trigger":"created"}`, TriggerCreated},
+	{`{"app":{"status":{"operationState":{"phase":"Succeeded"}}}}`, TriggerSyncSucceeded},
+	{`{"app":{"status":{"operationState":{"phase":"Error"}}}}`, TriggerSyncFailed},
+	{`{"app":{"status":{"health":{"status":"Degraded"}}}}`, TriggerHealthDegraded},
+	{`{"app":{"metadata":{"deletionTimestamp":"2021-06-08T09:30:00Z"}}}`, TriggerDeleted}}
+
+func TestTriggerName(t *testing.T) {
+	for _, tt := range TriggerNameTests {
+		msg, err := ArgocdOutMessageFromBytes([]byte(tt.v))
+		if err != nil {
+			t.Errorf("ArgocdOutMessageFromBytes(%v): err %v", tt.v, err)
+			continue
+		}
+		if got := msg.TriggerName(); got != tt.want {
+			t.Errorf("ArgocdOutMessage.TriggerName(%v): want %v, got %v", tt.v, tt.want, got)
+		}
+	}
+}
@@ -1,21 +1,29 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 	cc "github.com/grokify/commonchat"
 	"github.com/grokify/simplego/net/anyhttp"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/grokify/chathooks/pkg/adapters"
 	"github.com/grokify/chathooks/pkg/config"
 	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/redact"
+	"github.com/grokify/chathooks/pkg/routes"
+	"github.com/grokify/chathooks/pkg/rules"
+	"github.com/grokify/chathooks/pkg/tenants"
+	"github.com/grokify/chathooks/pkg/tracing"
 )
 
 const (
@@ -25,14 +33,20 @@ const (
 type Handler struct {
 	Config          config.Configuration
 	AdapterSet      adapters.AdapterSet
+	Routes          *routes.RouteSet
+	Tenants         *tenants.TenantSet
 	Key             string
+	Fingerprint     []string // default dedup fingerprint paths
+	Correlation     []string // paths of the ID threading related events
 	Normalize       Normalize
 	MessageBodyType models.MessageBodyType
+	BodyType        func(req *models.Request) models.MessageBodyType // optional per request `MessageBodyType`
 }
 
 type HandlerRequest struct {
 	Env         map[string]string // handler environment
 	QueryParams url.Values        // query string params
+	Headers     http.Header       // request headers
 	Body        []byte            // message, e.g. request body
 }
 
@@ -40,26 +54,70 @@ func NewHandlerRequest() HandlerRequest {
 	return HandlerRequest{
 		Env:         map[string]string{},
 		QueryParams: url.Values{},
+		Headers:     http.Header{},
 		Body:        []byte("")}
 }
 
 type Normalize func(config.Configuration, HandlerRequest) (cc.Message, error)
 
-// HandleAwsLambda is the method to respond to a fasthttp request.
-func (h Handler) HandleAwsLambda(ctx context.Context, awsReq events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	hookData := models.HookDataFromAwsLambdaEvent(h.MessageBodyType, awsReq, h.MessageBodyType)
-	errs := h.HandleCanonical(hookData)
-	awsRes, err := models.BuildAwsAPIGatewayProxyResponse(hookData, errs...)
-	return awsRes, err
+// AuthError is a `Normalize` error for requests failing a handler's
+// own authentication, e.g. a token header. It responds with
+// `StatusCode`, 401 for missing and 403 for wrong credentials.
+type AuthError struct {
+	StatusCode int
+	Reason     string
 }
 
+func (e AuthError) Error() string { return e.Reason }
+
 // HandleNetHTTP is the method to respond to a fasthttp request.
 func (h Handler) HandleAnyHTTP(aRes anyhttp.Response, aReq anyhttp.Request) {
-	hookData := models.HookDataFromAnyHTTPReq(h.MessageBodyType, aReq)
-	errs := h.HandleCanonical(hookData)
+	h.HandleRequestAnyHTTP(aRes, models.NewRequestAnyHTTP(aReq))
+}
+
+// HandleRequestAnyHTTP handles a request already mapped from `aRes`'s
+// engine, e.g. with a trace context set by the caller.
+func (h Handler) HandleRequestAnyHTTP(aRes anyhttp.Response, req *models.Request) {
+	awsRes, err := h.HandleRequest(req)
+	respondAnyHTTP(aRes, awsRes, err)
+}
+
+// HandleHookData handles a request whose parameters are given in
+// `hookData`, e.g. from a sealed hook ID, instead of the query string.
+// The body, headers and context are read from `req`.
+func (h Handler) HandleHookData(aRes anyhttp.Response, req *models.Request, hookData models.HookData) {
+	hookData.InputHeaders = req.Headers
+	hookData.Context = req.Context
+	awsRes, err := h.handleRequest(req, hookData)
+	respondAnyHTTP(aRes, awsRes, err)
+}
 
-	awsRes, err := models.BuildAwsAPIGatewayProxyResponse(hookData, errs...)
+// HandleRequest handles an engine independent request. The engine
+// handlers map their requests to `models.Request` and call it.
+func (h Handler) HandleRequest(req *models.Request) (events.APIGatewayProxyResponse, error) {
+	return h.handleRequest(req, models.NewHookData(req))
+}
+
+func (h Handler) handleRequest(req *models.Request, hookData models.HookData) (events.APIGatewayProxyResponse, error) {
+	bodyType := h.MessageBodyType
+	if h.BodyType != nil {
+		bodyType = h.BodyType(req)
+	}
+	body, info := req.Message(bodyType, h.MaxBodySize(hookData.Route, hookData.InputType, hookData.Token))
+	hookData.InputBody = body
+	if info != nil {
+		log.Info().
+			Str("input_type", h.Key).
+			Str("remote_ip", req.RemoteIP).
+			Int("http_status", info.StatusCode).
+			Str("body", string(info.Body)).
+			Msg("request body rejected")
+		return h.BuildResponse(hookData, *info)
+	}
+	return h.BuildResponse(hookData, h.HandleCanonical(hookData)...)
+}
 
+func respondAnyHTTP(aRes anyhttp.Response, awsRes events.APIGatewayProxyResponse, err error) {
 	if err != nil {
 		aRes.SetStatusCode(http.StatusInternalServerError)
 		log.Info().
@@ -86,10 +144,7 @@ func (h Handler) HandleAnyHTTP(aRes anyhttp.Response, aReq anyhttp.Request) {
 
 // HandleNetHTTP is the method to respond to a fasthttp request.
 func (h Handler) HandleNetHTTP(res http.ResponseWriter, req *http.Request) {
-	hookData := models.HookDataFromNetHTTPReq(h.MessageBodyType, req)
-	errs := h.HandleCanonical(hookData)
-
-	awsRes, err := models.BuildAwsAPIGatewayProxyResponse(hookData, errs...)
+	awsRes, err := h.HandleRequest(models.NewRequestNetHTTP(req))
 
 	if err != nil {
 		res.WriteHeader(http.StatusInternalServerError)
@@ -105,10 +160,7 @@ func (h Handler) HandleNetHTTP(res http.ResponseWriter, req *http.Request) {
 
 // HandleFastHTTP is the method to respond to a fasthttp request.
 func (h Handler) HandleFastHTTP(ctx *fasthttp.RequestCtx) {
-	hookData := models.HookDataFromFastHTTPReqCtx(h.MessageBodyType, ctx)
-	errs := h.HandleCanonical(hookData)
-
-	awsRes, err := models.BuildAwsAPIGatewayProxyResponse(hookData, errs...)
+	awsRes, err := h.HandleRequest(models.NewRequestFastHTTP(ctx))
 
 	if err != nil {
 		ctx.SetStatusCode(http.StatusInternalServerError)
@@ -122,21 +174,117 @@ func (h Handler) HandleFastHTTP(ctx *fasthttp.RequestCtx) {
 	}
 }
 
+// lookupRoute returns the route of a request, using the tenant's
+// routes for tenant tokens.
+func (h Handler) lookupRoute(name, inputType, token string) (*routes.Route, bool) {
+	if len(inputType) == 0 {
+		inputType = h.Key
+	}
+	if tenant, ok := h.Tenants.ForToken(token); ok {
+		return tenant.LookupRoute(name, inputType)
+	}
+	return h.Routes.Lookup(name, inputType)
+}
+
+// MaxBodySize returns the body size limit of a request: the route's
+// `maxBodySize`, else `CHATHOOKS_MAX_BODY_SIZE`, else
+// `models.DefaultMaxBodySize`.
+func (h Handler) MaxBodySize(route, inputType, token string) int64 {
+	if r, ok := h.lookupRoute(route, inputType, token); ok && r.MaxBodySize > 0 {
+		return r.MaxBodySize
+	}
+	if h.Config.MaxBodySize > 0 {
+		return h.Config.MaxBodySize
+	}
+	return models.DefaultMaxBodySize
+}
+
+// BuildResponse builds the response for a request. It includes the
+// redacted input only if the request's route has `verboseResponse`.
+func (h Handler) BuildResponse(hookData models.HookData, errs ...models.ErrorInfo) (events.APIGatewayProxyResponse, error) {
+	if len(hookData.InputType) == 0 {
+		hookData.InputType = h.Key
+	}
+	if route, ok := h.lookupRoute(hookData.Route, hookData.InputType, hookData.Token); ok && route.Verbose {
+		return models.BuildVerboseAwsAPIGatewayProxyResponse(hookData, redact.NewRedactor(h.Config.RedactFields), errs...)
+	}
+	return models.BuildAwsAPIGatewayProxyResponse(hookData, errs...)
+}
+
 // HandleCanonical is the method to handle a processed request.
+// Requests with a tenant token are limited to the tenant's input
+// types, outputs, routes and quota. It is traced as a child of the
+// request's span.
 func (h Handler) HandleCanonical(hookData models.HookData) []models.ErrorInfo {
-	log.Debug().
-		Str("event", "incoming.webhook").
-		Str("handler", DisplayName).
-		Str("input_body", string(hookData.InputBody)).
-		Msg("HANDLE_CANONICAL")
+	if len(hookData.InputType) == 0 {
+		hookData.InputType = h.Key
+	}
+	ctx, span := tracing.Start(hookData.Context, "Handler.HandleCanonical", trace.WithAttributes(
+		tracing.AttrInputType.String(hookData.InputType),
+		tracing.AttrRoute.String(hookData.Route)))
+	defer span.End()
+	hookData.Context = ctx
+	errs := h.handleTenant(hookData, span)
+	tracing.SetStatusCode(span, models.GetMaxStatusCode(errs...), 500)
+	return errs
+}
+
+// handleTenant handles a request as its tenant, if any.
+func (h Handler) handleTenant(hookData models.HookData, span trace.Span) []models.ErrorInfo {
+	tenant, ok := h.Tenants.ForToken(hookData.Token)
+	if !ok {
+		return h.handleCanonical(hookData, nil, log.Logger)
+	}
+	hookData.Tenant = tenant.ID
+	span.SetAttributes(tracing.AttrTenant.String(tenant.ID))
+	logger := log.With().Str("tenant", tenant.ID).Logger()
+	if info := h.Tenants.Admit(tenant, hookData.InputType); info != nil {
+		logger.Warn().
+			Str("input_type", hookData.InputType).
+			Int("http_status", info.StatusCode).
+			Str("body", string(info.Body)).
+			Msg("tenant request rejected")
+		return []models.ErrorInfo{*info}
+	}
+	hookData, denied := tenant.FilterOutputs(hookData)
+	errs := append(denied, h.handleCanonical(hookData, tenant, logger)...)
+	tenant.Count(errs)
+	logger.Info().
+		Str("input_type", hookData.InputType).
+		Int("http_status", models.GetMaxStatusCode(errs...)).
+		Msg("tenant request")
+	return errs
+}
+
+func (h Handler) handleCanonical(hookData models.HookData, tenant *tenants.Tenant, logger zerolog.Logger) []models.ErrorInfo {
+	if e := logger.Debug(); e.Enabled() {
+		e.Str("event", "incoming.webhook").
+			Str("handler", DisplayName).
+			Str("input_body", string(redact.NewRedactor(h.Config.RedactFields).JSON(hookData.InputBody))).
+			Msg("HANDLE_CANONICAL")
+	}
 
 	ccMsg, err := h.Normalize(h.Config,
 		HandlerRequest{
 			QueryParams: hookData.CustomQueryParams,
+			Headers:     hookData.InputHeaders,
 			Body:        hookData.InputBody})
 
 	if err != nil {
-		log.Info().
+		// Handlers skip unwanted events with `SKIP_` errors.
+		if strings.HasPrefix(err.Error(), "SKIP_") {
+			return []models.ErrorInfo{models.NewStatusInfo(models.StatusFiltered, []byte(err.Error()))}
+		}
+		var authErr AuthError
+		if errors.As(err, &authErr) {
+			logger.Warn().
+				Err(err).
+				Int("http_status", authErr.StatusCode).
+				Str("handler", DisplayName).
+				Msg("request authentication failed")
+			return []models.ErrorInfo{{StatusCode: authErr.StatusCode, Body: []byte(err.Error())}}
+		}
+		logger.Info().
 			Err(err).
 			Str("type", "http.response").
 			Int("http_status", fasthttp.StatusNotAcceptable).
@@ -146,5 +294,28 @@ func (h Handler) HandleCanonical(hookData models.HookData) []models.ErrorInfo {
 		return []models.ErrorInfo{{StatusCode: 500, Body: []byte(err.Error())}}
 	}
 	hookData.CanonicalMessage = ccMsg
+	if len(h.Fingerprint) > 0 || len(h.Correlation) > 0 {
+		doc := rules.NewDocument(hookData.InputBody, ccMsg)
+		hookData.Fingerprint = rules.Fingerprint(h.Fingerprint, doc)
+		if len(h.Correlation) > 0 {
+			if id := rules.Fingerprint(h.Correlation, doc); len(id) > 0 {
+				hookData.CorrelationID = h.Key + "|" + id
+			}
+		}
+	}
+
+	lookup := h.Routes.Lookup
+	if tenant != nil {
+		lookup = tenant.LookupRoute
+	}
+	if route, ok := lookup(hookData.Route, hookData.InputType); ok {
+		var info *models.ErrorInfo
+		hookData, info = route.Process(hookData)
+		if info != nil {
+			return []models.ErrorInfo{*info}
+		}
+	} else if len(hookData.Route) > 0 {
+		return []models.ErrorInfo{{StatusCode: 404, Body: []byte("E_ROUTE_NOT_FOUND [" + hookData.Route + "]")}}
+	}
 	return h.AdapterSet.SendWebhooks(hookData)
 }
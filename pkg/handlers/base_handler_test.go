@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	cc "github.com/grokify/commonchat"
+	"github.com/grokify/simplego/net/anyhttp"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/valyala/fasthttp"
+
+	"github.com/grokify/chathooks/pkg/awslambda"
+	"github.com/grokify/chathooks/pkg/config"
+	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/routes"
+	"github.com/grokify/chathooks/pkg/rules"
+	"github.com/grokify/chathooks/pkg/state"
+	"github.com/grokify/chathooks/pkg/tenants"
+)
+
+func TestHandleCanonicalFiltered(t *testing.T) {
+	routeSet := routes.NewRouteSet()
+	err := routeSet.Add(&routes.Route{
+		Name: "quiet",
+		Filters: rules.FilterSet{Rules: []rules.FilterRule{
+			{When: []rules.Condition{{Path: "level", Value: "debug"}}, Action: rules.ActionDrop}}}})
+	if err != nil {
+		t.Fatalf("RouteSet.Add(): want no error, got %v", err)
+	}
+
+	tests := []struct {
+		normalize  Normalize
+		route      string
+		wantCode   int
+		wantStatus string
+	}{
+		{func(cfg config.Configuration, hReq HandlerRequest) (cc.Message, error) {
+			return cc.NewMessage(), errors.New("SKIP_TEST_EVENT")
+		}, "", 200, models.StatusFiltered},
+		{func(cfg config.Configuration, hReq HandlerRequest) (cc.Message, error) {
+			return cc.NewMessage(), errors.New("E_TEST_EVENT")
+		}, "", 500, ""},
+		{func(cfg config.Configuration, hReq HandlerRequest) (cc.Message, error) {
+			return cc.NewMessage(), AuthError{StatusCode: 403, Reason: "E_TEST_TOKEN_NOT_VALID"}
+		}, "", 403, ""},
+		{func(cfg config.Configuration, hReq HandlerRequest) (cc.Message, error) {
+			return cc.NewMessage(), nil
+		}, "quiet", 200, models.StatusFiltered},
+		{func(cfg config.Configuration, hReq HandlerRequest) (cc.Message, error) {
+			return cc.NewMessage(), nil
+		}, "missing", 404, ""}}
+
+	for i, tt := range tests {
+		h := Handler{Routes: routeSet, Normalize: tt.normalize}
+		hookData := models.HookData{Route: tt.route, InputBody: []byte(`{"level":"debug"}`)}
+		awsRes, err := models.BuildAwsAPIGatewayProxyResponse(hookData, h.HandleCanonical(hookData)...)
+		if err != nil {
+			t.Fatalf("BuildAwsAPIGatewayProxyResponse(%v): want no error, got %v", i, err)
+		}
+		resInfo := struct {
+			Status string `json:"status"`
+		}{}
+		if err := json.Unmarshal([]byte(awsRes.Body), &resInfo); err != nil {
+			t.Fatalf("json.Unmarshal(%v): want no error, got %v", i, err)
+		}
+		if awsRes.StatusCode != tt.wantCode || resInfo.Status != tt.wantStatus {
+			t.Errorf("Handler.HandleCanonical(%v): want %v/%v, got %v/%v",
+				i, tt.wantCode, tt.wantStatus, awsRes.StatusCode, resInfo.Status)
+		}
+	}
+}
+
+func TestHandleCanonicalTenant(t *testing.T) {
+	tenantSet := tenants.NewTenantSet(nil)
+	err := tenantSet.Add(&tenants.Tenant{
+		ID:         "payments",
+		Tokens:     []string{"pay-token"},
+		InputTypes: []string{"pingdom"},
+		Routes: []*routes.Route{{Name: "quiet",
+			Filters: rules.FilterSet{Rules: []rules.FilterRule{
+				{When: []rules.Condition{{Path: "level", Value: "debug"}}, Action: rules.ActionDrop}}}}}},
+		state.NewMemoryStore(nil), nil)
+	if err != nil {
+		t.Fatalf("TenantSet.Add(): want no error, got %v", err)
+	}
+
+	tests := []struct {
+		token     string
+		inputType string
+		wantCode  int
+	}{
+		{"pay-token", "pingdom", 200},
+		{"pay-token", "travisci", 403},
+		{"", "pingdom", 404}}
+
+	for _, tt := range tests {
+		h := Handler{Routes: routes.NewRouteSet(), Tenants: tenantSet, Key: "pingdom",
+			Normalize: func(cfg config.Configuration, hReq HandlerRequest) (cc.Message, error) {
+				return cc.NewMessage(), nil
+			}}
+		hookData := models.HookData{Token: tt.token, InputType: tt.inputType, Route: "quiet",
+			InputBody: []byte(`{"level":"debug"}`)}
+		if got := models.GetMaxStatusCode(h.HandleCanonical(hookData)...); got != tt.wantCode {
+			t.Errorf("Handler.HandleCanonical(%v, %v): want %v, got %v", tt.token, tt.inputType, tt.wantCode, got)
+		}
+	}
+}
+
+func TestBuildResponseRedacted(t *testing.T) {
+	routeSet := routes.NewRouteSet()
+	if err := routeSet.Add(&routes.Route{Name: "debug", Verbose: true}); err != nil {
+		t.Fatalf("RouteSet.Add(): want no error, got %v", err)
+	}
+	h := Handler{Routes: routeSet, Config: config.Configuration{RedactFields: []string{"email"}}}
+
+	tests := []struct {
+		route       string
+		wantBody    string
+		wantMissing []string
+	}{
+		{"", "", []string{"hook-secret-token", "B0123/XYZsecret", "inputBody", "a@b.c"}},
+		{"debug", `{"email":"[REDACTED]","level":"debug"}`, []string{"hook-secret-token", "B0123/XYZsecret", "a@b.c"}}}
+
+	for _, tt := range tests {
+		hookData := models.HookData{
+			Route:     tt.route,
+			Token:     "hook-secret-token",
+			OutputURL: "https://hooks.slack.com/services/T0123/B0123/XYZsecret",
+			InputBody: []byte(`{"level":"debug","email":"a@b.c"}`)}
+		awsRes, err := h.BuildResponse(hookData)
+		if err != nil {
+			t.Fatalf("Handler.BuildResponse(%v): want no error, got %v", tt.route, err)
+		}
+		for _, secret := range tt.wantMissing {
+			if strings.Contains(awsRes.Body, secret) {
+				t.Errorf("Handler.BuildResponse(%v): want no %v, got %v", tt.route, secret, awsRes.Body)
+			}
+		}
+		resInfo := models.ResponseInfo{}
+		if err := json.Unmarshal([]byte(awsRes.Body), &resInfo); err != nil {
+			t.Fatalf("json.Unmarshal(%v): want no error, got %v", tt.route, err)
+		}
+		if string(resInfo.HookData.InputBody) != tt.wantBody || resInfo.HookData.Token != "hook*************" {
+			t.Errorf("Handler.BuildResponse(%v): want %v, got %v %v",
+				tt.route, tt.wantBody, string(resInfo.HookData.InputBody), resInfo.HookData.Token)
+		}
+	}
+}
+
+func TestHandleRequestLogRedacted(t *testing.T) {
+	buf := &bytes.Buffer{}
+	defer func(logger zerolog.Logger) { log.Logger = logger }(log.Logger)
+	log.Logger = zerolog.New(buf).Level(zerolog.DebugLevel)
+
+	h := Handler{Key: "pingdom",
+		Config: config.Configuration{RedactFields: []string{"email"}},
+		Normalize: func(cfg config.Configuration, hReq HandlerRequest) (cc.Message, error) {
+			return cc.NewMessage(), nil
+		}}
+	req := httptest.NewRequest(http.MethodPost, "/hook",
+		strings.NewReader(`{"email":"a@b.c","password":"hunter2","level":"debug"}`))
+	if _, err := h.HandleRequest(models.NewRequestNetHTTP(req)); err != nil {
+		t.Fatalf("Handler.HandleRequest(): want no error, got %v", err)
+	}
+	logged := buf.String()
+	if strings.Count(logged, `\"level\"`) != 1 || !strings.Contains(logged, "HANDLE_CANONICAL") ||
+		strings.Contains(logged, "a@b.c") || strings.Contains(logged, "hunter2") {
+		t.Errorf("Handler.HandleRequest(): want one HANDLE_CANONICAL body log without email and password, got %v", logged)
+	}
+}
+
+// handleLambda serves an API Gateway v1 event with `h` as on Lambda,
+// through `awslambda`.
+func handleLambda(h Handler, event events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	event.HTTPMethod = http.MethodPost
+	payload, _ := json.Marshal(event)
+	res, _ := awslambda.NewHandler(http.HandlerFunc(h.HandleNetHTTP))(context.Background(), payload)
+	awsRes, _ := res.(events.APIGatewayProxyResponse)
+	return awsRes
+}
+
+func TestHandleAwsLambdaBody(t *testing.T) {
+	routeSet := routes.NewRouteSet()
+	for _, route := range []*routes.Route{
+		{Name: "quiet", Filters: rules.FilterSet{Rules: []rules.FilterRule{
+			{When: []rules.Condition{{Path: "level", Value: "debug"}}, Action: rules.ActionDrop}}}},
+		{Name: "small", MaxBodySize: 8}} {
+		if err := routeSet.Add(route); err != nil {
+			t.Fatalf("RouteSet.Add(): want no error, got %v", err)
+		}
+	}
+
+	tests := []struct {
+		route       string
+		maxBodySize int64
+		contentType string
+		body        string
+		base64      bool
+		wantCode    int
+	}{
+		{"quiet", 0, "application/json", `{"level":"debug"}`, false, 200},
+		{"quiet", 0, "", `{"level":"debug"}`, false, 200},
+		{"quiet", 0, "", "eyJsZXZlbCI6ImRlYnVnIn0=", true, 200},
+		{"quiet", 0, "", "not base64", true, 400},
+		{"quiet", 0, "application/x-www-form-urlencoded", "level=debug", false, 415},
+		{"quiet", 8, "application/json", `{"level":"debug"}`, false, 413},
+		{"small", 0, "application/json", `{"level":"debug"}`, false, 413}}
+
+	for _, tt := range tests {
+		h := Handler{Routes: routeSet, Key: "pingdom",
+			Config: config.Configuration{MaxBodySize: tt.maxBodySize},
+			Normalize: func(cfg config.Configuration, hReq HandlerRequest) (cc.Message, error) {
+				return cc.NewMessage(), nil
+			}}
+		awsRes := handleLambda(h, events.APIGatewayProxyRequest{
+			QueryStringParameters: map[string]string{models.QueryParamRoute: tt.route},
+			Headers:               map[string]string{"content-type": tt.contentType},
+			Body:                  tt.body,
+			IsBase64Encoded:       tt.base64})
+		if awsRes.StatusCode != tt.wantCode {
+			t.Errorf("awslambda.NewHandler(%v, %v, %v): want %v, got %v",
+				tt.route, tt.contentType, tt.body, tt.wantCode, awsRes.StatusCode)
+		}
+	}
+}
+
+func TestHandleEngines(t *testing.T) {
+	const target = "/hook?inputType=wootric&wootricFormatResponse=score%5BScore%5D"
+	var got HandlerRequest
+	h := Handler{Key: "wootric",
+		Normalize: func(cfg config.Configuration, hReq HandlerRequest) (cc.Message, error) {
+			got = hReq
+			return cc.NewMessage(), errors.New("SKIP_TEST_EVENT")
+		}}
+	newNetHTTP := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{"a":1}`))
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+	newFastHTTP := func() *fasthttp.RequestCtx {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.Header.SetMethod(http.MethodPost)
+		ctx.Request.SetRequestURI(target)
+		ctx.Request.Header.SetContentType("application/json")
+		ctx.Request.SetBodyString(`{"a":1}`)
+		return ctx
+	}
+
+	engines := map[string]func() int{
+		"nethttp": func() int {
+			rec := httptest.NewRecorder()
+			h.HandleNetHTTP(rec, newNetHTTP())
+			return rec.Code
+		},
+		"fasthttp": func() int {
+			ctx := newFastHTTP()
+			h.HandleFastHTTP(ctx)
+			return ctx.Response.StatusCode()
+		},
+		"anyhttp": func() int {
+			rec := httptest.NewRecorder()
+			h.HandleAnyHTTP(anyhttp.NewResReqNetHttp(rec, newNetHTTP()))
+			return rec.Code
+		},
+		"awslambda": func() int {
+			awsRes := handleLambda(h, events.APIGatewayProxyRequest{
+				QueryStringParameters: map[string]string{"inputType": "wootric", "wootricFormatResponse": "score[Score]"},
+				Headers:               map[string]string{"Content-Type": "application/json"},
+				Body:                  `{"a":1}`})
+			return awsRes.StatusCode
+		}}
+
+	for name, handle := range engines {
+		got = HandlerRequest{}
+		if code := handle(); code != http.StatusOK {
+			t.Errorf("Handler(%v): want %v, got %v", name, http.StatusOK, code)
+		}
+		if got.QueryParams.Get("wootricFormatResponse") != "score[Score]" || string(got.Body) != `{"a":1}` ||
+			got.Headers.Get("Content-Type") != "application/json" {
+			t.Errorf("Handler(%v): want custom param, header and body, got %v %v %v",
+				name, got.QueryParams, got.Headers, string(got.Body))
+		}
+	}
+}
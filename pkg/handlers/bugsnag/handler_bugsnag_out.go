@@ -26,6 +26,17 @@ func NewHandler() handlers.Handler {
 	return handlers.Handler{MessageBodyType: MessageBodyType, Normalize: Normalize}
 }
 
+func init() {
+	handlers.Register(handlers.HandlerInfo{
+		Key:              HandlerKey,
+		DisplayName:      DisplayName,
+		MessageDirection: MessageDirection,
+		MessageBodyType:  MessageBodyType,
+		ExampleSlugs:     []string{"exception-stack-trace-single", "exception-stack-trace-multi", "exception-error-message-long"},
+		NewHandler:       NewHandler,
+		ExampleMessage:   ExampleMessage})
+}
+
 /*
 Bugsnag
 
@@ -149,12 +160,6 @@ func buildTextMessage(src BugsnagOutMessage) string {
 }
 
 func BugsnagOutMessageFromBytes(bytes []byte) (BugsnagOutMessage, error) {
-	log.Debug().
-		Str("type", "message.raw").
-		Str("handler", HandlerKey).
-		Str("request_body", string(bytes)).
-		Msg(config.InfoInputMessageParseBegin)
-
 	msg := BugsnagOutMessage{}
 	err := json.Unmarshal(bytes, &msg)
 	if err != nil {
@@ -0,0 +1,271 @@
+package buildkite
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	cc "github.com/grokify/commonchat"
+	"github.com/grokify/simplego/html/htmlutil"
+	"github.com/rs/zerolog/log"
+
+	"github.com/grokify/chathooks/pkg/config"
+	"github.com/grokify/chathooks/pkg/handlers"
+	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/util"
+)
+
+const (
+	DisplayName      = "Buildkite"
+	HandlerKey       = "buildkite"
+	MessageDirection = "out"
+	DocumentationURL = "https://buildkite.com/docs/apis/webhooks"
+	MessageBodyType  = models.JSON
+
+	HeaderEvent = "X-Buildkite-Event"
+	HeaderToken = "X-Buildkite-Token"
+
+	// BuildkiteQryVarToken is the webhook token configured in Buildkite.
+	// When set, the `X-Buildkite-Token` header must match it.
+	BuildkiteQryVarToken = "buildkiteToken"
+
+	buildkiteTimeFormat = "2006-01-02 15:04:05 MST"
+)
+
+func NewHandler() handlers.Handler {
+	return handlers.Handler{
+		Key:             HandlerKey,
+		MessageBodyType: MessageBodyType,
+		Normalize:       Normalize}
+}
+
+func init() {
+	handlers.Register(handlers.HandlerInfo{
+		Key:              HandlerKey,
+		DisplayName:      DisplayName,
+		MessageDirection: MessageDirection,
+		MessageBodyType:  MessageBodyType,
+		DocumentationURL: DocumentationURL,
+		ExampleSlugs:     []string{"build-finished", "job-finished"},
+		CustomParams: []handlers.CustomParam{
+			{Name: BuildkiteQryVarToken, Description: "Webhook token. The `X-Buildkite-Token` header must match it."},
+		},
+		NewHandler:     NewHandler,
+		ExampleMessage: ExampleMessage})
+}
+
+var stateColors = map[string]string{
+	"passed":   htmlutil.Color2GreenHex,
+	"blocked":  htmlutil.Color2YellowHex,
+	"canceled": htmlutil.Color2YellowHex,
+	"failed":   htmlutil.Color2RedHex}
+
+func Normalize(cfg config.Configuration, hReq handlers.HandlerRequest) (cc.Message, error) {
+	if hReq.QueryParams == nil {
+		hReq.QueryParams = url.Values{}
+	}
+	if hReq.Headers == nil {
+		hReq.Headers = http.Header{}
+	}
+	ccMsg := cc.NewMessage()
+	iconURL, err := cfg.GetAppIconURL(HandlerKey)
+	if err == nil {
+		ccMsg.IconURL = iconURL.String()
+	}
+
+	wantToken := strings.TrimSpace(hReq.QueryParams.Get(BuildkiteQryVarToken))
+	if len(wantToken) > 0 {
+		haveToken := strings.TrimSpace(hReq.Headers.Get(HeaderToken))
+		if len(haveToken) == 0 {
+			return ccMsg, handlers.AuthError{StatusCode: http.StatusUnauthorized, Reason: "E_BUILDKITE_TOKEN_MISSING"}
+		} else if subtle.ConstantTimeCompare([]byte(wantToken), []byte(haveToken)) != 1 {
+			return ccMsg, handlers.AuthError{StatusCode: http.StatusForbidden, Reason: "E_BUILDKITE_TOKEN_NOT_VALID"}
+		}
+	}
+
+	src, err := BuildkiteOutMessageFromBytes(hReq.Body)
+	if err != nil {
+		return ccMsg, err
+	}
+	if len(src.Event) == 0 {
+		src.Event = strings.TrimSpace(hReq.Headers.Get(HeaderEvent))
+	}
+
+	eventParts := strings.SplitN(src.Event, ".", 2)
+	if len(eventParts) != 2 || (eventParts[0] != "build" && eventParts[0] != "job") {
+		return ccMsg, fmt.Errorf("SKIP_BUILDKITE_EVENT_NOT_SUPPORTED [%s]", src.Event)
+	}
+	eventVerb := strings.Replace(eventParts[1], "_", " ", -1)
+
+	buildName := fmt.Sprintf("Build #%v", src.Build.Number)
+	if len(src.Build.WebURL) > 0 {
+		buildName = fmt.Sprintf("[%s](%s)", buildName, src.Build.WebURL)
+	}
+	pipelineName := src.Pipeline.Name
+	if len(src.Build.Branch) > 0 {
+		pipelineName = fmt.Sprintf("%s/%s", pipelineName, src.Build.Branch)
+	}
+
+	attachment := cc.NewAttachment()
+
+	if eventParts[0] == "job" {
+		ccMsg.Activity = fmt.Sprintf("Job %s", eventVerb)
+		jobName := src.Job.DisplayName()
+		if len(src.Job.WebURL) > 0 {
+			jobName = fmt.Sprintf("[%s](%s)", jobName, src.Job.WebURL)
+		}
+		ccMsg.Title = fmt.Sprintf("%s in %s for **%s** %s",
+			jobName, buildName, pipelineName, StateSuffix(src.Job.State))
+		if color, ok := stateColors[src.Job.State]; ok {
+			attachment.Color = color
+		}
+		if src.Job.ExitStatus != nil {
+			attachment.AddField(cc.Field{Title: "Exit Status", Value: fmt.Sprintf("%v", *src.Job.ExitStatus), Short: true})
+		}
+		if dur := util.DurationDisplayTimes(ParseTime(src.Job.StartedAt), ParseTime(src.Job.FinishedAt)); len(dur) > 0 {
+			attachment.AddField(cc.Field{Title: "Duration", Value: dur, Short: true})
+		}
+	} else {
+		ccMsg.Activity = fmt.Sprintf("Build %s", eventVerb)
+		ccMsg.Title = fmt.Sprintf("%s for **%s** %s",
+			buildName, pipelineName, StateSuffix(src.Build.State))
+		if color, ok := stateColors[src.Build.State]; ok {
+			attachment.Color = color
+		}
+		if len(strings.TrimSpace(src.Build.Message)) > 0 {
+			attachment.AddField(cc.Field{Title: "Message", Value: strings.TrimSpace(src.Build.Message)})
+		}
+	}
+
+	if len(src.Build.Branch) > 0 {
+		attachment.AddField(cc.Field{Title: "Branch", Value: src.Build.Branch, Short: true})
+	}
+	if len(src.Build.Commit) > 0 && src.Build.Commit != "HEAD" {
+		attachment.AddField(cc.Field{
+			Title: "Commit",
+			Value: util.CommitMarkdown(src.Pipeline.Repository, src.Build.Commit),
+			Short: true})
+	}
+	if eventParts[0] == "build" {
+		if dur := util.DurationDisplayTimes(ParseTime(src.Build.StartedAt), ParseTime(src.Build.FinishedAt)); len(dur) > 0 {
+			attachment.AddField(cc.Field{Title: "Duration", Value: dur, Short: true})
+		}
+	}
+	if len(src.Build.Creator.Name) > 0 {
+		attachment.AddField(cc.Field{Title: "Author", Value: src.Build.Creator.Name, Short: true})
+	} else if len(src.Sender.Name) > 0 {
+		attachment.AddField(cc.Field{Title: "Author", Value: src.Sender.Name, Short: true})
+	}
+
+	if len(attachment.Fields) > 0 {
+		ccMsg.AddAttachment(attachment)
+	}
+	return ccMsg, nil
+}
+
+func StateSuffix(state string) string {
+	suffixes := map[string]string{
+		"scheduled": "is scheduled",
+		"running":   "is running",
+		"passed":    "passed",
+		"failed":    "failed",
+		"blocked":   "is blocked",
+		"canceling": "is canceling",
+		"canceled":  "was canceled",
+		"skipped":   "was skipped",
+		"not_run":   "was not run"}
+	state = strings.ToLower(state)
+	if suffix, ok := suffixes[state]; ok {
+		return suffix
+	}
+	return state
+}
+
+// ParseTime parses the Buildkite webhook time format, which may also be
+// RFC 3339. It returns the zero time when the value cannot be parsed.
+func ParseTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{buildkiteTimeFormat, time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+type BuildkiteOutMessage struct {
+	Event    string               `json:"event,omitempty"`
+	Build    BuildkiteOutBuild    `json:"build,omitempty"`
+	Job      BuildkiteOutJob      `json:"job,omitempty"`
+	Pipeline BuildkiteOutPipeline `json:"pipeline,omitempty"`
+	Sender   BuildkiteOutUser     `json:"sender,omitempty"`
+}
+
+func BuildkiteOutMessageFromBytes(bytes []byte) (BuildkiteOutMessage, error) {
+	msg := BuildkiteOutMessage{}
+	err := json.Unmarshal(bytes, &msg)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("type", "message.json.unmarshal").
+			Str("handler", HandlerKey).
+			Msg(config.ErrorInputMessageParseFailed)
+	}
+	return msg, err
+}
+
+type BuildkiteOutBuild struct {
+	ID         string           `json:"id,omitempty"`
+	URL        string           `json:"url,omitempty"`
+	WebURL     string           `json:"web_url,omitempty"`
+	Number     int64            `json:"number,omitempty"`
+	State      string           `json:"state,omitempty"`
+	Message    string           `json:"message,omitempty"`
+	Commit     string           `json:"commit,omitempty"`
+	Branch     string           `json:"branch,omitempty"`
+	Source     string           `json:"source,omitempty"`
+	Creator    BuildkiteOutUser `json:"creator,omitempty"`
+	CreatedAt  string           `json:"created_at,omitempty"`
+	StartedAt  string           `json:"started_at,omitempty"`
+	FinishedAt string           `json:"finished_at,omitempty"`
+}
+
+type BuildkiteOutJob struct {
+	ID         string `json:"id,omitempty"`
+	Type       string `json:"type,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Label      string `json:"label,omitempty"`
+	State      string `json:"state,omitempty"`
+	WebURL     string `json:"web_url,omitempty"`
+	Command    string `json:"command,omitempty"`
+	ExitStatus *int   `json:"exit_status,omitempty"`
+	StartedAt  string `json:"started_at,omitempty"`
+	FinishedAt string `json:"finished_at,omitempty"`
+}
+
+func (job *BuildkiteOutJob) DisplayName() string {
+	if len(job.Name) > 0 {
+		return job.Name
+	} else if len(job.Label) > 0 {
+		return job.Label
+	}
+	return "Job"
+}
+
+type BuildkiteOutPipeline struct {
+	ID         string `json:"id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Slug       string `json:"slug,omitempty"`
+	Repository string `json:"repository,omitempty"`
+	WebURL     string `json:"web_url,omitempty"`
+}
+
+type BuildkiteOutUser struct {
+	ID    string `json:"id,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
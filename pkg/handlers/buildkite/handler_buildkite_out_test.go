@@ -0,0 +1,45 @@
+package buildkite
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/grokify/chathooks/pkg/config"
+	"github.com/grokify/chathooks/pkg/handlers"
+)
+
+const testBuild = `{"event":"build.finished","build":{"web_url":"https://buildkite.com/acme-inc/chathooks/builds/1","number":1,"state":"passed","branch":"master","started_at":"2021-06-07 20:10:10 UTC","finished_at":"2021-06-07 20:13:45 UTC"},"pipeline":{"name":"Chathooks"}}`
+
+var NormalizeTokenTests = []struct {
+	wantToken string
+	haveToken string
+	wantErr   bool
+	wantCode  int
+}{
+	{"", "", false, 0},
+	{"deadbeef", "deadbeef", false, 0},
+	{"deadbeef", "", true, http.StatusUnauthorized},
+	{"deadbeef", "cafebabe", true, http.StatusForbidden}}
+
+func TestNormalizeToken(t *testing.T) {
+	for _, tt := range NormalizeTokenTests {
+		qry := url.Values{}
+		qry.Set(BuildkiteQryVarToken, tt.wantToken)
+		headers := http.Header{}
+		headers.Set(HeaderToken, tt.haveToken)
+
+		ccMsg, err := Normalize(config.Configuration{}, handlers.HandlerRequest{
+			QueryParams: qry, Headers: headers, Body: []byte(testBuild)})
+		if tt.wantErr != (err != nil) {
+			t.Errorf("buildkite.Normalize(%v,%v): want err %v, got %v", tt.wantToken, tt.haveToken, tt.wantErr, err)
+			continue
+		}
+		if authErr, ok := err.(handlers.AuthError); tt.wantErr && (!ok || authErr.StatusCode != tt.wantCode) {
+			t.Errorf("buildkite.Normalize(%v,%v): want AuthError %v, got %v", tt.wantToken, tt.haveToken, tt.wantCode, err)
+		}
+		if !tt.wantErr && ccMsg.Title != "[Build #1](https://buildkite.com/acme-inc/chathooks/builds/1) for **Chathooks/master** passed" {
+			t.Errorf("buildkite.Normalize(%v,%v): got title %v", tt.wantToken, tt.haveToken, ccMsg.Title)
+		}
+	}
+}
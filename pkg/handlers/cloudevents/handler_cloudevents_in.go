@@ -0,0 +1,368 @@
+package cloudevents
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	cc "github.com/grokify/commonchat"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/grokify/chathooks/pkg/config"
+	"github.com/grokify/chathooks/pkg/handlers"
+	"github.com/grokify/chathooks/pkg/models"
+)
+
+const (
+	DisplayName      = "CloudEvents"
+	HandlerKey       = "cloudevents"
+	MessageDirection = "in"
+	DocumentationURL = "https://github.com/cloudevents/spec/blob/v1.0.1/http-protocol-binding.md"
+	MessageBodyType  = models.JSON
+
+	ContentTypeStructured = "application/cloudevents+json"
+	ContentTypeBatch      = "application/cloudevents-batch+json"
+	HeaderPrefix          = "Ce-"
+
+	// CloudeventsQryVarTemplate is a `cc.Message` JSON template used to
+	// format each event. `${path}` values are read from the event in
+	// structured form, e.g. `${data.status}`.
+	CloudeventsQryVarTemplate = "cloudeventsTemplate"
+)
+
+func NewHandler() handlers.Handler {
+	return handlers.Handler{
+		Key:             HandlerKey,
+		MessageBodyType: MessageBodyType,
+		BodyType:        BodyType,
+		Normalize:       Normalize}
+}
+
+// BodyType accepts binary mode events with any content type, as their
+// body is the event data, e.g. XML or an image.
+func BodyType(req *models.Request) models.MessageBodyType {
+	if len(req.Headers.Get(HeaderPrefix+"Specversion")) > 0 {
+		return models.Raw
+	}
+	return MessageBodyType
+}
+
+func init() {
+	handlers.Register(handlers.HandlerInfo{
+		Key:              HandlerKey,
+		DisplayName:      DisplayName,
+		MessageDirection: MessageDirection,
+		MessageBodyType:  MessageBodyType,
+		DocumentationURL: DocumentationURL,
+		ExampleSlugs:     []string{"structured", "batch"},
+		CustomParams: []handlers.CustomParam{
+			{Name: CloudeventsQryVarTemplate, Description: "`cc.Message` JSON template used to format each event."},
+		},
+		NewHandler:          NewHandler,
+		NewTemplatedHandler: newTemplatedHandler,
+		ExampleMessage:      ExampleMessage})
+}
+
+// newTemplatedHandler returns a handler that formats events with `tmpl`
+// unless the request supplies its own template. It is used for
+// `CHATHOOKS_TEMPLATES_FILE` entries with `"inputType": "cloudevents"`.
+func newTemplatedHandler(tmpl string) handlers.Handler {
+	return handlers.Handler{
+		Key:             HandlerKey,
+		MessageBodyType: MessageBodyType,
+		BodyType:        BodyType,
+		Normalize: func(cfg config.Configuration, hReq handlers.HandlerRequest) (cc.Message, error) {
+			return normalizeTemplate(cfg, hReq, tmpl)
+		}}
+}
+
+func Normalize(cfg config.Configuration, hReq handlers.HandlerRequest) (cc.Message, error) {
+	return normalizeTemplate(cfg, hReq, "")
+}
+
+func normalizeTemplate(cfg config.Configuration, hReq handlers.HandlerRequest, tmpl string) (cc.Message, error) {
+	if hReq.QueryParams == nil {
+		hReq.QueryParams = url.Values{}
+	}
+	if try := strings.TrimSpace(hReq.QueryParams.Get(CloudeventsQryVarTemplate)); len(try) > 0 {
+		tmpl = try
+	}
+
+	events, err := CloudEventsFromRequest(hReq.Headers, hReq.Body)
+	if err != nil {
+		return cc.NewMessage(), err
+	}
+
+	msgs := []cc.Message{}
+	for _, evt := range events {
+		ccMsg, err := NormalizeEvent(cfg, evt, tmpl)
+		if err != nil {
+			return ccMsg, err
+		}
+		msgs = append(msgs, ccMsg)
+	}
+	if len(msgs) == 0 {
+		return cc.NewMessage(), errors.New("SKIP_CLOUDEVENTS_BATCH_EMPTY")
+	} else if len(msgs) == 1 {
+		return msgs[0], nil
+	}
+	return mergeMessages(cfg, msgs), nil
+}
+
+// NormalizeEvent converts a single event, applying `tmpl` when
+// one is provided.
+func NormalizeEvent(cfg config.Configuration, evt CloudEvent, tmpl string) (cc.Message, error) {
+	ccMsg := cc.NewMessage()
+	if len(tmpl) > 0 {
+		structured, err := json.Marshal(evt)
+		if err != nil {
+			return ccMsg, err
+		}
+		ccMsg, err = handlers.RenderTemplate(tmpl, structured)
+		if err != nil {
+			return ccMsg, errors.Wrap(err, "cloudevents.NormalizeEvent")
+		}
+	} else {
+		ccMsg.Activity = evt.Type
+		if len(evt.Subject) > 0 {
+			ccMsg.Title = evt.Subject
+		} else {
+			ccMsg.Title = fmt.Sprintf("%s from %s", evt.Type, evt.Source)
+		}
+		if data := evt.DataDisplay(); len(data) > 0 {
+			ccMsg.Text = data
+		}
+	}
+	if len(ccMsg.IconURL) == 0 {
+		iconURL, err := cfg.GetAppIconURL(HandlerKey)
+		if err == nil {
+			ccMsg.IconURL = iconURL.String()
+		}
+	}
+
+	attachment := cc.NewAttachment()
+	if len(evt.Type) > 0 {
+		attachment.AddField(cc.Field{Title: "Type", Value: evt.Type, Short: true})
+	}
+	if len(evt.Source) > 0 {
+		attachment.AddField(cc.Field{Title: "Source", Value: evt.Source, Short: true})
+	}
+	if len(evt.Subject) > 0 {
+		attachment.AddField(cc.Field{Title: "Subject", Value: evt.Subject, Short: true})
+	}
+	if len(evt.Time) > 0 {
+		attachment.AddField(cc.Field{Title: "Time", Value: evt.Time, Short: true})
+	}
+	if len(attachment.Fields) > 0 {
+		ccMsg.AddAttachment(attachment)
+	}
+	return ccMsg, nil
+}
+
+func mergeMessages(cfg config.Configuration, msgs []cc.Message) cc.Message {
+	ccMsg := cc.NewMessage()
+	iconURL, err := cfg.GetAppIconURL(HandlerKey)
+	if err == nil {
+		ccMsg.IconURL = iconURL.String()
+	}
+	ccMsg.Activity = fmt.Sprintf("%v events", len(msgs))
+	for _, msg := range msgs {
+		summary := cc.NewAttachment()
+		summary.Title = msg.Title
+		summary.Text = msg.Text
+		for _, att := range msg.Attachments {
+			if len(att.Title) == 0 && len(att.Text) == 0 {
+				summary.Fields = append(summary.Fields, att.Fields...)
+			} else {
+				ccMsg.AddAttachment(att)
+			}
+		}
+		ccMsg.AddAttachment(summary)
+	}
+	return ccMsg
+}
+
+// CloudEvent is a CloudEvents v1.0 event in JSON structured form.
+type CloudEvent struct {
+	SpecVersion     string                     `json:"specversion,omitempty"`
+	ID              string                     `json:"id,omitempty"`
+	Source          string                     `json:"source,omitempty"`
+	Type            string                     `json:"type,omitempty"`
+	Subject         string                     `json:"subject,omitempty"`
+	Time            string                     `json:"time,omitempty"`
+	DataContentType string                     `json:"datacontenttype,omitempty"`
+	DataSchema      string                     `json:"dataschema,omitempty"`
+	Data            json.RawMessage            `json:"data,omitempty"`
+	DataBase64      string                     `json:"data_base64,omitempty"`
+	Extensions      map[string]json.RawMessage `json:"-"`
+}
+
+var contextAttributes = map[string]int{
+	"specversion": 1, "id": 1, "source": 1, "type": 1, "subject": 1, "time": 1,
+	"datacontenttype": 1, "dataschema": 1, "data": 1, "data_base64": 1}
+
+func (evt *CloudEvent) UnmarshalJSON(raw []byte) error {
+	type cloudEventAlias CloudEvent
+	alias := cloudEventAlias{}
+	if err := json.Unmarshal(raw, &alias); err != nil {
+		return err
+	}
+	all := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &all); err != nil {
+		return err
+	}
+	alias.Extensions = map[string]json.RawMessage{}
+	for key, val := range all {
+		if _, ok := contextAttributes[key]; !ok {
+			alias.Extensions[key] = val
+		}
+	}
+	*evt = CloudEvent(alias)
+	return nil
+}
+
+func (evt CloudEvent) MarshalJSON() ([]byte, error) {
+	type cloudEventAlias CloudEvent
+	raw, err := json.Marshal(cloudEventAlias(evt))
+	if err != nil || len(evt.Extensions) == 0 {
+		return raw, err
+	}
+	all := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &all); err != nil {
+		return raw, err
+	}
+	for key, val := range evt.Extensions {
+		if _, ok := all[key]; !ok {
+			all[key] = val
+		}
+	}
+	return json.Marshal(all)
+}
+
+// DataDisplay returns `data` as text. JSON strings are unquoted and
+// other JSON values are returned as compact JSON.
+func (evt *CloudEvent) DataDisplay() string {
+	if len(evt.Data) == 0 {
+		if len(evt.DataBase64) > 0 {
+			if decoded, err := base64.StdEncoding.DecodeString(evt.DataBase64); err == nil && utf8.Valid(decoded) {
+				return string(decoded)
+			}
+		}
+		return ""
+	}
+	str := ""
+	if err := json.Unmarshal(evt.Data, &str); err == nil {
+		return str
+	}
+	compacted := bytes.Buffer{}
+	if err := json.Compact(&compacted, evt.Data); err != nil {
+		return string(evt.Data)
+	}
+	return compacted.String()
+}
+
+// CloudEventsFromRequest reads events in structured, batch or binary
+// content mode, selected by the `Content-Type` and `ce-*` headers.
+func CloudEventsFromRequest(headers http.Header, body []byte) ([]CloudEvent, error) {
+	if headers == nil {
+		headers = http.Header{}
+	}
+	mediaType, _, err := mime.ParseMediaType(headers.Get("Content-Type"))
+	if err != nil {
+		mediaType = ""
+	}
+
+	switch {
+	case mediaType == ContentTypeBatch:
+		events := []CloudEvent{}
+		err := json.Unmarshal(body, &events)
+		return events, logParseError(err)
+	case mediaType == ContentTypeStructured:
+		evt := CloudEvent{}
+		err := json.Unmarshal(body, &evt)
+		return []CloudEvent{evt}, logParseError(err)
+	case len(headers.Get(HeaderPrefix+"Specversion")) > 0:
+		return []CloudEvent{CloudEventFromBinary(headers, body)}, nil
+	}
+
+	// Fall back to sniffing the body when the content type was lost,
+	// e.g. by an intermediary or an API gateway.
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		events := []CloudEvent{}
+		err := json.Unmarshal(trimmed, &events)
+		return events, logParseError(err)
+	}
+	evt := CloudEvent{}
+	err = json.Unmarshal(trimmed, &evt)
+	if err == nil && len(evt.SpecVersion) == 0 {
+		err = errors.New("E_CLOUDEVENTS_NO_SPECVERSION")
+	}
+	return []CloudEvent{evt}, logParseError(err)
+}
+
+// CloudEventFromBinary builds an event from `ce-*` headers with
+// the body as `data`.
+func CloudEventFromBinary(headers http.Header, body []byte) CloudEvent {
+	evt := CloudEvent{Extensions: map[string]json.RawMessage{}}
+	for key := range headers {
+		canonical := http.CanonicalHeaderKey(key)
+		if !strings.HasPrefix(canonical, HeaderPrefix) {
+			continue
+		}
+		name := strings.ToLower(strings.TrimPrefix(canonical, HeaderPrefix))
+		val, err := url.PathUnescape(headers.Get(key))
+		if err != nil {
+			val = headers.Get(key)
+		}
+		switch name {
+		case "specversion":
+			evt.SpecVersion = val
+		case "id":
+			evt.ID = val
+		case "source":
+			evt.Source = val
+		case "type":
+			evt.Type = val
+		case "subject":
+			evt.Subject = val
+		case "time":
+			evt.Time = val
+		case "dataschema":
+			evt.DataSchema = val
+		default:
+			if raw, err := json.Marshal(val); err == nil {
+				evt.Extensions[name] = raw
+			}
+		}
+	}
+	evt.DataContentType = headers.Get("Content-Type")
+	if len(body) > 0 {
+		if json.Valid(body) {
+			evt.Data = json.RawMessage(body)
+		} else if utf8.Valid(body) {
+			evt.Data, _ = json.Marshal(string(body))
+		} else {
+			evt.DataBase64 = base64.StdEncoding.EncodeToString(body)
+		}
+	}
+	return evt
+}
+
+func logParseError(err error) error {
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("type", "message.json.unmarshal").
+			Str("handler", HandlerKey).
+			Msg(config.ErrorInputMessageParseFailed)
+	}
+	return err
+}
@@ -0,0 +1,135 @@
+package cloudevents
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/grokify/chathooks/pkg/awslambda"
+	"github.com/grokify/chathooks/pkg/config"
+	"github.com/grokify/chathooks/pkg/handlers"
+)
+
+const testStructured = `{"specversion":"1.0","id":"1","source":"/billing","type":"invoice.paid","subject":"INV-1","data":{"customer":"Acme \"West\"","amount":12.5}}`
+
+var NormalizeTests = []struct {
+	contentType   string
+	ceHeaders     map[string]string
+	body          string
+	template      string
+	wantTitle     string
+	wantText      string
+	wantNumFields int
+}{
+	{ContentTypeStructured, nil, testStructured, "", "INV-1", `{"customer":"Acme \"West\"","amount":12.5}`, 3},
+	{"", nil, testStructured, `{"title":"Paid ${data.amount}"}`, "Paid 12.5", "", 3},
+	{ContentTypeBatch, nil, "[" + testStructured + "," + testStructured + "]", "", "", "", 0},
+	{"text/plain",
+		map[string]string{"ce-specversion": "1.0", "ce-id": "2", "ce-source": "/ops", "ce-type": "deploy.done", "ce-time": "2021-06-07T21:00:00Z"},
+		"deployed v1.2", "", "deploy.done from /ops", "deployed v1.2", 3}}
+
+func TestNormalize(t *testing.T) {
+	for _, tt := range NormalizeTests {
+		headers := http.Header{}
+		headers.Set("Content-Type", tt.contentType)
+		for key, val := range tt.ceHeaders {
+			headers.Set(key, val)
+		}
+		qry := url.Values{}
+		qry.Set(CloudeventsQryVarTemplate, tt.template)
+
+		ccMsg, err := Normalize(config.Configuration{}, handlers.HandlerRequest{
+			QueryParams: qry, Headers: headers, Body: []byte(tt.body)})
+		if err != nil {
+			t.Errorf("cloudevents.Normalize(%v): err %v", tt.body, err)
+			continue
+		}
+		if ccMsg.Title != tt.wantTitle {
+			t.Errorf("cloudevents.Normalize(%v): want title %v, got %v", tt.body, tt.wantTitle, ccMsg.Title)
+		}
+		if ccMsg.Text != tt.wantText {
+			t.Errorf("cloudevents.Normalize(%v): want text %v, got %v", tt.body, tt.wantText, ccMsg.Text)
+		}
+		if tt.contentType == ContentTypeBatch {
+			if ccMsg.Activity != "2 events" || len(ccMsg.Attachments) != 2 {
+				t.Errorf("cloudevents.Normalize(%v): want 2 events, got %v / %v attachments", tt.body, ccMsg.Activity, len(ccMsg.Attachments))
+			}
+			continue
+		}
+		if len(ccMsg.Attachments) != 1 || len(ccMsg.Attachments[0].Fields) != tt.wantNumFields {
+			t.Errorf("cloudevents.Normalize(%v): want %v fields, got %v", tt.body, tt.wantNumFields, ccMsg.Attachments)
+		}
+	}
+}
+
+func TestNormalizeEmptyBatch(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Content-Type", ContentTypeBatch)
+	_, err := Normalize(config.Configuration{}, handlers.HandlerRequest{Headers: headers, Body: []byte("[]")})
+	if err == nil || !strings.HasPrefix(err.Error(), "SKIP_") {
+		t.Errorf("cloudevents.Normalize([]): want SKIP_ error, got %v", err)
+	}
+}
+
+func TestTemplatedHandlerDefinition(t *testing.T) {
+	def := handlers.TemplatedHandlerDefinition{}
+	raw := `{"name":"billing","inputType":"cloudevents","template":{"title":"Paid ${data.amount}"}}`
+	if err := json.Unmarshal([]byte(raw), &def); err != nil {
+		t.Fatalf("json.Unmarshal(TemplatedHandlerDefinition): want no error, got %v", err)
+	}
+	handler, err := def.NewHandler()
+	if err != nil {
+		t.Fatalf("TemplatedHandlerDefinition.NewHandler(): want no error, got %v", err)
+	}
+	if handler.Key != "billing" || handler.MessageBodyType != MessageBodyType || handler.BodyType == nil {
+		t.Errorf("TemplatedHandlerDefinition.NewHandler(): want billing cloudevents handler, got %v/%v",
+			handler.Key, handler.MessageBodyType)
+	}
+	headers := http.Header{}
+	headers.Set("Content-Type", ContentTypeStructured)
+	ccMsg, err := handler.Normalize(config.Configuration{}, handlers.HandlerRequest{Headers: headers, Body: []byte(testStructured)})
+	if err != nil || ccMsg.Title != "Paid 12.5" {
+		t.Errorf("Handler.Normalize(): want title Paid 12.5, got %v (%v)", ccMsg.Title, err)
+	}
+}
+
+var HandleBinaryTests = []struct {
+	contentType string
+	ceHeaders   bool
+	body        string
+	base64      bool
+	wantCode    int
+}{
+	{"application/xml", true, "<invoice><id>INV-1</id></invoice>", false, http.StatusOK},
+	{"application/octet-stream", true, "AAEC/w==", true, http.StatusOK},
+	{"application/xml", false, "<invoice><id>INV-1</id></invoice>", false, http.StatusUnsupportedMediaType}}
+
+func TestHandleBinary(t *testing.T) {
+	for _, tt := range HandleBinaryTests {
+		headers := map[string]string{"content-type": tt.contentType}
+		if tt.ceHeaders {
+			headers["ce-specversion"] = "1.0"
+			headers["ce-id"] = "3"
+			headers["ce-source"] = "/billing"
+			headers["ce-type"] = "invoice.paid"
+		}
+		payload, _ := json.Marshal(events.APIGatewayProxyRequest{
+			HTTPMethod:      http.MethodPost,
+			Headers:         headers,
+			Body:            tt.body,
+			IsBase64Encoded: tt.base64})
+		res, err := awslambda.NewHandler(http.HandlerFunc(NewHandler().HandleNetHTTP))(context.Background(), payload)
+		if err != nil {
+			t.Fatalf("awslambda.NewHandler(%v): want no error, got %v", tt.contentType, err)
+		}
+		if awsRes, _ := res.(events.APIGatewayProxyResponse); awsRes.StatusCode != tt.wantCode {
+			t.Errorf("awslambda.NewHandler(%v, binary %v): want %v, got %v %v",
+				tt.contentType, tt.ceHeaders, tt.wantCode, awsRes.StatusCode, awsRes.Body)
+		}
+	}
+}
@@ -23,6 +23,17 @@ func NewHandler() handlers.Handler {
 	return handlers.Handler{MessageBodyType: MessageBodyType, Normalize: Normalize}
 }
 
+func init() {
+	handlers.Register(handlers.HandlerInfo{
+		Key:              HandlerKey,
+		DisplayName:      DisplayName,
+		MessageDirection: MessageDirection,
+		MessageBodyType:  MessageBodyType,
+		ExampleSlugs:     []string{"page-created", "comment-created"},
+		NewHandler:       NewHandler,
+		ExampleMessage:   ExampleMessage})
+}
+
 func Normalize(cfg config.Configuration, hReq handlers.HandlerRequest) (cc.Message, error) {
 	ccMsg := cc.NewMessage()
 	iconURL, err := cfg.GetAppIconURL(HandlerKey)
@@ -81,12 +92,6 @@ type ConfluenceOutMessage struct {
 }
 
 func ConfluenceOutMessageFromBytes(bytes []byte) (ConfluenceOutMessage, error) {
-	log.Debug().
-		Str("type", "message.raw").
-		Str("handler", HandlerKey).
-		Str("request_body", string(bytes)).
-		Msg(config.InfoInputMessageParseBegin)
-
 	msg := ConfluenceOutMessage{}
 	err := json.Unmarshal(bytes, &msg)
 	if err != nil {
@@ -22,6 +22,17 @@ func NewHandler() handlers.Handler {
 	return handlers.Handler{MessageBodyType: MessageBodyType, Normalize: Normalize}
 }
 
+func init() {
+	handlers.Register(handlers.HandlerInfo{
+		Key:              HandlerKey,
+		DisplayName:      DisplayName,
+		MessageDirection: MessageDirection,
+		MessageBodyType:  MessageBodyType,
+		DocumentationURL: DocumentationURL,
+		NewHandler:       NewHandler,
+		ExampleMessage:   handlers.SingleExample(ExampleMessage)})
+}
+
 func Normalize(cfg config.Configuration, hReq handlers.HandlerRequest) (cc.Message, error) {
 	ccMsg, err := CcMessageFromBytes(hReq.Body)
 	if err != nil {
@@ -22,6 +22,18 @@ func NewHandler() handlers.Handler {
 	return handlers.Handler{MessageBodyType: MessageBodyType, Normalize: Normalize}
 }
 
+func init() {
+	handlers.Register(handlers.HandlerInfo{
+		Key:              HandlerKey,
+		DisplayName:      DisplayName,
+		MessageDirection: MessageDirection,
+		MessageBodyType:  MessageBodyType,
+		DocumentationURL: DocumentationURL,
+		ExampleSlugs:     []string{"formatted1", "formatted2"},
+		NewHandler:       NewHandler,
+		ExampleMessage:   ExampleMessage})
+}
+
 func Normalize(cfg config.Configuration, hReq handlers.HandlerRequest) (cc.Message, error) {
 	ccMsg, err := CcMessageFromBytes(hReq.Body)
 	if err != nil {
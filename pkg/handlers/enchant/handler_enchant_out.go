@@ -23,6 +23,16 @@ func NewHandler() handlers.Handler {
 	return handlers.Handler{MessageBodyType: MessageBodyType, Normalize: Normalize}
 }
 
+func init() {
+	handlers.Register(handlers.HandlerInfo{
+		Key:              HandlerKey,
+		DisplayName:      DisplayName,
+		MessageDirection: MessageDirection,
+		MessageBodyType:  MessageBodyType,
+		NewHandler:       NewHandler,
+		ExampleMessage:   handlers.SingleExample(ExampleMessage)})
+}
+
 func Normalize(cfg config.Configuration, hReq handlers.HandlerRequest) (cc.Message, error) {
 	ccMsg := cc.NewMessage()
 	iconURL, err := cfg.GetAppIconURL(HandlerKey)
@@ -88,11 +98,6 @@ type EnchantModel struct {
 }
 
 func EnchantOutMessageFromBytes(bytes []byte) (EnchantOutMessage, error) {
-	log.Debug().
-		Str("type", "message.raw").
-		Str("request_body", string(bytes)).
-		Msg("handler_enchant_parse_message")
-
 	msg := EnchantOutMessage{}
 	err := json.Unmarshal(bytes, &msg)
 	if err != nil {
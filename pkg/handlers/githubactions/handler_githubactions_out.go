@@ -0,0 +1,238 @@
+package githubactions
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	cc "github.com/grokify/commonchat"
+	"github.com/grokify/simplego/html/htmlutil"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/grokify/chathooks/pkg/config"
+	"github.com/grokify/chathooks/pkg/handlers"
+	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/util"
+)
+
+const (
+	DisplayName      = "GitHub Actions"
+	HandlerKey       = "githubactions"
+	MessageDirection = "out"
+	DocumentationURL = "https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#workflow_run"
+	MessageBodyType  = models.URLEncodedJSONPayloadOrJSON
+
+	HeaderEvent      = "X-GitHub-Event"
+	EventWorkflowRun = "workflow_run"
+	ActionRequested  = "requested"
+	ActionInProgress = "in_progress"
+	ActionCompleted  = "completed"
+)
+
+func NewHandler() handlers.Handler {
+	return handlers.Handler{
+		Key:             HandlerKey,
+		MessageBodyType: MessageBodyType,
+		Normalize:       Normalize}
+}
+
+func init() {
+	handlers.Register(handlers.HandlerInfo{
+		Key:              HandlerKey,
+		DisplayName:      DisplayName,
+		MessageDirection: MessageDirection,
+		MessageBodyType:  MessageBodyType,
+		DocumentationURL: DocumentationURL,
+		ExampleSlugs:     []string{"workflow-run-completed", "workflow-run-requested"},
+		NewHandler:       NewHandler,
+		ExampleMessage:   ExampleMessage})
+}
+
+var conclusionColors = map[string]string{
+	"success":         htmlutil.Color2GreenHex,
+	"neutral":         htmlutil.Color2YellowHex,
+	"cancelled":       htmlutil.Color2YellowHex,
+	"skipped":         htmlutil.Color2YellowHex,
+	"stale":           htmlutil.Color2YellowHex,
+	"action_required": htmlutil.Color2YellowHex,
+	"timed_out":       htmlutil.Color2RedHex,
+	"failure":         htmlutil.Color2RedHex}
+
+func Normalize(cfg config.Configuration, hReq handlers.HandlerRequest) (cc.Message, error) {
+	if hReq.Headers == nil {
+		hReq.Headers = http.Header{}
+	}
+	ccMsg := cc.NewMessage()
+	iconURL, err := cfg.GetAppIconURL(HandlerKey)
+	if err == nil {
+		ccMsg.IconURL = iconURL.String()
+	}
+
+	event := strings.TrimSpace(hReq.Headers.Get(HeaderEvent))
+	if len(event) > 0 && event != EventWorkflowRun {
+		return ccMsg, fmt.Errorf("SKIP_GITHUBACTIONS_EVENT_NOT_SUPPORTED [%s]", event)
+	}
+
+	src, err := GithubactionsOutMessageFromBytes(hReq.Body)
+	if err != nil {
+		return ccMsg, errors.Wrap(err, "githubactions.Normalize")
+	}
+	if len(src.WorkflowRun.HTMLURL) == 0 {
+		return ccMsg, errors.New("SKIP_GITHUBACTIONS_NOT_WORKFLOW_RUN")
+	}
+
+	run := src.WorkflowRun
+	workflowName := run.Name
+	if len(src.Workflow.Name) > 0 {
+		workflowName = src.Workflow.Name
+	}
+	runName := fmt.Sprintf("[%s #%v](%s)", workflowName, run.RunNumber, run.HTMLURL)
+	repoName := src.Repository.FullName
+	if len(run.HeadBranch) > 0 {
+		repoName = fmt.Sprintf("%s/%s", repoName, run.HeadBranch)
+	}
+
+	switch src.Action {
+	case ActionRequested:
+		ccMsg.Activity = "Workflow requested"
+		ccMsg.Title = fmt.Sprintf("%s for **%s** was requested", runName, repoName)
+	case ActionInProgress:
+		ccMsg.Activity = "Workflow in progress"
+		ccMsg.Title = fmt.Sprintf("%s for **%s** is in progress", runName, repoName)
+	default:
+		ccMsg.Activity = fmt.Sprintf("Workflow %s", strings.Replace(src.Action, "_", " ", -1))
+		ccMsg.Title = fmt.Sprintf("%s for **%s** %s", runName, repoName, ConclusionSuffix(run.Conclusion))
+	}
+
+	attachment := cc.NewAttachment()
+	if color, ok := conclusionColors[run.Conclusion]; ok {
+		attachment.Color = color
+	}
+
+	if msg := strings.TrimSpace(run.HeadCommit.Message); len(msg) > 0 {
+		if idx := strings.Index(msg, "\n"); idx > 0 {
+			msg = strings.TrimSpace(msg[:idx])
+		}
+		attachment.AddField(cc.Field{Title: "Message", Value: msg})
+	}
+	if len(run.HeadBranch) > 0 {
+		attachment.AddField(cc.Field{Title: "Branch", Value: run.HeadBranch, Short: true})
+	}
+	if len(run.HeadSHA) > 0 {
+		attachment.AddField(cc.Field{
+			Title: "Commit",
+			Value: util.CommitMarkdown(src.Repository.HTMLURL, run.HeadSHA),
+			Short: true})
+	}
+	if len(run.Event) > 0 {
+		attachment.AddField(cc.Field{Title: "Trigger", Value: run.Event, Short: true})
+	}
+	if src.Action == ActionCompleted {
+		if dur := run.DurationDisplay(); len(dur) > 0 {
+			attachment.AddField(cc.Field{Title: "Duration", Value: dur, Short: true})
+		}
+	}
+	if len(run.Actor.Login) > 0 {
+		attachment.AddField(cc.Field{Title: "Actor", Value: run.Actor.Login, Short: true})
+	} else if len(src.Sender.Login) > 0 {
+		attachment.AddField(cc.Field{Title: "Actor", Value: src.Sender.Login, Short: true})
+	}
+
+	if len(attachment.Fields) > 0 {
+		ccMsg.AddAttachment(attachment)
+	}
+	return ccMsg, nil
+}
+
+func ConclusionSuffix(conclusion string) string {
+	suffixes := map[string]string{
+		"success":         "passed",
+		"failure":         "failed",
+		"cancelled":       "was cancelled",
+		"skipped":         "was skipped",
+		"timed_out":       "timed out",
+		"action_required": "requires action",
+		"neutral":         "completed",
+		"stale":           "is stale"}
+	if suffix, ok := suffixes[strings.ToLower(conclusion)]; ok {
+		return suffix
+	}
+	return "completed"
+}
+
+type GithubactionsOutMessage struct {
+	Action      string                      `json:"action,omitempty"`
+	WorkflowRun GithubactionsOutWorkflowRun `json:"workflow_run,omitempty"`
+	Workflow    GithubactionsOutWorkflow    `json:"workflow,omitempty"`
+	Repository  GithubactionsOutRepository  `json:"repository,omitempty"`
+	Sender      GithubactionsOutUser        `json:"sender,omitempty"`
+}
+
+func GithubactionsOutMessageFromBytes(bytes []byte) (GithubactionsOutMessage, error) {
+	msg := GithubactionsOutMessage{}
+	err := json.Unmarshal(bytes, &msg)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("type", "message.json.unmarshal").
+			Str("handler", HandlerKey).
+			Msg(config.ErrorInputMessageParseFailed)
+	}
+	return msg, err
+}
+
+type GithubactionsOutWorkflowRun struct {
+	ID           int64                  `json:"id,omitempty"`
+	Name         string                 `json:"name,omitempty"`
+	HeadBranch   string                 `json:"head_branch,omitempty"`
+	HeadSHA      string                 `json:"head_sha,omitempty"`
+	RunNumber    int64                  `json:"run_number,omitempty"`
+	RunAttempt   int64                  `json:"run_attempt,omitempty"`
+	Event        string                 `json:"event,omitempty"`
+	Status       string                 `json:"status,omitempty"`
+	Conclusion   string                 `json:"conclusion,omitempty"`
+	HTMLURL      string                 `json:"html_url,omitempty"`
+	CreatedAt    time.Time              `json:"created_at,omitempty"`
+	UpdatedAt    time.Time              `json:"updated_at,omitempty"`
+	RunStartedAt time.Time              `json:"run_started_at,omitempty"`
+	Actor        GithubactionsOutUser   `json:"actor,omitempty"`
+	HeadCommit   GithubactionsOutCommit `json:"head_commit,omitempty"`
+}
+
+func (run *GithubactionsOutWorkflowRun) DurationDisplay() string {
+	start := run.RunStartedAt
+	if start.IsZero() {
+		start = run.CreatedAt
+	}
+	return util.DurationDisplayTimes(start, run.UpdatedAt)
+}
+
+type GithubactionsOutWorkflow struct {
+	ID      int64  `json:"id,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Path    string `json:"path,omitempty"`
+	HTMLURL string `json:"html_url,omitempty"`
+}
+
+type GithubactionsOutRepository struct {
+	FullName string `json:"full_name,omitempty"`
+	HTMLURL  string `json:"html_url,omitempty"`
+}
+
+type GithubactionsOutUser struct {
+	Login string `json:"login,omitempty"`
+}
+
+type GithubactionsOutCommit struct {
+	ID      string                       `json:"id,omitempty"`
+	Message string                       `json:"message,omitempty"`
+	Author  GithubactionsOutCommitAuthor `json:"author,omitempty"`
+}
+
+type GithubactionsOutCommitAuthor struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
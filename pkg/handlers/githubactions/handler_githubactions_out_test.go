@@ -0,0 +1,56 @@
+package githubactions
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/grokify/simplego/html/htmlutil"
+
+	"github.com/grokify/chathooks/pkg/config"
+	"github.com/grokify/chathooks/pkg/handlers"
+)
+
+const testRunFormat = `{"action":"%s","workflow_run":{"name":"CI","head_branch":"main","run_number":42,"conclusion":"%s","html_url":"https://github.com/grokify/chathooks/actions/runs/1"},"repository":{"full_name":"grokify/chathooks"}}`
+
+var NormalizeTests = []struct {
+	event      string
+	action     string
+	conclusion string
+	wantTitle  string
+	wantColor  string
+	wantErr    bool
+}{
+	{"workflow_run", "requested", "", "[CI #42](https://github.com/grokify/chathooks/actions/runs/1) for **grokify/chathooks/main** was requested", "", false},
+	{"workflow_run", "in_progress", "", "[CI #42](https://github.com/grokify/chathooks/actions/runs/1) for **grokify/chathooks/main** is in progress", "", false},
+	{"workflow_run", "completed", "success", "[CI #42](https://github.com/grokify/chathooks/actions/runs/1) for **grokify/chathooks/main** passed", htmlutil.Color2GreenHex, false},
+	{"workflow_run", "completed", "failure", "[CI #42](https://github.com/grokify/chathooks/actions/runs/1) for **grokify/chathooks/main** failed", htmlutil.Color2RedHex, false},
+	{"workflow_run", "completed", "timed_out", "[CI #42](https://github.com/grokify/chathooks/actions/runs/1) for **grokify/chathooks/main** timed out", htmlutil.Color2RedHex, false},
+	{"", "completed", "cancelled", "[CI #42](https://github.com/grokify/chathooks/actions/runs/1) for **grokify/chathooks/main** was cancelled", htmlutil.Color2YellowHex, false},
+	{"", "completed", "startup_failure", "[CI #42](https://github.com/grokify/chathooks/actions/runs/1) for **grokify/chathooks/main** completed", "", false},
+	{"push", "completed", "success", "", "", true}}
+
+func TestNormalize(t *testing.T) {
+	for _, tt := range NormalizeTests {
+		headers := http.Header{}
+		headers.Set(HeaderEvent, tt.event)
+		ccMsg, err := Normalize(config.Configuration{}, handlers.HandlerRequest{
+			Headers: headers, Body: []byte(fmt.Sprintf(testRunFormat, tt.action, tt.conclusion))})
+		if tt.wantErr != (err != nil) {
+			t.Errorf("githubactions.Normalize(%v,%v,%v): want err %v, got %v", tt.event, tt.action, tt.conclusion, tt.wantErr, err)
+			continue
+		} else if tt.wantErr {
+			continue
+		}
+		if ccMsg.Title != tt.wantTitle {
+			t.Errorf("githubactions.Normalize(%v,%v,%v): want title %v, got %v", tt.event, tt.action, tt.conclusion, tt.wantTitle, ccMsg.Title)
+		}
+		gotColor := ""
+		if len(ccMsg.Attachments) > 0 {
+			gotColor = ccMsg.Attachments[0].Color
+		}
+		if gotColor != tt.wantColor {
+			t.Errorf("githubactions.Normalize(%v,%v,%v): want color %v, got %v", tt.event, tt.action, tt.conclusion, tt.wantColor, gotColor)
+		}
+	}
+}
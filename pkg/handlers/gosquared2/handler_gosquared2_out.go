@@ -22,6 +22,18 @@ func NewHandler() handlers.Handler {
 	return handlers.Handler{MessageBodyType: MessageBodyType, Normalize: Normalize}
 }
 
+// gosquared2 shares the GoSquared icon and examples.
+func init() {
+	handlers.Register(handlers.HandlerInfo{
+		Key:              "gosquared2",
+		DisplayName:      DisplayName,
+		MessageBodyType:  MessageBodyType,
+		DocumentationURL: DocumentationURL,
+		ExampleSlugs:     []string{"site-traffic", "smart-group", "live-chat"},
+		NewHandler:       NewHandler,
+		ExampleMessage:   ExampleMessage})
+}
+
 func Normalize(cfg config.Configuration, hReq handlers.HandlerRequest) (cc.Message, error) {
 	bytes := hReq.Body
 	src, err := GosquaredOutBaseMessageFromBytes(bytes)
@@ -26,6 +26,18 @@ func NewHandler() handlers.Handler {
 	return handlers.Handler{MessageBodyType: MessageBodyType, Normalize: Normalize}
 }
 
+func init() {
+	handlers.Register(handlers.HandlerInfo{
+		Key:                  HandlerKey,
+		DisplayName:          DisplayName,
+		MessageDirection:     MessageDirection,
+		MessageBodyType:      MessageBodyType,
+		ExampleFileExtension: "txt",
+		ExampleSlugs:         []string{"build"},
+		NewHandler:           NewHandler,
+		ExampleMessage:       handlers.SingleExample(ExampleMessage)})
+}
+
 func BuildInboundMessage(ctx *fasthttp.RequestCtx) (HerokuOutMessage, error) {
 	return HerokuOutMessage{
 		App:      string(ctx.FormValue("app")),
@@ -0,0 +1,204 @@
+package jenkins
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	cc "github.com/grokify/commonchat"
+	"github.com/grokify/simplego/html/htmlutil"
+	"github.com/grokify/simplego/type/stringsutil"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/grokify/chathooks/pkg/config"
+	"github.com/grokify/chathooks/pkg/handlers"
+	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/util"
+)
+
+const (
+	DisplayName      = "Jenkins"
+	HandlerKey       = "jenkins"
+	MessageDirection = "out"
+	DocumentationURL = "https://plugins.jenkins.io/notification/"
+	MessageBodyType  = models.JSON
+
+	JenkinsQryVarPhases = "jenkinsPhases"
+
+	PhaseStarted   = "STARTED"
+	PhaseCompleted = "COMPLETED"
+	PhaseFinalized = "FINALIZED"
+)
+
+func NewHandler() handlers.Handler {
+	return handlers.Handler{
+		Key:             HandlerKey,
+		MessageBodyType: MessageBodyType,
+		Normalize:       Normalize}
+}
+
+func init() {
+	handlers.Register(handlers.HandlerInfo{
+		Key:              HandlerKey,
+		DisplayName:      DisplayName,
+		MessageDirection: MessageDirection,
+		MessageBodyType:  MessageBodyType,
+		DocumentationURL: DocumentationURL,
+		ExampleSlugs:     []string{"started", "completed", "finalized"},
+		CustomParams: []handlers.CustomParam{
+			{Name: JenkinsQryVarPhases, Description: "Comma-delimited build phases to send, e.g. `STARTED,FINALIZED`."},
+		},
+		NewHandler:     NewHandler,
+		ExampleMessage: ExampleMessage})
+}
+
+var statusColors = map[string]string{
+	"SUCCESS":  htmlutil.Color2GreenHex,
+	"UNSTABLE": htmlutil.Color2YellowHex,
+	"ABORTED":  htmlutil.Color2YellowHex,
+	"FAILURE":  htmlutil.Color2RedHex}
+
+func Normalize(cfg config.Configuration, hReq handlers.HandlerRequest) (cc.Message, error) {
+	if hReq.QueryParams == nil {
+		hReq.QueryParams = url.Values{}
+	}
+	ccMsg := cc.NewMessage()
+	iconURL, err := cfg.GetAppIconURL(HandlerKey)
+	if err == nil {
+		ccMsg.IconURL = iconURL.String()
+	}
+
+	src, err := JenkinsOutMessageFromBytes(hReq.Body)
+	if err != nil {
+		return ccMsg, err
+	}
+
+	phase := strings.ToUpper(strings.TrimSpace(src.Build.Phase))
+	phases := stringsutil.SliceCondenseSpace(
+		strings.Split(hReq.QueryParams.Get(JenkinsQryVarPhases), ","), true, false)
+	if len(phases) > 0 {
+		matched := false
+		for _, try := range phases {
+			if strings.EqualFold(try, phase) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return ccMsg, errors.New("SKIP_JENKINS_PHASE_NOT_MATCHED")
+		}
+	}
+
+	buildName := fmt.Sprintf("Build #%v", src.Build.Number)
+	if len(src.Build.FullURL) > 0 {
+		buildName = fmt.Sprintf("[%s](%s)", buildName, src.Build.FullURL)
+	}
+
+	switch phase {
+	case PhaseStarted:
+		ccMsg.Activity = "Build started"
+		ccMsg.Title = fmt.Sprintf("%s for **%s** started", buildName, src.Name)
+	case PhaseCompleted, PhaseFinalized:
+		ccMsg.Activity = fmt.Sprintf("Build %s", strings.ToLower(phase))
+		ccMsg.Title = fmt.Sprintf("%s for **%s** %s", buildName, src.Name, src.Build.StatusSuffix())
+	default:
+		ccMsg.Activity = fmt.Sprintf("Build %s", strings.ToLower(phase))
+		ccMsg.Title = fmt.Sprintf("%s for **%s** is %s", buildName, src.Name, strings.ToLower(phase))
+	}
+
+	attachment := cc.NewAttachment()
+	if color, ok := statusColors[strings.ToUpper(src.Build.Status)]; ok && phase != PhaseStarted {
+		attachment.Color = color
+	}
+
+	if len(src.Build.SCM.Branch) > 0 {
+		attachment.AddField(cc.Field{Title: "Branch", Value: src.Build.SCM.Branch, Short: true})
+	}
+	if len(src.Build.SCM.Commit) > 0 {
+		attachment.AddField(cc.Field{
+			Title: "Commit",
+			Value: util.CommitMarkdown(src.Build.SCM.URL, src.Build.SCM.Commit),
+			Short: true})
+	}
+	if len(src.Build.Status) > 0 && phase != PhaseStarted {
+		attachment.AddField(cc.Field{Title: "Status", Value: src.Build.Status, Short: true})
+	}
+	if src.Build.Duration > 0 {
+		attachment.AddField(cc.Field{Title: "Duration", Value: src.Build.DurationDisplay(), Short: true})
+	}
+	if len(src.Build.SCM.Culprits) > 0 {
+		attachment.AddField(cc.Field{Title: "Culprits", Value: strings.Join(src.Build.SCM.Culprits, ", ")})
+	}
+	if len(strings.TrimSpace(src.Build.Notes)) > 0 {
+		attachment.AddField(cc.Field{Title: "Notes", Value: strings.TrimSpace(src.Build.Notes)})
+	}
+
+	if len(attachment.Fields) > 0 {
+		ccMsg.AddAttachment(attachment)
+	}
+	return ccMsg, nil
+}
+
+// JenkinsOutMessage is the job notification sent by the
+// Jenkins Notification plugin using the JSON format.
+type JenkinsOutMessage struct {
+	Name        string          `json:"name,omitempty"`
+	DisplayName string          `json:"display_name,omitempty"`
+	URL         string          `json:"url,omitempty"`
+	Build       JenkinsOutBuild `json:"build,omitempty"`
+}
+
+func JenkinsOutMessageFromBytes(bytes []byte) (JenkinsOutMessage, error) {
+	msg := JenkinsOutMessage{}
+	err := json.Unmarshal(bytes, &msg)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("type", "message.json.unmarshal").
+			Str("handler", HandlerKey).
+			Msg(config.ErrorInputMessageParseFailed)
+	}
+	return msg, err
+}
+
+type JenkinsOutBuild struct {
+	FullURL    string            `json:"full_url,omitempty"`
+	Number     int64             `json:"number,omitempty"`
+	QueueID    int64             `json:"queue_id,omitempty"`
+	Timestamp  int64             `json:"timestamp,omitempty"`
+	Duration   int64             `json:"duration,omitempty"` // milliseconds
+	Phase      string            `json:"phase,omitempty"`
+	Status     string            `json:"status,omitempty"`
+	URL        string            `json:"url,omitempty"`
+	SCM        JenkinsOutSCM     `json:"scm,omitempty"`
+	Parameters map[string]string `json:"parameters,omitempty"`
+	Log        string            `json:"log,omitempty"`
+	Notes      string            `json:"notes,omitempty"`
+}
+
+func (build *JenkinsOutBuild) StatusSuffix() string {
+	suffixes := map[string]string{
+		"SUCCESS":   "passed",
+		"FAILURE":   "failed",
+		"UNSTABLE":  "is unstable",
+		"ABORTED":   "was aborted",
+		"NOT_BUILT": "was not built"}
+	if suffix, ok := suffixes[strings.ToUpper(build.Status)]; ok {
+		return suffix
+	}
+	return strings.ToLower(build.Status)
+}
+
+func (build *JenkinsOutBuild) DurationDisplay() string {
+	return util.DurationDisplay(build.Duration / 1000)
+}
+
+type JenkinsOutSCM struct {
+	URL      string   `json:"url,omitempty"`
+	Branch   string   `json:"branch,omitempty"`
+	Commit   string   `json:"commit,omitempty"`
+	Changes  []string `json:"changes,omitempty"`
+	Culprits []string `json:"culprits,omitempty"`
+}
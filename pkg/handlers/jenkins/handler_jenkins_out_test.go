@@ -0,0 +1,51 @@
+package jenkins
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/grokify/chathooks/pkg/config"
+	"github.com/grokify/chathooks/pkg/handlers"
+)
+
+const testBuildFormat = `{"name":"chathooks","build":{"full_url":"https://ci.example.com/job/chathooks/7/","number":7,"phase":"%s","status":"%s","duration":95000,"scm":{"branch":"master"}}}`
+
+var NormalizeTests = []struct {
+	phase     string
+	status    string
+	phases    string
+	wantTitle string
+	wantColor bool
+	wantErr   bool
+}{
+	{"STARTED", "", "", "[Build #7](https://ci.example.com/job/chathooks/7/) for **chathooks** started", false, false},
+	{"STARTED", "SUCCESS", "", "[Build #7](https://ci.example.com/job/chathooks/7/) for **chathooks** started", false, false},
+	{"COMPLETED", "FAILURE", "", "[Build #7](https://ci.example.com/job/chathooks/7/) for **chathooks** failed", true, false},
+	{"FINALIZED", "SUCCESS", "", "[Build #7](https://ci.example.com/job/chathooks/7/) for **chathooks** passed", true, false},
+	{"FINALIZED", "UNSTABLE", "", "[Build #7](https://ci.example.com/job/chathooks/7/) for **chathooks** is unstable", true, false},
+	{"QUEUED", "", "", "[Build #7](https://ci.example.com/job/chathooks/7/) for **chathooks** is queued", false, false},
+	{"FINALIZED", "SUCCESS", "started, finalized", "[Build #7](https://ci.example.com/job/chathooks/7/) for **chathooks** passed", true, false},
+	{"COMPLETED", "SUCCESS", "STARTED,FINALIZED", "", false, true}}
+
+func TestNormalize(t *testing.T) {
+	for _, tt := range NormalizeTests {
+		qry := url.Values{}
+		qry.Set(JenkinsQryVarPhases, tt.phases)
+		ccMsg, err := Normalize(config.Configuration{}, handlers.HandlerRequest{
+			QueryParams: qry, Body: []byte(fmt.Sprintf(testBuildFormat, tt.phase, tt.status))})
+		if tt.wantErr != (err != nil) {
+			t.Errorf("jenkins.Normalize(%v,%v,%v): want err %v, got %v", tt.phase, tt.status, tt.phases, tt.wantErr, err)
+			continue
+		} else if tt.wantErr {
+			continue
+		}
+		if ccMsg.Title != tt.wantTitle {
+			t.Errorf("jenkins.Normalize(%v,%v,%v): want title %v, got %v", tt.phase, tt.status, tt.phases, tt.wantTitle, ccMsg.Title)
+		}
+		gotColor := len(ccMsg.Attachments) > 0 && len(ccMsg.Attachments[0].Color) > 0
+		if gotColor != tt.wantColor {
+			t.Errorf("jenkins.Normalize(%v,%v,%v): want color %v, got %v", tt.phase, tt.status, tt.phases, tt.wantColor, gotColor)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package k8sevents
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	cc "github.com/grokify/commonchat"
+	"github.com/grokify/simplego/html/htmlutil"
+	"github.com/grokify/simplego/type/stringsutil"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/grokify/chathooks/pkg/config"
+	"github.com/grokify/chathooks/pkg/handlers"
+	"github.com/grokify/chathooks/pkg/models"
+)
+
+const (
+	DisplayName      = "Kubernetes Events"
+	HandlerKey       = "k8sevents"
+	MessageDirection = "out"
+	DocumentationURL = "https://kubernetes.io/docs/reference/kubernetes-api/cluster-resources/event-v1/"
+	MessageBodyType  = models.JSON
+
+	K8seventsQryVarReasons    = "k8seventsReasons"
+	K8seventsQryVarNamespaces = "k8seventsNamespaces"
+)
+
+func NewHandler() handlers.Handler {
+	return handlers.Handler{
+		Key:             HandlerKey,
+		MessageBodyType: MessageBodyType,
+		Normalize:       Normalize}
+}
+
+func init() {
+	handlers.Register(handlers.HandlerInfo{
+		Key:              HandlerKey,
+		DisplayName:      DisplayName,
+		MessageDirection: MessageDirection,
+		MessageBodyType:  MessageBodyType,
+		DocumentationURL: DocumentationURL,
+		ExampleSlugs:     []string{"pod-backoff", "deployment-scaled"},
+		CustomParams: []handlers.CustomParam{
+			{Name: K8seventsQryVarReasons, Description: "Comma-delimited event reasons to send."},
+			{Name: K8seventsQryVarNamespaces, Description: "Comma-delimited namespaces to send."},
+		},
+		NewHandler:     NewHandler,
+		ExampleMessage: ExampleMessage})
+}
+
+func Normalize(cfg config.Configuration, hReq handlers.HandlerRequest) (cc.Message, error) {
+	if hReq.QueryParams == nil {
+		hReq.QueryParams = url.Values{}
+	}
+	ccMsg := cc.NewMessage()
+	iconURL, err := cfg.GetAppIconURL(HandlerKey)
+	if err == nil {
+		ccMsg.IconURL = iconURL.String()
+	}
+
+	src, err := K8seventsOutMessageFromBytes(hReq.Body)
+	if err != nil {
+		return ccMsg, err
+	}
+
+	reasons := splitQueryParam(hReq.QueryParams, K8seventsQryVarReasons)
+	if len(reasons) > 0 && !containsFold(reasons, src.Reason) {
+		return ccMsg, errors.New("SKIP_K8SEVENTS_REASON_NOT_MATCHED")
+	}
+	namespaces := splitQueryParam(hReq.QueryParams, K8seventsQryVarNamespaces)
+	if len(namespaces) > 0 && !containsFold(namespaces, src.Namespace()) {
+		return ccMsg, errors.New("SKIP_K8SEVENTS_NAMESPACE_NOT_MATCHED")
+	}
+
+	ccMsg.Activity = fmt.Sprintf("%s event", src.TypeDisplay())
+
+	ccMsg.Title = fmt.Sprintf("%s **%s** %s",
+		src.InvolvedObject.Kind,
+		src.ObjectDisplay(),
+		src.Reason)
+
+	attachment := cc.NewAttachment()
+	if strings.EqualFold(src.Type, "Warning") {
+		attachment.Color = htmlutil.Color2RedHex
+	} else {
+		attachment.Color = htmlutil.Color2GreenHex
+	}
+
+	if len(strings.TrimSpace(src.Message)) > 0 {
+		attachment.AddField(cc.Field{Title: "Message", Value: strings.TrimSpace(src.Message)})
+	}
+	if len(src.Reason) > 0 {
+		attachment.AddField(cc.Field{Title: "Reason", Value: src.Reason, Short: true})
+	}
+	if ns := src.Namespace(); len(ns) > 0 {
+		attachment.AddField(cc.Field{Title: "Namespace", Value: ns, Short: true})
+	}
+	if component := src.Component(); len(component) > 0 {
+		attachment.AddField(cc.Field{Title: "Component", Value: component, Short: true})
+	}
+	if src.Count > 1 {
+		attachment.AddField(cc.Field{Title: "Count", Value: fmt.Sprintf("%v", src.Count), Short: true})
+	}
+	if len(src.ClusterName) > 0 {
+		attachment.AddField(cc.Field{Title: "Cluster", Value: src.ClusterName, Short: true})
+	}
+
+	ccMsg.AddAttachment(attachment)
+	return ccMsg, nil
+}
+
+func splitQueryParam(qry url.Values, key string) []string {
+	return stringsutil.SliceCondenseSpace(strings.Split(qry.Get(key), ","), true, false)
+}
+
+func containsFold(haystack []string, needle string) bool {
+	for _, try := range haystack {
+		if strings.EqualFold(try, needle) {
+			return true
+		}
+	}
+	return false
+}
+
+// K8seventsOutMessage is a `core/v1` Kubernetes Event as emitted
+// by event exporters such as `kubernetes-event-exporter`.
+type K8seventsOutMessage struct {
+	Metadata           K8seventsObjectMeta     `json:"metadata,omitempty"`
+	Reason             string                  `json:"reason,omitempty"`
+	Message            string                  `json:"message,omitempty"`
+	Source             K8seventsEventSource    `json:"source,omitempty"`
+	FirstTimestamp     string                  `json:"firstTimestamp,omitempty"`
+	LastTimestamp      string                  `json:"lastTimestamp,omitempty"`
+	Count              int64                   `json:"count,omitempty"`
+	Type               string                  `json:"type,omitempty"`
+	ReportingComponent string                  `json:"reportingComponent,omitempty"`
+	ReportingInstance  string                  `json:"reportingInstance,omitempty"`
+	InvolvedObject     K8seventsInvolvedObject `json:"involvedObject,omitempty"`
+	ClusterName        string                  `json:"clusterName,omitempty"`
+}
+
+func K8seventsOutMessageFromBytes(bytes []byte) (K8seventsOutMessage, error) {
+	msg := K8seventsOutMessage{}
+	err := json.Unmarshal(bytes, &msg)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("type", "message.json.unmarshal").
+			Str("handler", HandlerKey).
+			Msg(config.ErrorInputMessageParseFailed)
+	}
+	return msg, err
+}
+
+// Namespace returns the namespace of the involved object, falling back
+// to the namespace of the event itself.
+func (msg *K8seventsOutMessage) Namespace() string {
+	if len(msg.InvolvedObject.Namespace) > 0 {
+		return msg.InvolvedObject.Namespace
+	}
+	return msg.Metadata.Namespace
+}
+
+func (msg *K8seventsOutMessage) Component() string {
+	if len(msg.Source.Component) > 0 {
+		return msg.Source.Component
+	}
+	return msg.ReportingComponent
+}
+
+func (msg *K8seventsOutMessage) TypeDisplay() string {
+	if len(msg.Type) == 0 {
+		return "Normal"
+	}
+	return msg.Type
+}
+
+func (msg *K8seventsOutMessage) ObjectDisplay() string {
+	if ns := msg.Namespace(); len(ns) > 0 {
+		return fmt.Sprintf("%s/%s", ns, msg.InvolvedObject.Name)
+	}
+	return msg.InvolvedObject.Name
+}
+
+type K8seventsObjectMeta struct {
+	Name              string `json:"name,omitempty"`
+	Namespace         string `json:"namespace,omitempty"`
+	UID               string `json:"uid,omitempty"`
+	CreationTimestamp string `json:"creationTimestamp,omitempty"`
+}
+
+type K8seventsEventSource struct {
+	Component string `json:"component,omitempty"`
+	Host      string `json:"host,omitempty"`
+}
+
+type K8seventsInvolvedObject struct {
+	Kind       string            `json:"kind,omitempty"`
+	Namespace  string            `json:"namespace,omitempty"`
+	Name       string            `json:"name,omitempty"`
+	UID        string            `json:"uid,omitempty"`
+	APIVersion string            `json:"apiVersion,omitempty"`
+	FieldPath  string            `json:"fieldPath,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+}
@@ -0,0 +1,40 @@
+package k8sevents
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/grokify/chathooks/pkg/config"
+	"github.com/grokify/chathooks/pkg/handlers"
+)
+
+const testEvent = `{"reason":"BackOff","message":"Back-off restarting failed container","type":"Warning","involvedObject":{"kind":"Pod","namespace":"guestbook","name":"guestbook-ui"}}`
+
+var NormalizeFilterTests = []struct {
+	reasons    string
+	namespaces string
+	wantSkip   bool
+}{
+	{"", "", false},
+	{"backoff", "", false},
+	{"FailedScheduling,BackOff", "guestbook", false},
+	{"FailedScheduling", "", true},
+	{"", "kube-system", true},
+	{"BackOff", "kube-system", true}}
+
+func TestNormalizeFilters(t *testing.T) {
+	for _, tt := range NormalizeFilterTests {
+		qry := url.Values{}
+		qry.Set(K8seventsQryVarReasons, tt.reasons)
+		qry.Set(K8seventsQryVarNamespaces, tt.namespaces)
+
+		ccMsg, err := Normalize(config.Configuration{},
+			handlers.HandlerRequest{QueryParams: qry, Body: []byte(testEvent)})
+		if tt.wantSkip != (err != nil) {
+			t.Errorf("k8sevents.Normalize(%v,%v): want skip %v, got err %v", tt.reasons, tt.namespaces, tt.wantSkip, err)
+		}
+		if !tt.wantSkip && ccMsg.Title != "Pod **guestbook/guestbook-ui** BackOff" {
+			t.Errorf("k8sevents.Normalize(%v,%v): want title %v, got %v", tt.reasons, tt.namespaces, "Pod **guestbook/guestbook-ui** BackOff", ccMsg.Title)
+		}
+	}
+}
@@ -25,6 +25,22 @@ func NewHandler() handlers.Handler {
 	return handlers.Handler{MessageBodyType: MessageBodyType, Normalize: Normalize}
 }
 
+func init() {
+	handlers.Register(handlers.HandlerInfo{
+		Key:              HandlerKey,
+		DisplayName:      DisplayName,
+		MessageDirection: MessageDirection,
+		MessageBodyType:  MessageBodyType,
+		ExampleSlugs: []string{
+			"create", "close", "delete", "acknowledge", "unacknowledge",
+			"add-note", "add-recipient", "add-tags", "add-team",
+			"remove-tags", "assign-ownership", "take-ownership", "escalate",
+			"custom-action-test-action"},
+		Correlation:    []string{"alert.alertId"},
+		NewHandler:     NewHandler,
+		ExampleMessage: ExampleMessage})
+}
+
 func Normalize(cfg config.Configuration, hReq handlers.HandlerRequest) (cc.Message, error) {
 	ccMsg := cc.NewMessage()
 	iconURL, err := cfg.GetAppIconURL(HandlerKey)
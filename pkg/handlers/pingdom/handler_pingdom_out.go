@@ -24,6 +24,21 @@ func NewHandler() handlers.Handler {
 	return handlers.Handler{MessageBodyType: MessageBodyType, Normalize: Normalize}
 }
 
+func init() {
+	handlers.Register(handlers.HandlerInfo{
+		Key:              HandlerKey,
+		DisplayName:      DisplayName,
+		MessageDirection: MessageDirection,
+		MessageBodyType:  MessageBodyType,
+		DocumentationURL: DocumentationURL,
+		ExampleSlugs: []string{
+			"dns-check", "http-check", "http-custom-check", "imap-check", "ping-check",
+			"pop3-check", "smtp-check", "tcp-check", "transaction-check", "udp-check"},
+		Fingerprint:    []string{"check_id", "current_state"},
+		NewHandler:     NewHandler,
+		ExampleMessage: ExampleMessage})
+}
+
 func Normalize(cfg config.Configuration, hReq handlers.HandlerRequest) (cc.Message, error) {
 	ccMsg := cc.NewMessage()
 	iconURL, err := cfg.GetAppIconURL(HandlerKey)
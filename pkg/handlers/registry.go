@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	cc "github.com/grokify/commonchat"
+
+	"github.com/grokify/chathooks/pkg/config"
+	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/util"
+)
+
+// ExampleMessageFunc builds a message from the example
+// file for `eventSlug`.
+type ExampleMessageFunc func(cfg config.Configuration, data util.ExampleData, eventSlug string) (cc.Message, error)
+
+// SingleExample adapts handlers with one example file
+// that is not selected by slug.
+func SingleExample(fn func(cfg config.Configuration, data util.ExampleData) (cc.Message, error)) ExampleMessageFunc {
+	return func(cfg config.Configuration, data util.ExampleData, eventSlug string) (cc.Message, error) {
+		return fn(cfg, data)
+	}
+}
+
+// CustomParam is a handler specific query parameter.
+type CustomParam struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// HandlerInfo describes an input handler. Handler packages register
+// it in `init()` so the service, examples and the `/handlers` catalog
+// share one list. `NewTemplatedHandler` is set by input types that can
+// format their events with a `CHATHOOKS_TEMPLATES_FILE` template.
+type HandlerInfo struct {
+	Key                  string                    `json:"key"`
+	DisplayName          string                    `json:"displayName"`
+	MessageDirection     string                    `json:"messageDirection,omitempty"`
+	MessageBodyType      models.MessageBodyType    `json:"messageBodyType"`
+	DocumentationURL     string                    `json:"documentationUrl,omitempty"`
+	ExampleFileExtension string                    `json:"exampleFileExtension,omitempty"`
+	ExampleSlugs         []string                  `json:"exampleSlugs,omitempty"`
+	CustomParams         []CustomParam             `json:"customParams,omitempty"`
+	Fingerprint          []string                  `json:"fingerprint,omitempty"`
+	Correlation          []string                  `json:"correlation,omitempty"`
+	NewHandler           func() Handler            `json:"-"`
+	NewTemplatedHandler  func(tmpl string) Handler `json:"-"`
+	ExampleMessage       ExampleMessageFunc        `json:"-"`
+}
+
+var (
+	registryMutex sync.RWMutex
+	registry      = map[string]HandlerInfo{}
+)
+
+// Register adds a handler to the registry. It panics if the key is
+// empty or already registered, like `database/sql.Register`.
+func Register(info HandlerInfo) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	info.Key = strings.TrimSpace(info.Key)
+	if len(info.Key) == 0 {
+		panic("handlers: Register key is empty")
+	} else if info.NewHandler == nil {
+		panic(fmt.Sprintf("handlers: Register NewHandler is nil [%s]", info.Key))
+	} else if _, ok := registry[info.Key]; ok {
+		panic(fmt.Sprintf("handlers: Register called twice [%s]", info.Key))
+	}
+	registry[info.Key] = info
+}
+
+// Registered returns the registered handlers sorted by key.
+func Registered() []HandlerInfo {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	infos := []HandlerInfo{}
+	for _, info := range registry {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+	return infos
+}
+
+// LookupHandlerInfo returns the registered handler for `key`.
+func LookupHandlerInfo(key string) (HandlerInfo, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	info, ok := registry[key]
+	return info, ok
+}
+
+// NewExampleData returns the example file information
+// for the registered handlers.
+func NewExampleData() util.ExampleData {
+	data := util.ExampleData{Data: map[string]util.ExampleSource{}}
+	for _, info := range Registered() {
+		if len(info.ExampleSlugs) == 0 && len(info.ExampleFileExtension) == 0 {
+			continue
+		}
+		data.Data[info.Key] = util.ExampleSource{
+			FileExtension: info.ExampleFileExtension,
+			EventSlugs:    info.ExampleSlugs}
+	}
+	return data
+}
@@ -2,14 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
-	"regexp"
-	"strconv"
+	"fmt"
+	"io/ioutil"
 	"strings"
 
 	cc "github.com/grokify/commonchat"
-	"github.com/tidwall/gjson"
 
 	"github.com/grokify/chathooks/pkg/config"
+	"github.com/grokify/chathooks/pkg/models"
 )
 
 func NewTemplatedHandler(tmpl string) Handler {
@@ -19,28 +19,99 @@ func NewTemplatedHandler(tmpl string) Handler {
 }
 
 func getTemplatedNormalizer(tmpl string) func(cfg config.Configuration, hReq HandlerRequest) (cc.Message, error) {
+	mt, parseErr := ParseMessageTemplate(tmpl)
 	return func(cfg config.Configuration, hReq HandlerRequest) (cc.Message, error) {
-		ccMsg := cc.NewMessage()
-		src := string(hReq.Body)
-
-		tokenPattern := regexp.MustCompile(`\${.+?}`)
-		keyPattern := regexp.MustCompile(`\${(.+?)}`)
-		formattedJson := tokenPattern.ReplaceAllStringFunc(tmpl, func(match string) string {
-			matches := keyPattern.FindStringSubmatch(match)
-			result := gjson.Get(src, strings.TrimSpace(matches[1]))
-			switch result.Type {
-			case gjson.String:
-				return result.Str
-			case gjson.Number:
-				return strconv.FormatFloat(result.Num, 'f', -1, 64)
-			case gjson.JSON:
-				return result.Raw
-			default:
-				return result.Type.String()
-			}
-		})
-
-		err := json.Unmarshal([]byte(formattedJson), &ccMsg)
-		return ccMsg, err
+		if parseErr != nil {
+			return cc.NewMessage(), parseErr
+		}
+		return mt.Message(hReq.Body)
 	}
 }
+
+// RenderTemplate renders a `cc.Message` JSON template using values
+// from `src`. See `MessageTemplate` for the template syntax.
+func RenderTemplate(tmpl string, src []byte) (cc.Message, error) {
+	mt, err := ParseMessageTemplate(tmpl)
+	if err != nil {
+		return cc.NewMessage(), err
+	}
+	return mt.Message(src)
+}
+
+// TemplatedHandlerDefinition is a user-defined input type that is
+// formatted with a `cc.Message` JSON template. With `InputType`, the
+// body is parsed by that registered input type, e.g. `cloudevents`.
+type TemplatedHandlerDefinition struct {
+	Name            string                 `json:"name"`
+	InputType       string                 `json:"-"`
+	MessageBodyType models.MessageBodyType `json:"-"`
+	Template        string                 `json:"-"`
+}
+
+// UnmarshalJSON reads `messageBodyType` by name, e.g. `url_encoded`,
+// and `template` as either a string or a JSON object.
+func (def *TemplatedHandlerDefinition) UnmarshalJSON(raw []byte) error {
+	aux := struct {
+		Name            string          `json:"name"`
+		InputType       string          `json:"inputType"`
+		MessageBodyType string          `json:"messageBodyType"`
+		Template        json.RawMessage `json:"template"`
+	}{}
+	if err := json.Unmarshal(raw, &aux); err != nil {
+		return err
+	}
+	def.Name = strings.ToLower(strings.TrimSpace(aux.Name))
+	if len(def.Name) == 0 {
+		return fmt.Errorf("E_TEMPLATED_HANDLER_NO_NAME")
+	}
+	def.InputType = strings.ToLower(strings.TrimSpace(aux.InputType))
+	bodyType, err := models.ParseMessageBodyType(aux.MessageBodyType)
+	if err != nil {
+		return err
+	}
+	def.MessageBodyType = bodyType
+	tmpl := ""
+	if err := json.Unmarshal(aux.Template, &tmpl); err != nil {
+		tmpl = string(aux.Template)
+	}
+	def.Template = strings.TrimSpace(tmpl)
+	if len(def.Template) == 0 {
+		return fmt.Errorf("E_TEMPLATED_HANDLER_NO_TEMPLATE [%s]", def.Name)
+	}
+	return nil
+}
+
+// NewHandler returns a handler for the definition. Template errors are
+// reported when the definition is loaded.
+func (def *TemplatedHandlerDefinition) NewHandler() (Handler, error) {
+	if _, err := ParseMessageTemplate(def.Template); err != nil {
+		return Handler{}, fmt.Errorf("%s [%s]", err.Error(), def.Name)
+	}
+	handler := NewTemplatedHandler(def.Template)
+	if len(def.InputType) > 0 {
+		info, ok := LookupHandlerInfo(def.InputType)
+		if !ok || info.NewTemplatedHandler == nil {
+			return Handler{}, fmt.Errorf("E_TEMPLATED_HANDLER_INPUT_TYPE_NOT_SUPPORTED [%s] [%s]", def.Name, def.InputType)
+		}
+		handler = info.NewTemplatedHandler(def.Template)
+		def.MessageBodyType = info.MessageBodyType
+	}
+	handler.Key = def.Name
+	handler.MessageBodyType = def.MessageBodyType
+	return handler, nil
+}
+
+// ReadTemplatedHandlerDefinitions reads a JSON file of the form
+// `{"handlers":[{"name":"...","messageBodyType":"json","template":{...}}]}`.
+// Entries may set `inputType` instead of `messageBodyType`.
+func ReadTemplatedHandlerDefinitions(filepath string) ([]TemplatedHandlerDefinition, error) {
+	bytes, err := ioutil.ReadFile(filepath)
+	if err != nil {
+		return []TemplatedHandlerDefinition{}, err
+	}
+	file := struct {
+		Handlers []TemplatedHandlerDefinition `json:"handlers"`
+	}{}
+	err = json.Unmarshal(bytes, &file)
+	return file.Handlers, err
+}
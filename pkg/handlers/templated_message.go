@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	cc "github.com/grokify/commonchat"
+	"github.com/tidwall/gjson"
+)
+
+// MessageTemplate is a `cc.Message` JSON template. Tokens are written as
+// `${...}` and read values from the request body using gjson paths:
+//
+//	${path}                     value of `path`
+//	${path ?? default}          `default` when `path` is missing, null or ""
+//	${#if path}...${#else}...${/if}
+//	${#unless path}...${/unless}
+//	${#each path}...${/each}    loop over an array or object values
+//
+// Inside `#each`, paths are relative to the current element. `@this` is
+// the element, `@index`, `@first` and `@last` describe the position and
+// `@root.path` reads from the whole body. Use `$${` for a literal `${`.
+//
+// Values written inside a JSON string are escaped for that string. Values
+// written outside a string are written as JSON, e.g.
+// `"attachments": ${data.attachments}`.
+type MessageTemplate struct {
+	nodes []tmplNode
+}
+
+type tmplNode interface{}
+
+type tmplText string
+
+type tmplValue struct {
+	path       string
+	fallback   string
+	isFallback bool
+}
+
+type tmplCond struct {
+	path   string
+	negate bool
+	then   []tmplNode
+	els    []tmplNode
+}
+
+type tmplEach struct {
+	path string
+	body []tmplNode
+}
+
+// ParseMessageTemplate parses a template so it can be rendered
+// multiple times.
+func ParseMessageTemplate(tmpl string) (*MessageTemplate, error) {
+	tokens, err := tokenizeTemplate(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	p := tmplParser{tokens: tokens}
+	nodes, end, err := p.parse()
+	if err != nil {
+		return nil, err
+	} else if len(end) > 0 {
+		return nil, fmt.Errorf("E_TEMPLATE_UNEXPECTED_TOKEN [%s]", end)
+	}
+	return &MessageTemplate{nodes: nodes}, nil
+}
+
+// Render returns the rendered template JSON.
+func (mt *MessageTemplate) Render(src []byte) []byte {
+	w := tmplWriter{}
+	root := gjson.ParseBytes(src)
+	w.render(mt.nodes, tmplScope{root: root, value: root})
+	return w.buf.Bytes()
+}
+
+// Message renders the template and parses it as a `cc.Message`.
+func (mt *MessageTemplate) Message(src []byte) (cc.Message, error) {
+	ccMsg := cc.NewMessage()
+	err := json.Unmarshal(mt.Render(src), &ccMsg)
+	return ccMsg, err
+}
+
+type tmplToken struct {
+	text    string
+	isToken bool
+}
+
+func tokenizeTemplate(tmpl string) ([]tmplToken, error) {
+	tokens := []tmplToken{}
+	text := strings.Builder{}
+	for len(tmpl) > 0 {
+		if strings.HasPrefix(tmpl, "$${") {
+			text.WriteString("${")
+			tmpl = tmpl[3:]
+			continue
+		} else if !strings.HasPrefix(tmpl, "${") {
+			text.WriteByte(tmpl[0])
+			tmpl = tmpl[1:]
+			continue
+		}
+		end := strings.Index(tmpl, "}")
+		if end < 0 {
+			return tokens, fmt.Errorf("E_TEMPLATE_UNCLOSED_TOKEN [%s]", tmpl)
+		}
+		if text.Len() > 0 {
+			tokens = append(tokens, tmplToken{text: text.String()})
+			text.Reset()
+		}
+		tokens = append(tokens, tmplToken{
+			text:    strings.TrimSpace(tmpl[2:end]),
+			isToken: true})
+		tmpl = tmpl[end+1:]
+	}
+	if text.Len() > 0 {
+		tokens = append(tokens, tmplToken{text: text.String()})
+	}
+	return tokens, nil
+}
+
+type tmplParser struct {
+	tokens []tmplToken
+	pos    int
+}
+
+// parse reads nodes until the end of input or a closing token, which
+// is returned for the caller to check.
+func (p *tmplParser) parse() ([]tmplNode, string, error) {
+	nodes := []tmplNode{}
+	for p.pos < len(p.tokens) {
+		tok := p.tokens[p.pos]
+		p.pos++
+		if !tok.isToken {
+			nodes = append(nodes, tmplText(tok.text))
+			continue
+		}
+		switch {
+		case tok.text == "#else" || strings.HasPrefix(tok.text, "/"):
+			return nodes, tok.text, nil
+		case strings.HasPrefix(tok.text, "#if ") || strings.HasPrefix(tok.text, "#unless "):
+			parts := strings.SplitN(tok.text, " ", 2)
+			cond := tmplCond{
+				path:   strings.TrimSpace(parts[1]),
+				negate: parts[0] == "#unless"}
+			closing := "/" + strings.TrimPrefix(parts[0], "#")
+			then, end, err := p.parse()
+			if err != nil {
+				return nodes, "", err
+			}
+			cond.then = then
+			if end == "#else" {
+				cond.els, end, err = p.parse()
+				if err != nil {
+					return nodes, "", err
+				}
+			}
+			if end != closing {
+				return nodes, "", fmt.Errorf("E_TEMPLATE_MISSING_CLOSE [%s]", closing)
+			}
+			nodes = append(nodes, cond)
+		case strings.HasPrefix(tok.text, "#each "):
+			each := tmplEach{path: strings.TrimSpace(strings.TrimPrefix(tok.text, "#each "))}
+			body, end, err := p.parse()
+			if err != nil {
+				return nodes, "", err
+			} else if end != "/each" {
+				return nodes, "", fmt.Errorf("E_TEMPLATE_MISSING_CLOSE [%s]", "/each")
+			}
+			each.body = body
+			nodes = append(nodes, each)
+		case strings.HasPrefix(tok.text, "#"):
+			return nodes, "", fmt.Errorf("E_TEMPLATE_UNKNOWN_BLOCK [%s]", tok.text)
+		default:
+			val := tmplValue{path: tok.text}
+			if idx := strings.Index(tok.text, "??"); idx >= 0 {
+				val.path = strings.TrimSpace(tok.text[:idx])
+				val.fallback = strings.TrimSpace(tok.text[idx+2:])
+				val.isFallback = true
+			}
+			nodes = append(nodes, val)
+		}
+	}
+	return nodes, "", nil
+}
+
+type tmplScope struct {
+	root   gjson.Result
+	value  gjson.Result
+	inLoop bool
+	index  int
+	last   bool
+}
+
+func (s tmplScope) lookup(path string) gjson.Result {
+	switch {
+	case path == "@this" || path == ".":
+		return s.value
+	case path == "@root":
+		return s.root
+	case strings.HasPrefix(path, "@root."):
+		return s.root.Get(strings.TrimPrefix(path, "@root."))
+	case s.inLoop && path == "@index":
+		return gjson.Parse(strconv.Itoa(s.index))
+	case s.inLoop && path == "@first":
+		return gjson.Parse(strconv.FormatBool(s.index == 0))
+	case s.inLoop && path == "@last":
+		return gjson.Parse(strconv.FormatBool(s.last))
+	}
+	return s.value.Get(path)
+}
+
+func isEmptyResult(r gjson.Result) bool {
+	return !r.Exists() || r.Type == gjson.Null || (r.Type == gjson.String && len(r.Str) == 0)
+}
+
+func isTruthyResult(r gjson.Result) bool {
+	switch r.Type {
+	case gjson.True:
+		return true
+	case gjson.String:
+		return len(r.Str) > 0
+	case gjson.Number:
+		return r.Num != 0
+	case gjson.JSON:
+		if r.IsArray() {
+			return len(r.Array()) > 0
+		}
+		return len(r.Map()) > 0
+	}
+	return false
+}
+
+type tmplWriter struct {
+	buf      bytes.Buffer
+	inString bool
+	escaped  bool
+}
+
+func (w *tmplWriter) render(nodes []tmplNode, scope tmplScope) {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case tmplText:
+			w.writeText(string(n))
+		case tmplValue:
+			w.writeValue(scope.lookup(n.path), n)
+		case tmplCond:
+			if isTruthyResult(scope.lookup(n.path)) != n.negate {
+				w.render(n.then, scope)
+			} else {
+				w.render(n.els, scope)
+			}
+		case tmplEach:
+			items := []gjson.Result{}
+			scope.lookup(n.path).ForEach(func(_, val gjson.Result) bool {
+				items = append(items, val)
+				return true
+			})
+			for i, item := range items {
+				w.render(n.body, tmplScope{
+					root:   scope.root,
+					value:  item,
+					inLoop: true,
+					index:  i,
+					last:   i == len(items)-1})
+			}
+		}
+	}
+}
+
+// writeText writes template text, tracking whether the output
+// is inside a JSON string.
+func (w *tmplWriter) writeText(s string) {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if w.inString {
+			if w.escaped {
+				w.escaped = false
+			} else if c == '\\' {
+				w.escaped = true
+			} else if c == '"' {
+				w.inString = false
+			}
+		} else if c == '"' {
+			w.inString = true
+		}
+	}
+	w.buf.WriteString(s)
+}
+
+func (w *tmplWriter) writeValue(r gjson.Result, val tmplValue) {
+	useFallback := val.isFallback && isEmptyResult(r)
+	if w.inString {
+		str := ""
+		if useFallback {
+			str = unquoteFallback(val.fallback)
+		} else if r.Exists() {
+			switch r.Type {
+			case gjson.String:
+				str = r.Str
+			case gjson.Null:
+				str = ""
+			default:
+				str = r.Raw
+			}
+		}
+		w.buf.WriteString(escapeJSONString(str))
+		return
+	}
+	if useFallback {
+		if json.Valid([]byte(val.fallback)) {
+			w.buf.WriteString(val.fallback)
+		} else {
+			w.buf.WriteString(`"` + escapeJSONString(val.fallback) + `"`)
+		}
+	} else if r.Exists() {
+		w.buf.WriteString(r.Raw)
+	} else {
+		w.buf.WriteString("null")
+	}
+}
+
+func unquoteFallback(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			return unquoted
+		}
+	}
+	return s
+}
+
+// escapeJSONString escapes a string for use inside a JSON string literal.
+func escapeJSONString(s string) string {
+	buf := bytes.Buffer{}
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return ""
+	}
+	out := strings.TrimSuffix(buf.String(), "\n")
+	return out[1 : len(out)-1]
+}
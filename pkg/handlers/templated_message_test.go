@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testTemplateBody = `{"name":"say \"hi\"","count":2,"empty":"","ok":true,"items":[{"n":"a"},{"n":"b"}],"attachments":[{"title":"t"}]}`
+
+var RenderTemplateTests = []struct {
+	tmpl string
+	want string
+}{
+	{`{"text":"${name}"}`, `{"text":"say \"hi\""}`},
+	{`{"text":"${count} / ${missing}"}`, `{"text":"2 / "}`},
+	{`{"text":"${empty ?? none}","title":"${missing ?? "a \"b\""}"}`, `{"text":"none","title":"a \"b\""}`},
+	{`{"text":"${#if ok}yes${#else}no${/if} ${#unless ok}x${/unless}"}`, `{"text":"yes "}`},
+	{`{"text":"${#each items}${n}${#unless @last},${/unless}${/each}"}`, `{"text":"a,b"}`},
+	{`{"text":"${#each items}${@index}:${@root.count} ${/each}"}`, `{"text":"0:2 1:2 "}`},
+	{`{"text":"$${name}","attachments":${attachments}}`, `{"text":"${name}","attachments":[{"title":"t"}]}`},
+	{`{"text":${missing ?? "x"},"title":${missing}}`, `{"text":"x","title":null}`}}
+
+func TestRenderTemplate(t *testing.T) {
+	for _, tt := range RenderTemplateTests {
+		mt, err := ParseMessageTemplate(tt.tmpl)
+		if err != nil {
+			t.Errorf("ParseMessageTemplate(%v): want no error, got %v", tt.tmpl, err)
+			continue
+		}
+		got := string(mt.Render([]byte(testTemplateBody)))
+		if got != tt.want {
+			t.Errorf("MessageTemplate.Render(%v): want %v, got %v", tt.tmpl, tt.want, got)
+		}
+		if !json.Valid([]byte(got)) {
+			t.Errorf("MessageTemplate.Render(%v): want valid JSON, got %v", tt.tmpl, got)
+		}
+	}
+}
+
+var ParseMessageTemplateErrorTests = []string{
+	`{"text":"${name"`,
+	`{"text":"${#if ok}yes"}`,
+	`{"text":"${#each items}${/if}"}`,
+	`{"text":"${/each}"}`,
+	`{"text":"${#with items}"}`}
+
+func TestParseMessageTemplateErrors(t *testing.T) {
+	for _, tmpl := range ParseMessageTemplateErrorTests {
+		if _, err := ParseMessageTemplate(tmpl); err == nil {
+			t.Errorf("ParseMessageTemplate(%v): want error, got nil", tmpl)
+		}
+	}
+}
+
+func TestTemplatedHandlerDefinition(t *testing.T) {
+	raw := `{"handlers":[{"name":"MyApp","messageBodyType":"url_encoded_json_payload","template":{"text":"${name}"}}]}`
+	file := struct {
+		Handlers []TemplatedHandlerDefinition `json:"handlers"`
+	}{}
+	if err := json.Unmarshal([]byte(raw), &file); err != nil {
+		t.Fatalf("json.Unmarshal(TemplatedHandlerDefinition): want no error, got %v", err)
+	}
+	handler, err := file.Handlers[0].NewHandler()
+	if err != nil {
+		t.Fatalf("TemplatedHandlerDefinition.NewHandler(): want no error, got %v", err)
+	}
+	if handler.Key != "myapp" || handler.MessageBodyType.String() != "url_encoded_json_payload" {
+		t.Errorf("TemplatedHandlerDefinition.NewHandler(): want myapp/url_encoded_json_payload, got %v/%v",
+			handler.Key, handler.MessageBodyType)
+	}
+	ccMsg, err := handler.Normalize(handler.Config, HandlerRequest{Body: []byte(testTemplateBody)})
+	if err != nil || ccMsg.Text != `say "hi"` {
+		t.Errorf("Handler.Normalize(): want %v, got %v (%v)", `say "hi"`, ccMsg.Text, err)
+	}
+}
+
+func TestTemplatedHandlerDefinitionInputType(t *testing.T) {
+	def := TemplatedHandlerDefinition{Name: "myapp", InputType: "unknown", Template: `{"text":"${name}"}`}
+	if _, err := def.NewHandler(); err == nil || !strings.Contains(err.Error(), "E_TEMPLATED_HANDLER_INPUT_TYPE_NOT_SUPPORTED") {
+		t.Errorf("TemplatedHandlerDefinition.NewHandler(unknown): want E_TEMPLATED_HANDLER_INPUT_TYPE_NOT_SUPPORTED, got %v", err)
+	}
+}
@@ -3,9 +3,7 @@ package travisci
 import (
 	"encoding/json"
 	"fmt"
-	"math"
 	"strings"
-	"time"
 
 	cc "github.com/grokify/commonchat"
 	"github.com/rs/zerolog/log"
@@ -13,6 +11,7 @@ import (
 	"github.com/grokify/chathooks/pkg/config"
 	"github.com/grokify/chathooks/pkg/handlers"
 	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/util"
 )
 
 const (
@@ -26,6 +25,16 @@ func NewHandler() handlers.Handler {
 	return handlers.Handler{MessageBodyType: MessageBodyType, Normalize: Normalize}
 }
 
+func init() {
+	handlers.Register(handlers.HandlerInfo{
+		Key:              HandlerKey,
+		DisplayName:      DisplayName,
+		MessageDirection: MessageDirection,
+		MessageBodyType:  MessageBodyType,
+		NewHandler:       NewHandler,
+		ExampleMessage:   handlers.SingleExample(ExampleMessage)})
+}
+
 func StatusMessageSuffix(statusMessage string) string {
 	suffixes := map[string]string{
 		"pending":       "is pending",
@@ -122,12 +131,6 @@ type TravisciOutMessage struct {
 }
 
 func TravisciOutMessageFromBytes(bytes []byte) (TravisciOutMessage, error) {
-	log.Debug().
-		Str("type", "message.raw").
-		Str("handler", HandlerKey).
-		Str("request_body", string(bytes)).
-		Msg(config.InfoInputMessageParseBegin)
-
 	msg := TravisciOutMessage{}
 	err := json.Unmarshal(bytes, &msg)
 	if err != nil {
@@ -181,22 +184,11 @@ func (msg *TravisciOutMessage) AsMarkdown() string {
 }
 
 func (msg *TravisciOutMessage) ShortCommit() string {
-	if len(msg.Commit) < 8 {
-		return msg.Commit
-	}
-	return msg.Commit[0:7]
+	return util.ShortCommit(msg.Commit)
 }
 
 func (msg *TravisciOutMessage) DurationDisplay() string {
-	if msg.Duration == 0 {
-		return "0 sec"
-	}
-	dur, err := time.ParseDuration(fmt.Sprintf("%vs", msg.Duration))
-	if err != nil {
-		return "unknown"
-	}
-	modSeconds := math.Mod(float64(msg.Duration), float64(60))
-	return fmt.Sprintf("%v min %v sec", int(dur.Minutes()), modSeconds)
+	return util.DurationDisplay(int64(msg.Duration))
 }
 
 func (msg *TravisciOutMessage) PullRequestURL() string {
@@ -28,6 +28,21 @@ func NewHandler() handlers.Handler {
 	return handlers.Handler{MessageBodyType: MessageBodyType, Normalize: Normalize}
 }
 
+func init() {
+	handlers.Register(handlers.HandlerInfo{
+		Key:              HandlerKey,
+		DisplayName:      DisplayName,
+		MessageDirection: MessageDirection,
+		MessageBodyType:  MessageBodyType,
+		ExampleSlugs: []string{
+			"chat-meta_feedback", "chat-meta_forward", "chat-meta_rating",
+			"chat-meta_receive", "chat-meta_start", "chat-meta_survey",
+			"chat-widget_config", "offline-message_receive",
+			"operator_away", "operator_back", "operator_offline", "operator_online"},
+		NewHandler:     NewHandler,
+		ExampleMessage: ExampleMessage})
+}
+
 func Normalize(cfg config.Configuration, hReq handlers.HandlerRequest) (cc.Message, error) {
 	bodyBytes := hReq.Body
 	srcMsgBase, err := UserlikeBaseOutMessageFromBytes(bodyBytes)
@@ -1,10 +1,13 @@
 package wootric
 
 import (
+	"errors"
+
 	cc "github.com/grokify/commonchat"
 
 	"github.com/grokify/chathooks/pkg/config"
 	"github.com/grokify/chathooks/pkg/handlers"
+	"github.com/grokify/chathooks/pkg/models"
 	"github.com/grokify/chathooks/pkg/util"
 )
 
@@ -13,5 +16,9 @@ func ExampleMessage(cfg config.Configuration, data util.ExampleData, eventSlug s
 	if err != nil {
 		return cc.Message{}, err
 	}
-	return Normalize(cfg, handlers.HandlerRequest{Body: bytes})
+	body, info := models.DecodeBody(MessageBodyType, "", bytes)
+	if info != nil {
+		return cc.Message{}, errors.New(string(info.Body))
+	}
+	return Normalize(cfg, handlers.HandlerRequest{Body: body})
 }
@@ -36,6 +36,22 @@ func NewHandler() handlers.Handler {
 		Normalize:       Normalize}
 }
 
+func init() {
+	handlers.Register(handlers.HandlerInfo{
+		Key:                  HandlerKey,
+		DisplayName:          DisplayName,
+		MessageDirection:     MessageDirection,
+		MessageBodyType:      MessageBodyType,
+		ExampleFileExtension: "txt",
+		ExampleSlugs:         []string{"decline-created", "response-created"},
+		CustomParams: []handlers.CustomParam{
+			{Name: WootricQryVarFormatResponse, Description: "Response field format."},
+			{Name: WootricQryVarSkipEmptyText, Description: "Skip responses without text."},
+		},
+		NewHandler:     NewHandler,
+		ExampleMessage: ExampleMessage})
+}
+
 func Normalize(cfg config.Configuration, hReq handlers.HandlerRequest) (cc.Message, error) {
 	if hReq.QueryParams == nil {
 		hReq.QueryParams = url.Values{}
@@ -46,14 +62,10 @@ func Normalize(cfg config.Configuration, hReq handlers.HandlerRequest) (cc.Messa
 		ccMsg.IconURL = iconURL.String()
 	}
 
-	body, err := url.QueryUnescape(string(hReq.Body))
+	src, err := ParseJSON(hReq.Body)
 	if err != nil {
 		return ccMsg, errors.Wrap(err, "wootric.Normalize")
 	}
-	src, err := ParseQueryString(body)
-	if err != nil {
-		return ccMsg, err
-	}
 
 	ccMsg.Activity = src.Activity()
 	//	ccMsg.Title = src.Activity()
@@ -0,0 +1,50 @@
+package wootric
+
+import (
+	"testing"
+
+	"github.com/grokify/chathooks/pkg/config"
+	"github.com/grokify/chathooks/pkg/handlers"
+	"github.com/grokify/chathooks/pkg/models"
+)
+
+var NormalizeTests = []struct {
+	v         string
+	wantScore string
+	wantEmail string
+	wantErr   string
+}{
+	{"response[id]=1128&response[email]=nps%40example.com&response[score]=7&response[text]=okay&response[end_user_properties][pricing_plan]=Enterprise&response[created_at]=2016-08-04%2013%3A57%3A26%20-0700&event_name=created&timestamp=2016-08-04%2013%3A57%3A31%20-0700",
+		"7", "nps@example.com", ""},
+	{"response[email]=a%2Bb%40example.com&response[score]=10&response[text]=x%26y",
+		"10", "a+b@example.com", ""},
+	{"decline[id]=19&decline[email]=nps%40example.com&event_name=created",
+		"", "", "SKIP_WOOTRIC_NOT_RESPONSE_IS_DECLINE"}}
+
+func TestNormalize(t *testing.T) {
+	for _, tt := range NormalizeTests {
+		body, info := models.DecodeBody(MessageBodyType, "application/x-www-form-urlencoded", []byte(tt.v))
+		if info != nil {
+			t.Fatalf("models.DecodeBody(%v): want no error, got %v", tt.v, string(info.Body))
+		}
+		msg, err := Normalize(config.Configuration{}, handlers.HandlerRequest{Body: body})
+		if len(tt.wantErr) > 0 {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("wootric.Normalize(%v): want %v, got %v", tt.v, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("wootric.Normalize(%v): want no error, got %v", tt.v, err)
+		}
+		fields := map[string]string{}
+		for _, attachment := range msg.Attachments {
+			for _, field := range attachment.Fields {
+				fields[field.Title] = field.Value
+			}
+		}
+		if fields["NPS Score"] != tt.wantScore || fields["User email"] != tt.wantEmail {
+			t.Errorf("wootric.Normalize(%v): want %v/%v, got %v", tt.v, tt.wantScore, tt.wantEmail, fields)
+		}
+	}
+}
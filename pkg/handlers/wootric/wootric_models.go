@@ -50,48 +50,46 @@ func (we *WootricEvent) Activity() string {
 	return strings.Join(parts, " ")
 }
 
+// ParseQueryString parses a Rails-style form encoded event.
 func ParseQueryString(raw string) (WootricEvent, error) {
 	evt := WootricEvent{}
-	err := urlutil.UnmarshalRailsQS(raw, &evt)
-	if err != nil {
+	if err := urlutil.UnmarshalRailsQS(raw, &evt); err != nil {
 		return evt, err
 	}
-	if len(strings.TrimSpace(evt.Timestamp)) > 0 {
-		t1, err := time.Parse(timeutil.Ruby, strings.TrimSpace(evt.Timestamp))
-		if err != nil {
-			return evt, err
-		}
-		evt.TimestampTime = t1
-	}
-	if len(strings.TrimSpace(evt.Response.CreatedAt)) > 0 {
-		t2, err := time.Parse(timeutil.Ruby, strings.TrimSpace(evt.Response.CreatedAt))
-		if err != nil {
-			return evt, err
-		}
-		evt.Response.CreatedAtTime = t2
-	}
-	if len(strings.TrimSpace(evt.Response.UpdatedAt)) > 0 {
-		t3, err := time.Parse(timeutil.Ruby, strings.TrimSpace(evt.Response.UpdatedAt))
-		if err != nil {
-			return evt, err
-		}
-		evt.Response.UpdatedAtTime = t3
+	return evt, evt.parseTimes()
+}
+
+// ParseJSON parses an event decoded to JSON by the
+// `url_encoded_rails` body type.
+func ParseJSON(data []byte) (WootricEvent, error) {
+	evt := WootricEvent{}
+	if err := json.Unmarshal(data, &evt); err != nil {
+		return evt, err
 	}
-	if len(strings.TrimSpace(evt.Decline.CreatedAt)) > 0 {
-		t2, err := time.Parse(timeutil.Ruby, strings.TrimSpace(evt.Decline.CreatedAt))
-		if err != nil {
-			return evt, err
+	return evt, evt.parseTimes()
+}
+
+func (we *WootricEvent) parseTimes() error {
+	times := []struct {
+		raw    string
+		parsed *time.Time
+	}{
+		{we.Timestamp, &we.TimestampTime},
+		{we.Response.CreatedAt, &we.Response.CreatedAtTime},
+		{we.Response.UpdatedAt, &we.Response.UpdatedAtTime},
+		{we.Decline.CreatedAt, &we.Decline.CreatedAtTime},
+		{we.Decline.UpdatedAt, &we.Decline.UpdatedAtTime}}
+	for _, t := range times {
+		if len(strings.TrimSpace(t.raw)) == 0 {
+			continue
 		}
-		evt.Decline.CreatedAtTime = t2
-	}
-	if len(strings.TrimSpace(evt.Decline.UpdatedAt)) > 0 {
-		t3, err := time.Parse(timeutil.Ruby, strings.TrimSpace(evt.Decline.UpdatedAt))
+		parsed, err := time.Parse(timeutil.Ruby, strings.TrimSpace(t.raw))
 		if err != nil {
-			return evt, err
+			return err
 		}
-		evt.Decline.UpdatedAtTime = t3
+		*t.parsed = parsed
 	}
-	return evt, err
+	return nil
 }
 
 type WootricResponse struct {
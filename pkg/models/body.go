@@ -0,0 +1,155 @@
+package models
+
+import (
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultMaxBodySize is the request body limit when neither the route
+// nor the configuration sets one.
+const DefaultMaxBodySize int64 = 1 << 20
+
+const (
+	contentTypeForm = "application/x-www-form-urlencoded"
+	formPayloadKey  = "payload"
+)
+
+// CheckContentType returns a 415 `ErrorInfo` if `contentType` does not
+// match `bodyType`. JSON bodies may be sent as `application/json`,
+// `text/json`, `+json` types or `text/plain`; form bodies as
+// `application/x-www-form-urlencoded`. Raw bodies may have any type.
+// An empty content type is accepted, as some senders do not set one.
+func CheckContentType(bodyType MessageBodyType, contentType string) *ErrorInfo {
+	if bodyType == Raw || len(strings.TrimSpace(contentType)) == 0 {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		switch bodyType {
+		case JSON:
+			if isJSONMediaType(mediaType) || mediaType == "text/plain" {
+				return nil
+			}
+		case URLEncoded, URLEncodedJSONPayload, URLEncodedRails:
+			if mediaType == contentTypeForm {
+				return nil
+			}
+		case URLEncodedJSONPayloadOrJSON:
+			if isJSONMediaType(mediaType) || mediaType == contentTypeForm {
+				return nil
+			}
+		}
+	}
+	return &ErrorInfo{
+		StatusCode: http.StatusUnsupportedMediaType,
+		Body:       []byte("E_CONTENT_TYPE_NOT_SUPPORTED [" + contentType + "]")}
+}
+
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" ||
+		mediaType == "text/json" ||
+		strings.HasSuffix(mediaType, "+json")
+}
+
+// DecodeBody returns the message in a raw request body. The `payload`
+// form field is extracted for `url_encoded_json_payload` bodies and
+// form-encoded `url_encoded_or_json` bodies, and `url_encoded_rails`
+// bodies are converted to JSON. Other bodies are returned as is.
+func DecodeBody(bodyType MessageBodyType, contentType string, raw []byte) ([]byte, *ErrorInfo) {
+	if info := CheckContentType(bodyType, contentType); info != nil {
+		return nil, info
+	}
+	switch bodyType {
+	case URLEncodedJSONPayloadOrJSON:
+		if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && isJSONMediaType(mediaType) {
+			return raw, nil
+		}
+		return decodeFormPayload(raw)
+	case URLEncodedJSONPayload:
+		return decodeFormPayload(raw)
+	case URLEncodedRails:
+		bytes, err := DecodeRailsForm(raw)
+		if err != nil {
+			return nil, &ErrorInfo{
+				StatusCode: http.StatusBadRequest,
+				Body:       []byte("E_BODY_FORM_NOT_VALID [" + err.Error() + "]")}
+		}
+		return bytes, nil
+	}
+	return raw, nil
+}
+
+func decodeFormPayload(raw []byte) ([]byte, *ErrorInfo) {
+	v, err := url.ParseQuery(string(raw))
+	if err != nil {
+		return nil, &ErrorInfo{
+			StatusCode: http.StatusBadRequest,
+			Body:       []byte("E_BODY_FORM_NOT_VALID [" + err.Error() + "]")}
+	}
+	if _, ok := v[formPayloadKey]; !ok {
+		return nil, &ErrorInfo{
+			StatusCode: http.StatusBadRequest,
+			Body:       []byte("E_BODY_PAYLOAD_MISSING [" + formPayloadKey + "]")}
+	}
+	return []byte(v.Get(formPayloadKey)), nil
+}
+
+// bodyLimit returns `limit`, or `DefaultMaxBodySize` if it is not set.
+func bodyLimit(limit int64) int64 {
+	if limit <= 0 {
+		return DefaultMaxBodySize
+	}
+	return limit
+}
+
+func bodyTooLarge(limit int64) *ErrorInfo {
+	return &ErrorInfo{
+		StatusCode: http.StatusRequestEntityTooLarge,
+		Body:       []byte("E_BODY_TOO_LARGE [" + strconv.FormatInt(limit, 10) + "]")}
+}
+
+// checkBodySize returns a 413 `ErrorInfo` if `size` exceeds `limit`.
+func checkBodySize(size int, limit int64) *ErrorInfo {
+	if limit = bodyLimit(limit); int64(size) > limit {
+		return bodyTooLarge(limit)
+	}
+	return nil
+}
+
+// readBody reads at most `limit` bytes from `r`.
+func readBody(r io.Reader, contentLength, limit int64) ([]byte, *ErrorInfo) {
+	limit = bodyLimit(limit)
+	if contentLength > limit {
+		return nil, bodyTooLarge(limit)
+	}
+	if r == nil {
+		return []byte{}, nil
+	}
+	bytes, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, &ErrorInfo{
+			StatusCode: http.StatusBadRequest,
+			Body:       []byte("E_BODY_READ [" + err.Error() + "]")}
+	}
+	if int64(len(bytes)) > limit {
+		return nil, bodyTooLarge(limit)
+	}
+	return bytes, nil
+}
+
+// readBodyFastHTTP returns the request body. fasthttp has already read
+// it, bounded by the server's `MaxRequestBodySize`.
+func readBodyFastHTTP(req *fasthttp.Request, limit int64) ([]byte, *ErrorInfo) {
+	body := req.Body()
+	if info := checkBodySize(len(body), limit); info != nil {
+		return nil, info
+	}
+	return body, nil
+}
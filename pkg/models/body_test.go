@@ -0,0 +1,74 @@
+package models
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+var decodeBodyTests = []struct {
+	bodyType    MessageBodyType
+	contentType string
+	raw         string
+	want        string
+	wantCode    int
+}{
+	{JSON, "application/json", `{"a":1}`, `{"a":1}`, 0},
+	{JSON, "application/json; charset=utf-8", `{"a":1}`, `{"a":1}`, 0},
+	{JSON, "application/vnd.api+json", `{"a":1}`, `{"a":1}`, 0},
+	{JSON, "", `{"a":1}`, `{"a":1}`, 0},
+	{JSON, "application/x-www-form-urlencoded", "a=1", "", http.StatusUnsupportedMediaType},
+	{JSON, "not a type", `{"a":1}`, "", http.StatusUnsupportedMediaType},
+	{URLEncoded, "application/x-www-form-urlencoded", "a=1", "a=1", 0},
+	{URLEncoded, "application/json", `{"a":1}`, "", http.StatusUnsupportedMediaType},
+	{URLEncodedJSONPayload, "application/x-www-form-urlencoded", `payload=%7B%22a%22%3A1%7D`, `{"a":1}`, 0},
+	{URLEncodedJSONPayload, "application/x-www-form-urlencoded", "a=1", "", http.StatusBadRequest},
+	{URLEncodedJSONPayload, "application/x-www-form-urlencoded", "payload=%zz", "", http.StatusBadRequest},
+	{URLEncodedJSONPayloadOrJSON, "application/json", `{"a":1}`, `{"a":1}`, 0},
+	{URLEncodedJSONPayloadOrJSON, "application/x-www-form-urlencoded", `payload=%7B%22a%22%3A1%7D`, `{"a":1}`, 0},
+	{URLEncodedJSONPayloadOrJSON, "text/html", `{"a":1}`, "", http.StatusUnsupportedMediaType}}
+
+func TestDecodeBody(t *testing.T) {
+	for _, tt := range decodeBodyTests {
+		got, info := DecodeBody(tt.bodyType, tt.contentType, []byte(tt.raw))
+		gotCode := 0
+		if info != nil {
+			gotCode = info.StatusCode
+		}
+		if string(got) != tt.want || gotCode != tt.wantCode {
+			t.Errorf("DecodeBody(%v, %v, %v): want %v/%v, got %v/%v",
+				tt.bodyType, tt.contentType, tt.raw, tt.want, tt.wantCode, string(got), gotCode)
+		}
+	}
+}
+
+var readBodyTests = []struct {
+	body          string
+	contentLength int64
+	limit         int64
+	wantCode      int
+}{
+	{"12345678", -1, 8, 0},
+	{"123456789", -1, 8, http.StatusRequestEntityTooLarge},
+	{"1234", 16, 8, http.StatusRequestEntityTooLarge},
+	{"1234", -1, 0, 0}}
+
+func TestReadBody(t *testing.T) {
+	for _, tt := range readBodyTests {
+		req, err := http.NewRequest(http.MethodPost, "/hook", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatalf("http.NewRequest(): want no error, got %v", err)
+		}
+		req.ContentLength = tt.contentLength
+		got, info := NewRequestNetHTTP(req).Message(JSON, tt.limit)
+		gotCode := 0
+		if info != nil {
+			gotCode = info.StatusCode
+		} else if string(got) != tt.body {
+			t.Errorf("Request.Message(%v, %v): want %v, got %v", tt.body, tt.limit, tt.body, string(got))
+		}
+		if gotCode != tt.wantCode {
+			t.Errorf("Request.Message(%v, %v): want %v, got %v", tt.body, tt.limit, tt.wantCode, gotCode)
+		}
+	}
+}
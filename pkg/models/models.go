@@ -1,9 +1,9 @@
 package models
 
 import (
-	"encoding/base64"
+	"context"
 	"encoding/json"
-	"io/ioutil"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
@@ -11,11 +11,9 @@ import (
 	"github.com/aws/aws-lambda-go/events"
 	cc "github.com/grokify/commonchat"
 	"github.com/grokify/simplego/net/anyhttp"
-	fhu "github.com/grokify/simplego/net/fasthttputil"
-	nhu "github.com/grokify/simplego/net/nethttputil"
-	"github.com/grokify/simplego/type/stringsutil"
-	"github.com/rs/zerolog/log"
 	"github.com/valyala/fasthttp"
+
+	"github.com/grokify/chathooks/pkg/redact"
 )
 
 const (
@@ -24,6 +22,23 @@ const (
 	QueryParamOutputType     = "outputType"
 	QueryParamToken          = "token"
 	QueryParamOutputURL      = "url"
+	QueryParamRoute          = "route"
+
+	// StatusFiltered is the response status for events that were
+	// dropped by a filter or skipped by a handler.
+	StatusFiltered = "filtered"
+
+	// StatusDeduplicated is the response status for repeated events
+	// suppressed by a route's dedup window.
+	StatusDeduplicated = "deduplicated"
+
+	// StatusDigested is the response status for events held for a
+	// route's digest.
+	StatusDigested = "digested"
+
+	// StatusRateLimited is the response status for events suppressed
+	// by a rate limit. They are counted in an overflow summary.
+	StatusRateLimited = "rate_limited"
 )
 
 var FixedParams = map[string]int{
@@ -31,7 +46,8 @@ var FixedParams = map[string]int{
 	QueryParamInputType:      2,
 	QueryParamOutputType:     3,
 	QueryParamToken:          4,
-	QueryParamOutputURL:      5}
+	QueryParamOutputURL:      5,
+	QueryParamRoute:          6}
 
 type RequestParams struct {
 	InputType  string `url:"inputType"`
@@ -48,6 +64,7 @@ const (
 	URLEncodedJSONPayload
 	URLEncodedJSONPayloadOrJSON
 	URLEncodedRails
+	Raw
 )
 
 var intervals = [...]string{
@@ -55,247 +72,94 @@ var intervals = [...]string{
 	"url_encoded",
 	"url_encoded_json_payload",
 	"url_encoded_or_json",
+	"url_encoded_rails",
+	"raw",
 }
 
-type HookData struct {
-	InputType         string     `json:"inputType,omitempty"`
-	InputBody         []byte     `json:"inputBody,omitempty"`
-	OutputType        string     `json:"outputType,omitempty"`
-	OutputURL         string     `json:"outputUrl,omitempty"`
-	OutputNames       []string   `json:"outputNames,omitempty"`
-	Token             string     `json:"token,omitempty"`
-	InputMessage      []byte     `json:"inputMessage,omitempty"`
-	CustomQueryParams url.Values `json:"customParams,omitempty"`
-	CanonicalMessage  cc.Message `json:"canonicalMessage,omitempty"`
-}
-
-type hookDataRequest struct {
-	BodyType              MessageBodyType
-	Headers               map[string]string
-	QueryStringParameters map[string]string
-	Body                  string
-	IsBase64Encoded       bool
-}
-
-// HookDataFromAwsLambdaEvent converts a Lambda event to
-// generic HookData.
-func HookDataFromAwsLambdaEvent(bodyType MessageBodyType, awsReq events.APIGatewayProxyRequest, messageBodyType MessageBodyType) HookData {
-	hookData := newHookDataGeneric(hookDataRequest{
-		BodyType:              bodyType,
-		Headers:               awsReq.Headers,
-		Body:                  awsReq.Body,
-		IsBase64Encoded:       awsReq.IsBase64Encoded,
-		QueryStringParameters: awsReq.QueryStringParameters})
-	// `application/x-www-form-urlencoded` is currently not supported
-	// with AWS Lambda because Lambda cannot support URL Query String
-	// parameterss with this Content-Type.
-	if messageBodyType == URLEncoded ||
-		messageBodyType == URLEncodedJSONPayload ||
-		messageBodyType == URLEncodedRails {
-		jsonData := awsJsonWrapper{}
-		err := json.Unmarshal(hookData.InputMessage, &jsonData)
-		if err == nil {
-			hookData.InputMessage = []byte(jsonData.Body)
-		}
+func (bodyType MessageBodyType) String() string {
+	if int(bodyType) >= 0 && int(bodyType) < len(intervals) {
+		return intervals[bodyType]
 	}
-	return hookData
-}
-
-type awsJsonWrapper struct {
-	Body string `json:"body,omitempty"`
+	return fmt.Sprintf("MessageBodyType(%d)", int(bodyType))
 }
 
-/*
-func HookDataFromEawsyLambdaEvent(bodyType MessageBodyType, eawsyReq *apigatewayproxyevt.Event) HookData {
-	return newHookDataGeneric(hookDataRequest{
-		BodyType:              bodyType,
-		Headers:               eawsyReq.Headers,
-		Body:                  eawsyReq.Body,
-		IsBase64Encoded:       eawsyReq.IsBase64Encoded,
-		QueryStringParameters: eawsyReq.QueryStringParameters})
-}*/
-
-func newHookDataGeneric(req hookDataRequest) HookData {
-	data := newHookDataForQueryString(req.QueryStringParameters)
-	data.InputBody = bodyToMessageBytesGeneric(
-		req.BodyType,
-		req.Headers,
-		req.Body,
-		req.IsBase64Encoded)
-	return data
+func (bodyType MessageBodyType) MarshalText() ([]byte, error) {
+	return []byte(bodyType.String()), nil
 }
 
-func GetMapString2Simple(mapSS map[string]string, key string) string {
-	if value, ok := mapSS[key]; ok {
-		return value
+func (bodyType *MessageBodyType) UnmarshalText(text []byte) error {
+	parsed, err := ParseMessageBodyType(string(text))
+	if err == nil {
+		*bodyType = parsed
 	}
-	return ""
+	return err
 }
 
-func newHookDataForQueryString(queryStringParameters map[string]string) HookData {
-	data := HookData{
-		CustomQueryParams: url.Values{}}
-	if input, ok := queryStringParameters[QueryParamInputType]; ok {
-		data.InputType = strings.TrimSpace(input)
-	}
-	if output, ok := queryStringParameters[QueryParamOutputType]; ok {
-		data.OutputType = strings.TrimSpace(output)
-	}
-	if url, ok := queryStringParameters[QueryParamOutputURL]; ok {
-		data.OutputURL = strings.TrimSpace(url)
+// ParseMessageBodyType returns the type for a name such as `url_encoded`.
+// An empty name is `json`.
+func ParseMessageBodyType(name string) (MessageBodyType, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) == 0 {
+		return JSON, nil
 	}
-	if token, ok := queryStringParameters[QueryParamToken]; ok {
-		data.Token = strings.TrimSpace(token)
-	}
-	if namedOutputs, ok := queryStringParameters[QueryParamOutputAdapters]; ok {
-		data.OutputNames = stringsutil.SliceCondenseSpace(strings.Split(namedOutputs, ","), true, false)
-	}
-	// Include any other parameter as a custom param.
-	for key, val := range queryStringParameters {
-		if _, ok := FixedParams[key]; !ok {
-			data.CustomQueryParams.Add(strings.ToLower(strings.TrimSpace(key)), val)
-			//data.CustomParams[strings.ToLower(strings.TrimSpace(key))] = val
+	for i, try := range intervals {
+		if try == name {
+			return MessageBodyType(i), nil
 		}
 	}
-	return data
-}
-
-func HookDataFromAnyHTTPReq(bodyType MessageBodyType, aReq anyhttp.Request) HookData {
-	return HookData{
-		InputType:         aReq.QueryArgs().GetString(QueryParamInputType),
-		InputBody:         BodyToMessageBytesAnyHTTP(bodyType, aReq),
-		OutputType:        aReq.QueryArgs().GetString(QueryParamOutputType),
-		OutputURL:         aReq.QueryArgs().GetString(QueryParamOutputURL),
-		Token:             aReq.QueryArgs().GetString(QueryParamToken),
-		CustomQueryParams: aReq.QueryArgs().GetURLValues(),
-		OutputNames:       strings.Split(aReq.QueryArgs().GetString(QueryParamOutputAdapters), ",")}
+	return JSON, fmt.Errorf("E_MESSAGE_BODY_TYPE_NOT_SUPPORTED [%s]", name)
 }
 
-func HookDataFromNetHTTPReq(bodyType MessageBodyType, req *http.Request) HookData {
-	return HookData{
-		InputType:   nhu.GetReqQueryParam(req, QueryParamInputType),
-		InputBody:   BodyToMessageBytesNetHTTP(bodyType, req),
-		OutputType:  nhu.GetReqQueryParam(req, QueryParamOutputType),
-		OutputURL:   nhu.GetReqQueryParam(req, QueryParamOutputURL),
-		Token:       nhu.GetReqQueryParam(req, QueryParamToken),
-		OutputNames: nhu.GetSplitReqQueryParam(req, QueryParamOutputAdapters, ",")}
+// Output is a delivery destination: an adapter type with a webhook
+// URL, e.g. `glip`, or a channel for API adapters, e.g. `slackbot`,
+// or the name of a pre-registered adapter. `thread` selects `reply`
+// or `update` for correlated events on adapters that support it.
+type Output struct {
+	Type    string `json:"type,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Channel string `json:"channel,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Thread  string `json:"thread,omitempty"`
 }
 
-func HookDataFromFastHTTPReqCtx(bodyType MessageBodyType, ctx *fasthttp.RequestCtx) HookData {
-	return HookData{
-		InputType:   fhu.GetReqQueryParam(ctx, QueryParamInputType),
-		InputBody:   BodyToMessageBytesFastHTTP(bodyType, ctx),
-		OutputType:  fhu.GetReqQueryParam(ctx, QueryParamOutputType),
-		OutputURL:   fhu.GetReqQueryParam(ctx, QueryParamOutputURL),
-		Token:       fhu.GetReqQueryParam(ctx, QueryParamToken),
-		OutputNames: fhu.GetSplitReqQueryParam(ctx, QueryParamOutputAdapters, ",'")}
+type HookData struct {
+	InputType         string      `json:"inputType,omitempty"`
+	InputBody         []byte      `json:"inputBody,omitempty"`
+	OutputType        string      `json:"outputType,omitempty"`
+	OutputURL         string      `json:"outputUrl,omitempty"`
+	OutputNames       []string    `json:"outputNames,omitempty"`
+	Token             string      `json:"token,omitempty"`
+	InputMessage      []byte      `json:"inputMessage,omitempty"`
+	CustomQueryParams url.Values  `json:"customParams,omitempty"`
+	Route             string      `json:"route,omitempty"`
+	Outputs           []Output    `json:"outputs,omitempty"`
+	Severity          string      `json:"severity,omitempty"`
+	Fingerprint       string      `json:"fingerprint,omitempty"`
+	CorrelationID     string      `json:"correlationId,omitempty"`
+	Tenant            string      `json:"tenant,omitempty"`
+	InputHeaders      http.Header `json:"-"`
+	CanonicalMessage  cc.Message  `json:"canonicalMessage,omitempty"`
+	// Context is the trace context of the request, if any.
+	Context context.Context `json:"-"`
 }
 
-func bodyToMessageBytesGeneric(bodyType MessageBodyType, headers map[string]string, body string, isBase64Encoded bool) []byte {
-	bodyConverted := []byte("")
-	if isBase64Encoded {
-		decoded, err := base64.StdEncoding.DecodeString(body)
-		if err != nil {
-			return []byte("")
-		}
-		body = string(decoded)
-	}
-	switch bodyType {
-	case URLEncodedJSONPayload:
-		v, err := url.ParseQuery(body)
-		if err != nil {
-			return []byte("")
-		}
-		bodyConverted = []byte(v.Get("payload"))
-	case URLEncodedJSONPayloadOrJSON:
-		if ct, ok := headers["content-type"]; ok {
-			ct = strings.TrimSpace(strings.ToLower(ct))
-			if strings.Index(ct, `application/json`) > -1 {
-				return []byte(body)
-			}
-		}
-		v, err := url.ParseQuery(body)
-		if err != nil {
-			return []byte("")
-		}
-		bodyConverted = []byte(v.Get("payload"))
-	default:
-		bodyConverted = []byte(body)
+func GetMapString2Simple(mapSS map[string]string, key string) string {
+	if value, ok := mapSS[key]; ok {
+		return value
 	}
-	log.Debug().
-		Str("body", string(bodyConverted)).
-		Msg("REQUEST_BODY")
-	return bodyConverted
+	return ""
 }
 
-func BodyToMessageBytesAnyHTTP(bodyType MessageBodyType, aReq anyhttp.Request) []byte {
-	switch bodyType {
-	case URLEncodedJSONPayload:
-		if err := aReq.ParseForm(); err != nil {
-			return []byte("")
-		}
-		return aReq.PostArgs().GetBytes("payload")
-	case URLEncodedJSONPayloadOrJSON:
-		ct := strings.TrimSpace(strings.ToLower(aReq.HeaderString("Content-Type")))
-		if strings.Index(ct, `application/json`) > -1 {
-			bytes, err := aReq.PostBody()
-			if err != nil {
-				return []byte("")
-			}
-			return bytes
-		}
-		if err := aReq.ParseForm(); err != nil {
-			return []byte("")
-		}
-		return aReq.PostArgs().GetBytes("payload")
-		//return []byte(req.Form.Get("payload"))
-	default:
-		bytes, err := aReq.PostBody()
-		if err != nil {
-			return []byte("")
-		}
-		return bytes
-	}
+func HookDataFromAnyHTTPReq(bodyType MessageBodyType, aReq anyhttp.Request, limit int64) (HookData, *ErrorInfo) {
+	return HookDataFromRequest(bodyType, NewRequestAnyHTTP(aReq), limit)
 }
 
-func BodyToMessageBytesNetHTTP(bodyType MessageBodyType, req *http.Request) []byte {
-	switch bodyType {
-	case URLEncodedJSONPayload:
-		return []byte(req.Form.Get("payload"))
-	case URLEncodedJSONPayloadOrJSON:
-		ct := strings.TrimSpace(strings.ToLower(req.Header.Get("Content-Type")))
-		if strings.Index(ct, `application/json`) > -1 {
-			bytes, err := ioutil.ReadAll(req.Body)
-			if err != nil {
-				return []byte("")
-			}
-			return bytes
-		}
-		return []byte(req.Form.Get("payload"))
-	default:
-		bytes, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			return []byte("")
-		}
-		return bytes
-	}
+func HookDataFromNetHTTPReq(bodyType MessageBodyType, req *http.Request, limit int64) (HookData, *ErrorInfo) {
+	return HookDataFromRequest(bodyType, NewRequestNetHTTP(req), limit)
 }
 
-func BodyToMessageBytesFastHTTP(bodyType MessageBodyType, ctx *fasthttp.RequestCtx) []byte {
-	switch bodyType {
-	case URLEncodedJSONPayload:
-		return ctx.FormValue("payload")
-	case URLEncodedJSONPayloadOrJSON:
-		ct := strings.TrimSpace(
-			strings.ToLower(
-				string(ctx.Request.Header.Peek("Content-Type"))))
-		if strings.Index(ct, `application/json`) > -1 {
-			return ctx.PostBody()
-		}
-		return ctx.FormValue("payload")
-	default:
-		return ctx.PostBody()
-	}
+func HookDataFromFastHTTPReqCtx(bodyType MessageBodyType, ctx *fasthttp.RequestCtx, limit int64) (HookData, *ErrorInfo) {
+	return HookDataFromRequest(bodyType, NewRequestFastHTTP(ctx), limit)
 }
 
 type AwsAPIGatewayProxyOutput struct {
@@ -308,12 +172,20 @@ type AwsAPIGatewayProxyOutput struct {
 type ErrorInfo struct {
 	StatusCode int
 	Body       []byte
+	Status     string `json:",omitempty"`
+}
+
+// NewStatusInfo returns a 200 response for an event that was
+// handled without delivery, e.g. `StatusFiltered`.
+func NewStatusInfo(status string, body []byte) ErrorInfo {
+	return ErrorInfo{StatusCode: http.StatusOK, Status: status, Body: body}
 }
 
 type ResponseInfo struct {
 	HookData   HookData    `json:"hookData,omitempty"`
 	Responses  []ErrorInfo `json:"responses,omitempty"`
 	StatusCode int         `json:"statusCode,omitempty"`
+	Status     string      `json:"status,omitempty"`
 	//URL        string      `json:"url,omitempty"`
 	//Body       interface{} `json:"body,omitempty"`
 	//InputType  string      `json:"inputType,omitempty"`
@@ -384,10 +256,58 @@ func ErrorsInfoToResponseInfoOld(errs ...ErrorInfo) ErrorInfo {
 	return resInfo
 }
 
+// Redact returns the parts of `hookData` that are safe to echo. Tokens
+// and output URLs are masked. With `verbose`, the input and canonical
+// message are included with the `redactor` fields redacted.
+func (hookData HookData) Redact(redactor *redact.Redactor, verbose bool) HookData {
+	redacted := HookData{
+		InputType:     hookData.InputType,
+		OutputType:    hookData.OutputType,
+		OutputURL:     redact.Target(hookData.OutputURL),
+		OutputNames:   hookData.OutputNames,
+		Token:         redact.Token(hookData.Token),
+		Route:         hookData.Route,
+		Severity:      hookData.Severity,
+		Fingerprint:   hookData.Fingerprint,
+		CorrelationID: hookData.CorrelationID,
+		Tenant:        hookData.Tenant}
+	for _, output := range hookData.Outputs {
+		output.URL = redact.Target(output.URL)
+		redacted.Outputs = append(redacted.Outputs, output)
+	}
+	if verbose {
+		redacted.InputBody = redactor.JSON(hookData.InputBody)
+		redacted.InputMessage = redactor.JSON(hookData.InputMessage)
+		redacted.CustomQueryParams = redactor.Values(hookData.CustomQueryParams)
+		redacted.CanonicalMessage = hookData.CanonicalMessage
+	}
+	return redacted
+}
+
+// BuildAwsAPIGatewayProxyResponse builds the response for a request.
+// The hook data is redacted and does not include the input.
 func BuildAwsAPIGatewayProxyResponse(hookData HookData, errs ...ErrorInfo) (events.APIGatewayProxyResponse, error) {
+	return buildResponse(hookData.Redact(nil, false), errs...)
+}
+
+// BuildVerboseAwsAPIGatewayProxyResponse builds the response for a
+// route with `verboseResponse`, including the redacted input.
+func BuildVerboseAwsAPIGatewayProxyResponse(hookData HookData, redactor *redact.Redactor, errs ...ErrorInfo) (events.APIGatewayProxyResponse, error) {
+	return buildResponse(hookData.Redact(redactor, true), errs...)
+}
+
+func buildResponse(hookData HookData, errs ...ErrorInfo) (events.APIGatewayProxyResponse, error) {
 	resInfo := ResponseInfo{
 		HookData:   hookData,
 		Responses:  errs,
 		StatusCode: GetMaxStatusCode(errs...)}
+	if resInfo.StatusCode < 300 {
+		for _, errInfo := range errs {
+			if len(errInfo.Status) > 0 {
+				resInfo.Status = errInfo.Status
+				break
+			}
+		}
+	}
 	return resInfo.ToAPIGatewayProxyResponse()
 }
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DecodeRailsForm converts a Rails-style nested form, e.g.
+// `response[score]=9&response[end_user][email]=a@b.c&tags[]=x`, to a
+// JSON object. `[]` appends to an array, and `a[][b]` starts a new
+// array element when the last element already has `b`, as in Rack.
+// All values are strings. Keys used both as a value and as a nested
+// form are an error.
+func DecodeRailsForm(raw []byte) ([]byte, error) {
+	params := map[string]interface{}{}
+	for _, pair := range strings.Split(string(raw), "&") {
+		if len(pair) == 0 {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		key, err := url.QueryUnescape(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		value := ""
+		if len(parts) == 2 {
+			if value, err = url.QueryUnescape(parts[1]); err != nil {
+				return nil, err
+			}
+		}
+		if len(key) == 0 {
+			continue
+		}
+		segments := railsKeySegments(key)
+		if err := setRailsParam(params, segments[0], segments[1:], value); err != nil {
+			return nil, fmt.Errorf("%s [%s]", err.Error(), key)
+		}
+	}
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(params); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// railsKeySegments splits `a[b][]` into `a`, `b` and an empty segment.
+// Keys that are not well formed are used as is.
+func railsKeySegments(key string) []string {
+	i := strings.Index(key, "[")
+	if i <= 0 {
+		return []string{key}
+	}
+	segments := []string{key[:i]}
+	rest := key[i:]
+	for len(rest) > 0 {
+		j := strings.Index(rest, "]")
+		if rest[0] != '[' || j < 0 {
+			return []string{key}
+		}
+		segments = append(segments, rest[1:j])
+		rest = rest[j+1:]
+	}
+	return segments
+}
+
+func setRailsParam(params map[string]interface{}, name string, rest []string, value string) error {
+	existing, exists := params[name]
+	if len(rest) == 0 {
+		if _, ok := existing.(string); exists && !ok {
+			return fmt.Errorf("E_RAILS_PARAM_CONFLICT")
+		}
+		params[name] = value
+		return nil
+	}
+	if len(rest[0]) > 0 {
+		child, ok := existing.(map[string]interface{})
+		if !exists {
+			child = map[string]interface{}{}
+			params[name] = child
+		} else if !ok {
+			return fmt.Errorf("E_RAILS_PARAM_CONFLICT")
+		}
+		return setRailsParam(child, rest[0], rest[1:], value)
+	}
+	list, ok := existing.([]interface{})
+	if exists && !ok {
+		return fmt.Errorf("E_RAILS_PARAM_CONFLICT")
+	}
+	if len(rest) == 1 {
+		params[name] = append(list, value)
+		return nil
+	}
+	childRest := rest[1:]
+	if len(childRest[0]) == 0 {
+		return fmt.Errorf("E_RAILS_PARAM_NOT_SUPPORTED")
+	}
+	if len(list) > 0 {
+		last, ok := list[len(list)-1].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("E_RAILS_PARAM_CONFLICT")
+		} else if !hasRailsParam(last, childRest) {
+			return setRailsParam(last, childRest[0], childRest[1:], value)
+		}
+	}
+	child := map[string]interface{}{}
+	params[name] = append(list, child)
+	return setRailsParam(child, childRest[0], childRest[1:], value)
+}
+
+// hasRailsParam returns whether the nested key `segments` is set.
+func hasRailsParam(params map[string]interface{}, segments []string) bool {
+	for i, segment := range segments {
+		if len(segment) == 0 {
+			return false
+		}
+		value, ok := params[segment]
+		if !ok {
+			return false
+		}
+		if i == len(segments)-1 {
+			return true
+		}
+		if params, ok = value.(map[string]interface{}); !ok {
+			return true
+		}
+	}
+	return false
+}
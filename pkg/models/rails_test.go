@@ -0,0 +1,69 @@
+package models
+
+import (
+	"net/http"
+	"testing"
+)
+
+var decodeRailsFormTests = []struct {
+	v       string
+	want    string
+	wantErr bool
+}{
+	{"a=1&b=2", `{"a":"1","b":"2"}`, false},
+	{"response[score]=9&response[end_user][email]=a%40b.c", `{"response":{"end_user":{"email":"a@b.c"},"score":"9"}}`, false},
+	{"tags[]=x&tags[]=y", `{"tags":["x","y"]}`, false},
+	{"items[][id]=1&items[][name]=a&items[][id]=2", `{"items":[{"id":"1","name":"a"},{"id":"2"}]}`, false},
+	{"a[b]=1&a[b]=2", `{"a":{"b":"2"}}`, false},
+	{"text=x+y%26z&empty=&flag", `{"empty":"","flag":"","text":"x y&z"}`, false},
+	{"a[b=1&c]=2", `{"a[b":"1","c]":"2"}`, false},
+	{"", `{}`, false},
+	{"a=1&a[b]=2", "", true},
+	{"a[b]=1&a=2", "", true},
+	{"a[]=1&a[b]=2", "", true},
+	{"a[]=1&a[][b]=2", "", true},
+	{"a=%zz", "", true}}
+
+func TestDecodeRailsForm(t *testing.T) {
+	for _, tt := range decodeRailsFormTests {
+		got, err := DecodeRailsForm([]byte(tt.v))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("DecodeRailsForm(%v): want error, got %v", tt.v, string(got))
+			}
+			continue
+		}
+		if err != nil || string(got) != tt.want {
+			t.Errorf("DecodeRailsForm(%v): want %v, got %v (%v)", tt.v, tt.want, string(got), err)
+		}
+	}
+}
+
+// railsEngineTests are run through each engine's body decoding.
+var railsEngineTests = []struct {
+	contentType string
+	body        string
+	want        string
+	wantCode    int
+}{
+	{"application/x-www-form-urlencoded", "response[score]=9&response[end_user_properties][plan]=Pro",
+		`{"response":{"end_user_properties":{"plan":"Pro"},"score":"9"}}`, 0},
+	{"", "decline[email]=nps%40example.com&event_name=created",
+		`{"decline":{"email":"nps@example.com"},"event_name":"created"}`, 0},
+	{"application/x-www-form-urlencoded", "a=1&a[b]=2", "", http.StatusBadRequest},
+	{"application/json", `{"a":1}`, "", http.StatusUnsupportedMediaType}}
+
+func TestRailsEngines(t *testing.T) {
+	for _, tt := range railsEngineTests {
+		for _, engine := range testEngines {
+			got, info := engine.newRequest(testRequest{"/hook", tt.contentType, tt.body}).Message(URLEncodedRails, 0)
+			gotCode := 0
+			if info != nil {
+				gotCode = info.StatusCode
+			}
+			if string(got) != tt.want || gotCode != tt.wantCode {
+				t.Errorf("Request.Message(%v, %v): want %v/%v, got %v/%v", engine.name, tt.body, tt.want, tt.wantCode, string(got), gotCode)
+			}
+		}
+	}
+}
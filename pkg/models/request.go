@@ -0,0 +1,151 @@
+package models
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/grokify/simplego/net/anyhttp"
+	"github.com/grokify/simplego/type/stringsutil"
+	"github.com/valyala/fasthttp"
+)
+
+// Request is an incoming webhook request independent of the engine
+// that received it. The `NewRequest*` functions map engine requests to
+// it. The body is read by `Message`, so its size can be limited by
+// the request's route. `Context` carries the request's trace context.
+type Request struct {
+	Context  context.Context
+	Method   string
+	Path     string
+	RemoteIP string
+	Query    url.Values
+	Headers  http.Header
+	Body     []byte
+	readBody func(limit int64) ([]byte, *ErrorInfo)
+}
+
+// NewRequestNetHTTP maps a `net/http` request.
+func NewRequestNetHTTP(req *http.Request) *Request {
+	remoteIP, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		remoteIP = req.RemoteAddr
+	}
+	return &Request{
+		Context:  req.Context(),
+		Method:   req.Method,
+		Path:     req.URL.Path,
+		RemoteIP: remoteIP,
+		Query:    req.URL.Query(),
+		Headers:  req.Header.Clone(),
+		readBody: func(limit int64) ([]byte, *ErrorInfo) {
+			return readBody(req.Body, req.ContentLength, limit)
+		}}
+}
+
+// NewRequestFastHTTP maps a `fasthttp` request.
+func NewRequestFastHTTP(ctx *fasthttp.RequestCtx) *Request {
+	query := url.Values{}
+	ctx.QueryArgs().VisitAll(func(key, val []byte) {
+		query.Add(string(key), string(val))
+	})
+	headers := http.Header{}
+	ctx.Request.Header.VisitAll(func(key, val []byte) {
+		headers.Add(string(key), string(val))
+	})
+	return &Request{
+		Method:   string(ctx.Method()),
+		Path:     string(ctx.Path()),
+		RemoteIP: ctx.RemoteIP().String(),
+		Query:    query,
+		Headers:  headers,
+		readBody: func(limit int64) ([]byte, *ErrorInfo) {
+			return readBodyFastHTTP(&ctx.Request, limit)
+		}}
+}
+
+// NewRequestAnyHTTP maps an `anyhttp` request using its engine's
+// mapping. Other implementations only provide the `Content-Type`
+// header.
+func NewRequestAnyHTTP(aReq anyhttp.Request) *Request {
+	switch req := aReq.(type) {
+	case *anyhttp.RequestNetHttp:
+		return NewRequestNetHTTP(req.Raw)
+	case *anyhttp.RequestFastHttp:
+		return NewRequestFastHTTP(req.Raw)
+	}
+	headers := http.Header{}
+	if contentType := aReq.HeaderString("Content-Type"); len(contentType) > 0 {
+		headers.Set("Content-Type", contentType)
+	}
+	return &Request{
+		Method:   string(aReq.Method()),
+		RemoteIP: aReq.RemoteAddress(),
+		Query:    aReq.QueryArgs().GetURLValues(),
+		Headers:  headers,
+		readBody: func(limit int64) ([]byte, *ErrorInfo) {
+			bytes, err := aReq.PostBody()
+			if err != nil {
+				return nil, &ErrorInfo{
+					StatusCode: http.StatusBadRequest,
+					Body:       []byte("E_BODY_READ [" + err.Error() + "]")}
+			}
+			return bytes, checkBodySize(len(bytes), limit)
+		}}
+}
+
+// ContentType returns the `Content-Type` header.
+func (r *Request) ContentType() string {
+	return r.Headers.Get("Content-Type")
+}
+
+// Message reads the body, rejecting bodies over `limit` bytes, and
+// returns the message for `bodyType`. See `DecodeBody`.
+func (r *Request) Message(bodyType MessageBodyType, limit int64) ([]byte, *ErrorInfo) {
+	if info := CheckContentType(bodyType, r.ContentType()); info != nil {
+		return nil, info
+	}
+	if r.readBody != nil {
+		body, info := r.readBody(limit)
+		if info != nil {
+			return nil, info
+		}
+		r.Body = body
+		r.readBody = nil
+	} else if info := checkBodySize(len(r.Body), limit); info != nil {
+		return nil, info
+	}
+	return DecodeBody(bodyType, r.ContentType(), r.Body)
+}
+
+// NewHookData returns the hook data for the query parameters and
+// headers of `req`. Parameters other than the `FixedParams` are custom
+// params for the handler.
+func NewHookData(req *Request) HookData {
+	data := HookData{
+		InputType:         strings.TrimSpace(req.Query.Get(QueryParamInputType)),
+		OutputType:        strings.TrimSpace(req.Query.Get(QueryParamOutputType)),
+		OutputURL:         strings.TrimSpace(req.Query.Get(QueryParamOutputURL)),
+		Token:             strings.TrimSpace(req.Query.Get(QueryParamToken)),
+		Route:             strings.TrimSpace(req.Query.Get(QueryParamRoute)),
+		OutputNames:       stringsutil.SliceCondenseSpace(strings.Split(req.Query.Get(QueryParamOutputAdapters), ","), true, false),
+		CustomQueryParams: url.Values{},
+		InputHeaders:      req.Headers,
+		Context:           req.Context}
+	for key, vals := range req.Query {
+		if _, ok := FixedParams[key]; !ok {
+			data.CustomQueryParams[key] = vals
+		}
+	}
+	return data
+}
+
+// HookDataFromRequest returns the hook data and message of `req`.
+func HookDataFromRequest(bodyType MessageBodyType, req *Request, limit int64) (HookData, *ErrorInfo) {
+	data := NewHookData(req)
+	message, info := req.Message(bodyType, limit)
+	data.InputBody = message
+	return data, info
+}
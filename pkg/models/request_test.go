@@ -0,0 +1,158 @@
+package models
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grokify/simplego/net/anyhttp"
+	"github.com/valyala/fasthttp"
+
+	"github.com/grokify/chathooks/pkg/awslambda"
+)
+
+// testRequest is built by each of the `testEngines`.
+type testRequest struct {
+	target      string
+	contentType string
+	body        string
+}
+
+const testRemoteIP = "203.0.113.7"
+
+func newTestNetHTTP(tr testRequest) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, tr.target, strings.NewReader(tr.body))
+	req.RemoteAddr = testRemoteIP + ":4321"
+	req.Header.Set("X-Request-Id", "abc")
+	if len(tr.contentType) > 0 {
+		req.Header.Set("Content-Type", tr.contentType)
+	}
+	return req
+}
+
+func newTestFastHTTP(tr testRequest) *fasthttp.RequestCtx {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	req.Header.SetMethod(http.MethodPost)
+	req.SetRequestURI(tr.target)
+	req.Header.Set("X-Request-Id", "abc")
+	if len(tr.contentType) > 0 {
+		req.Header.SetContentType(tr.contentType)
+	}
+	req.SetBodyString(tr.body)
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Init(req, &net.TCPAddr{IP: net.ParseIP(testRemoteIP), Port: 4321}, nil)
+	return ctx
+}
+
+// newTestAwsLambda maps `tr` with `awslambda` from an API Gateway v1
+// event, as on Lambda.
+func newTestAwsLambda(tr testRequest, isBase64 bool) *http.Request {
+	u, _ := url.Parse(tr.target)
+	event := events.APIGatewayProxyRequest{
+		HTTPMethod:                      http.MethodPost,
+		Path:                            u.Path,
+		MultiValueQueryStringParameters: u.Query(),
+		Headers:                         map[string]string{"x-request-id": "abc"},
+		Body:                            tr.body,
+		IsBase64Encoded:                 isBase64}
+	event.RequestContext.Identity.SourceIP = testRemoteIP
+	if len(tr.contentType) > 0 {
+		event.Headers["content-type"] = tr.contentType
+	}
+	if isBase64 {
+		event.Body = base64.StdEncoding.EncodeToString([]byte(tr.body))
+	}
+	payload, _ := json.Marshal(event)
+	req, _ := awslambda.NewRequest(context.Background(), payload)
+	return req.HTTP
+}
+
+// testEngines map the same request with each engine.
+var testEngines = []struct {
+	name       string
+	newRequest func(tr testRequest) *Request
+}{
+	{"nethttp", func(tr testRequest) *Request {
+		return NewRequestNetHTTP(newTestNetHTTP(tr))
+	}},
+	{"fasthttp", func(tr testRequest) *Request {
+		return NewRequestFastHTTP(newTestFastHTTP(tr))
+	}},
+	{"anyhttp/nethttp", func(tr testRequest) *Request {
+		return NewRequestAnyHTTP(anyhttp.NewRequestNetHttp(newTestNetHTTP(tr)))
+	}},
+	{"anyhttp/fasthttp", func(tr testRequest) *Request {
+		return NewRequestAnyHTTP(anyhttp.NewRequestFastHttp(newTestFastHTTP(tr)))
+	}},
+	{"awslambda", func(tr testRequest) *Request {
+		return NewRequestNetHTTP(newTestAwsLambda(tr, false))
+	}},
+	{"awslambda/base64", func(tr testRequest) *Request {
+		return NewRequestNetHTTP(newTestAwsLambda(tr, true))
+	}}}
+
+var conformanceTests = []struct {
+	req             testRequest
+	bodyType        MessageBodyType
+	wantInputType   string
+	wantToken       string
+	wantOutputNames []string
+	wantParams      url.Values
+	wantBody        string
+	wantCode        int
+}{
+	{testRequest{"/hook?inputType=wootric&token=t1&wootricFormatResponse=score%5BScore%5D&tag=a&tag=b", "application/json", `{"a":1}`},
+		JSON, "wootric", "t1", []string{}, url.Values{"wootricFormatResponse": {"score[Score]"}, "tag": {"a", "b"}}, `{"a":1}`, 0},
+	{testRequest{"/hook?inputType=slack&adapters=ops,+dev,", "", `{"a":1}`},
+		JSON, "slack", "", []string{"ops", "dev"}, url.Values{}, `{"a":1}`, 0},
+	{testRequest{"/webhook?inputType=travisci", "application/x-www-form-urlencoded", "payload=%7B%22a%22%3A1%7D"},
+		URLEncodedJSONPayload, "travisci", "", []string{}, url.Values{}, `{"a":1}`, 0},
+	{testRequest{"/hook?inputType=wootric", "application/x-www-form-urlencoded", "response[score]=9"},
+		URLEncodedRails, "wootric", "", []string{}, url.Values{}, `{"response":{"score":"9"}}`, 0},
+	{testRequest{"/hook?inputType=slack", "text/html", "<p>"},
+		JSON, "slack", "", []string{}, url.Values{}, "", http.StatusUnsupportedMediaType},
+	{testRequest{"/hook?inputType=cloudevents", "application/xml", "<p/>"},
+		Raw, "cloudevents", "", []string{}, url.Values{}, "<p/>", 0},
+	{testRequest{"/hook?inputType=slack", "application/json", strings.Repeat("x", 33)},
+		JSON, "slack", "", []string{}, url.Values{}, "", http.StatusRequestEntityTooLarge}}
+
+func TestRequestConformance(t *testing.T) {
+	for i, tt := range conformanceTests {
+		for _, engine := range testEngines {
+			req := engine.newRequest(tt.req)
+			hookData, info := HookDataFromRequest(tt.bodyType, req, 32)
+			gotCode := 0
+			if info != nil {
+				gotCode = info.StatusCode
+			}
+			if req.Method != http.MethodPost || req.Path != strings.Split(tt.req.target, "?")[0] ||
+				req.Headers.Get("X-Request-Id") != "abc" {
+				t.Errorf("%v(%v): want POST %v abc, got %v %v %v",
+					engine.name, i, tt.req.target, req.Method, req.Path, req.Headers.Get("X-Request-Id"))
+			}
+			if req.RemoteIP != testRemoteIP {
+				t.Errorf("%v(%v): want remote IP %v, got %v", engine.name, i, testRemoteIP, req.RemoteIP)
+			}
+			if hookData.InputType != tt.wantInputType || hookData.Token != tt.wantToken ||
+				!reflect.DeepEqual(hookData.OutputNames, tt.wantOutputNames) ||
+				!reflect.DeepEqual(hookData.CustomQueryParams, tt.wantParams) {
+				t.Errorf("%v(%v): want %v %v %v %v, got %v %v %v %v", engine.name, i,
+					tt.wantInputType, tt.wantToken, tt.wantOutputNames, tt.wantParams,
+					hookData.InputType, hookData.Token, hookData.OutputNames, hookData.CustomQueryParams)
+			}
+			if string(hookData.InputBody) != tt.wantBody || gotCode != tt.wantCode {
+				t.Errorf("%v(%v): want %v/%v, got %v/%v", engine.name, i,
+					tt.wantBody, tt.wantCode, string(hookData.InputBody), gotCode)
+			}
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package ratelimit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grokify/chathooks/pkg/state"
+)
+
+// Limit is a token bucket allowing `rate` messages `per` duration,
+// e.g. `1m`, with bursts of up to `burst` messages.
+type Limit struct {
+	Rate  int    `json:"rate"`
+	Per   string `json:"per,omitempty"`
+	Burst int    `json:"burst,omitempty"`
+	per   time.Duration
+}
+
+func (limit *Limit) Compile() error {
+	if limit.Rate <= 0 {
+		return fmt.Errorf("E_RATE_LIMIT_RATE_INVALID [%d]", limit.Rate)
+	}
+	limit.per = time.Minute
+	if per := strings.TrimSpace(limit.Per); len(per) > 0 {
+		dur, err := time.ParseDuration(per)
+		if err != nil || dur <= 0 {
+			return fmt.Errorf("E_RATE_LIMIT_PER_INVALID [%s]", limit.Per)
+		}
+		limit.per = dur
+	}
+	if limit.Burst < 0 {
+		return fmt.Errorf("E_RATE_LIMIT_BURST_INVALID [%d]", limit.Burst)
+	} else if limit.Burst == 0 {
+		limit.Burst = limit.Rate
+	}
+	return nil
+}
+
+type bucket struct {
+	limit   *Limit
+	tokens  float64
+	last    time.Time
+	pending map[string]*Overflow // by pending key
+}
+
+func (b *bucket) suppressed() int {
+	count := 0
+	for _, overflow := range b.pending {
+		count += overflow.Suppressed
+	}
+	return count
+}
+
+// refill adds the tokens earned since the last call.
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += float64(b.limit.Rate) * float64(elapsed) / float64(b.limit.per)
+		if b.tokens > float64(b.limit.Burst) {
+			b.tokens = float64(b.limit.Burst)
+		}
+	}
+	b.last = now
+}
+
+// Limiter holds token buckets by key. It is safe for concurrent use.
+type Limiter struct {
+	mutex   sync.Mutex
+	clock   state.Clock
+	buckets map[string]*bucket
+}
+
+func NewLimiter(clock state.Clock) *Limiter {
+	if clock == nil {
+		clock = state.SystemClock
+	}
+	return &Limiter{clock: clock, buckets: map[string]*bucket{}}
+}
+
+// Allow takes a token for `key`. If none is left, the message is
+// counted as suppressed under `pendingKey` and `pending`, e.g. its
+// destination, is kept for the overflow summary. Messages with
+// different pending keys are summarized separately.
+func (l *Limiter) Allow(key string, limit *Limit, pendingKey string, pending interface{}) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.clock.Now()
+	b, ok := l.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{limit: limit, tokens: float64(limit.Burst), last: now, pending: map[string]*Overflow{}}
+		l.buckets[key] = b
+	}
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true
+	}
+	overflow, ok := b.pending[pendingKey]
+	if !ok {
+		overflow = &Overflow{Key: key, PendingKey: pendingKey}
+		b.pending[pendingKey] = overflow
+	}
+	overflow.Suppressed++
+	overflow.Pending = pending
+	return false
+}
+
+// Overflow is a count of suppressed messages to summarize.
+type Overflow struct {
+	Key        string
+	PendingKey string
+	Suppressed int
+	Pending    interface{}
+}
+
+// Overflows returns the suppressed messages of the keys that have a
+// token again, one per pending key, taking one token for the
+// summaries. Idle full buckets are removed.
+func (l *Limiter) Overflows() []Overflow {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.clock.Now()
+	overflows := []Overflow{}
+	for key, b := range l.buckets {
+		b.refill(now)
+		if len(b.pending) > 0 {
+			if b.tokens >= 1 {
+				b.tokens--
+				for _, overflow := range b.pending {
+					overflows = append(overflows, *overflow)
+				}
+				b.pending = map[string]*Overflow{}
+			}
+		} else if b.tokens >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+	sort.Slice(overflows, func(i, j int) bool {
+		if overflows[i].Key != overflows[j].Key {
+			return overflows[i].Key < overflows[j].Key
+		}
+		return overflows[i].PendingKey < overflows[j].PendingKey
+	})
+	return overflows
+}
+
+// Status describes a bucket for the admin endpoint.
+type Status struct {
+	Key        string  `json:"key"`
+	Rate       int     `json:"rate"`
+	Per        string  `json:"per"`
+	Burst      int     `json:"burst"`
+	Tokens     float64 `json:"tokens"`
+	Suppressed int     `json:"suppressed"`
+}
+
+// Statuses returns the current buckets sorted by key.
+func (l *Limiter) Statuses() []Status {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.clock.Now()
+	statuses := []Status{}
+	for key, b := range l.buckets {
+		b.refill(now)
+		statuses = append(statuses, Status{
+			Key:        key,
+			Rate:       b.limit.Rate,
+			Per:        b.limit.per.String(),
+			Burst:      b.limit.Burst,
+			Tokens:     float64(int(b.tokens*100)) / 100,
+			Suppressed: b.suppressed()})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Key < statuses[j].Key })
+	return statuses
+}
+
+// SuppressedText is the overflow summary text.
+func SuppressedText(count int) string {
+	if count == 1 {
+		return "1 more event suppressed"
+	}
+	return fmt.Sprintf("%d more events suppressed", count)
+}
+
+// OutputLimits configures limits per output. `outputs` is keyed by
+// webhook URL or adapter name. Other outputs use `default`, if set.
+type OutputLimits struct {
+	Default *Limit            `json:"default,omitempty"`
+	Outputs map[string]*Limit `json:"outputs,omitempty"`
+}
+
+func (limits *OutputLimits) Compile() error {
+	if limits.Default != nil {
+		if err := limits.Default.Compile(); err != nil {
+			return err
+		}
+	}
+	for key, limit := range limits.Outputs {
+		if err := limit.Compile(); err != nil {
+			return fmt.Errorf("%s [%s]", err.Error(), key)
+		}
+	}
+	return nil
+}
+
+// Lookup returns the limit for an output or nil if it is unlimited.
+func (limits *OutputLimits) Lookup(key string) *Limit {
+	if limits == nil {
+		return nil
+	} else if limit, ok := limits.Outputs[key]; ok {
+		return limit
+	}
+	return limits.Default
+}
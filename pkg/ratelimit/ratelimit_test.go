@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+type testClock struct{ now time.Time }
+
+func (c *testClock) Now() time.Time { return c.now }
+
+var LimiterTests = []struct {
+	after time.Duration
+	allow bool
+}{
+	{0, true},
+	{0, true},
+	{0, false},
+	{0, false},
+	{10 * time.Second, false},
+	{20 * time.Second, true},
+	{0, false}}
+
+func TestLimiter(t *testing.T) {
+	clock := &testClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	limiter := NewLimiter(clock)
+	limit := &Limit{Rate: 2, Per: "1m"}
+	if err := limit.Compile(); err != nil {
+		t.Fatalf("Limit.Compile(): want no error, got %v", err)
+	}
+
+	for i, tt := range LimiterTests {
+		clock.now = clock.now.Add(tt.after)
+		if got := limiter.Allow("route", limit, fmt.Sprint(i%2), i); got != tt.allow {
+			t.Errorf("Limiter.Allow(%v): want %v, got %v", i, tt.allow, got)
+		}
+	}
+
+	if overflows := limiter.Overflows(); len(overflows) != 0 {
+		t.Errorf("Limiter.Overflows(): want 0 before refill, got %v", len(overflows))
+	}
+	clock.now = clock.now.Add(30 * time.Second)
+	overflows := limiter.Overflows()
+	if len(overflows) != 2 || overflows[0].Suppressed != 3 || overflows[0].Pending != 6 ||
+		overflows[1].Suppressed != 1 || overflows[1].Pending != 3 {
+		t.Fatalf("Limiter.Overflows(): want 3 suppressed with pending 6 and 1 with pending 3, got %v", overflows)
+	}
+	if got := SuppressedText(overflows[0].Suppressed); got != "3 more events suppressed" {
+		t.Errorf("SuppressedText(3): want %v, got %v", "3 more events suppressed", got)
+	}
+	if overflows := limiter.Overflows(); len(overflows) != 0 {
+		t.Errorf("Limiter.Overflows(again): want 0, got %v", len(overflows))
+	}
+
+	statuses := limiter.Statuses()
+	if len(statuses) != 1 || statuses[0].Key != "route" || statuses[0].Suppressed != 0 {
+		t.Errorf("Limiter.Statuses(): want route with 0 suppressed, got %v", statuses)
+	}
+}
+
+func TestOutputLimits(t *testing.T) {
+	limits := OutputLimits{
+		Default: &Limit{Rate: 10},
+		Outputs: map[string]*Limit{"https://example.com/team": {Rate: 1, Per: "1s"}}}
+	if err := limits.Compile(); err != nil {
+		t.Fatalf("OutputLimits.Compile(): want no error, got %v", err)
+	}
+	if got := limits.Lookup("https://example.com/team"); got == nil || got.Rate != 1 {
+		t.Errorf("OutputLimits.Lookup(team): want rate 1, got %v", got)
+	}
+	if got := limits.Lookup("glip"); got == nil || got.Rate != 10 || got.Burst != 10 {
+		t.Errorf("OutputLimits.Lookup(glip): want default, got %v", got)
+	}
+	var none *OutputLimits
+	if got := none.Lookup("glip"); got != nil {
+		t.Errorf("OutputLimits(nil).Lookup(glip): want nil, got %v", got)
+	}
+	if err := (&Limit{Rate: 1, Per: "-1m"}).Compile(); err == nil {
+		t.Errorf("Limit.Compile(per=-1m): want error, got none")
+	}
+}
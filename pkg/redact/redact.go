@@ -0,0 +1,130 @@
+// Package redact masks secrets such as tokens, webhook URLs and
+// sensitive JSON fields in logs and responses.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces the values of redacted fields.
+const Redacted = "[REDACTED]"
+
+// DefaultFields are always redacted. Field names match at any depth,
+// ignoring case.
+var DefaultFields = []string{
+	"token", "access_token", "accessToken", "api_key", "apiKey",
+	"password", "secret", "authorization"}
+
+// Token shows only the first four characters of a token.
+func Token(token string) string {
+	if len(token) <= 4 {
+		return strings.Repeat("*", len(token))
+	}
+	return token[:4] + strings.Repeat("*", len(token)-4)
+}
+
+// URL hides the path and query of a URL, which usually hold the
+// webhook secret, except for the last four characters of the path.
+// Values that are not URLs are returned as is.
+func URL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || len(u.Host) == 0 {
+		return s
+	}
+	masked := u.Scheme + "://" + u.Host + "/"
+	if len(u.Path) > 5 {
+		masked += "..." + u.Path[len(u.Path)-4:]
+	}
+	return masked
+}
+
+// Target masks an output URL, or a webhook ID used in its place.
+func Target(s string) string {
+	if strings.Contains(s, "://") {
+		return URL(s)
+	}
+	return Token(s)
+}
+
+// Redactor redacts configured fields in JSON bodies and parameters.
+type Redactor struct {
+	fields map[string]bool
+}
+
+// NewRedactor returns a redactor for `DefaultFields` and `fields`.
+func NewRedactor(fields []string) *Redactor {
+	r := &Redactor{fields: map[string]bool{}}
+	for _, field := range append(append([]string{}, DefaultFields...), fields...) {
+		if field = strings.TrimSpace(field); len(field) > 0 {
+			r.fields[strings.ToLower(field)] = true
+		}
+	}
+	return r
+}
+
+var defaultRedactor = NewRedactor(nil)
+
+// Field returns whether values of `name` are redacted. A nil redactor
+// uses `DefaultFields`.
+func (r *Redactor) Field(name string) bool {
+	if r == nil {
+		r = defaultRedactor
+	}
+	return r.fields[strings.ToLower(name)]
+}
+
+// JSON returns `body` with the values of redacted fields replaced.
+// Bodies that are not JSON are returned as is.
+func (r *Redactor) JSON(body []byte) []byte {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return body
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return body
+	}
+	redacted, err := json.Marshal(r.value(doc))
+	if err != nil {
+		return body
+	}
+	return redacted
+}
+
+func (r *Redactor) value(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, item := range val {
+			if r.Field(key) {
+				val[key] = Redacted
+			} else {
+				val[key] = r.value(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = r.value(item)
+		}
+	}
+	return v
+}
+
+// Values returns a copy of `values` with redacted parameters replaced.
+func (r *Redactor) Values(values url.Values) url.Values {
+	if values == nil {
+		return nil
+	}
+	redacted := url.Values{}
+	for key, vals := range values {
+		if r.Field(key) {
+			redacted[key] = []string{Redacted}
+		} else {
+			redacted[key] = append([]string{}, vals...)
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,68 @@
+package redact
+
+import (
+	"net/url"
+	"testing"
+)
+
+var URLTests = []struct {
+	v    string
+	want string
+}{
+	{"https://hooks.slack.com/services/T1/B2/secret1234", "https://hooks.slack.com/...1234"},
+	{"https://hooks.glip.com/webhook/v2/abc?token=x", "https://hooks.glip.com/.../abc"},
+	{"https://example.com/a", "https://example.com/"},
+	{"ops", "ops"}}
+
+func TestURL(t *testing.T) {
+	for _, tt := range URLTests {
+		if got := URL(tt.v); got != tt.want {
+			t.Errorf("redact.URL(%v): want %v, got %v", tt.v, tt.want, got)
+		}
+	}
+}
+
+var TargetTests = []struct {
+	v    string
+	want string
+}{
+	{"https://hooks.slack.com/services/T1/B2/secret1234", "https://hooks.slack.com/...1234"},
+	{"11112222-3333", "1111*********"},
+	{"abc", "***"},
+	{"", ""}}
+
+func TestTarget(t *testing.T) {
+	for _, tt := range TargetTests {
+		if got := Target(tt.v); got != tt.want {
+			t.Errorf("redact.Target(%v): want %v, got %v", tt.v, tt.want, got)
+		}
+	}
+}
+
+var RedactorJSONTests = []struct {
+	fields []string
+	body   string
+	want   string
+}{
+	{nil, `{"id":12345678901234567890,"Token":"abc","user":{"password":"x","name":"n"}}`,
+		`{"Token":"[REDACTED]","id":12345678901234567890,"user":{"name":"n","password":"[REDACTED]"}}`},
+	{[]string{"email"}, `{"events":[{"email":"a@b.c","apiKey":{"v":1}}]}`,
+		`{"events":[{"apiKey":"[REDACTED]","email":"[REDACTED]"}]}`},
+	{nil, `payload=%7B%7D`, `payload=%7B%7D`},
+	{nil, ``, ``}}
+
+func TestRedactorJSON(t *testing.T) {
+	for _, tt := range RedactorJSONTests {
+		if got := string(NewRedactor(tt.fields).JSON([]byte(tt.body))); got != tt.want {
+			t.Errorf("Redactor.JSON(%v): want %v, got %v", tt.body, tt.want, got)
+		}
+	}
+}
+
+func TestRedactorValues(t *testing.T) {
+	var r *Redactor
+	got := r.Values(url.Values{"secret": {"s"}, "channel": {"c"}})
+	if got.Get("secret") != Redacted || got.Get("channel") != "c" {
+		t.Errorf("Redactor.Values(): want secret redacted, got %v", got)
+	}
+}
@@ -0,0 +1,222 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	cc "github.com/grokify/commonchat"
+	"github.com/rs/zerolog/log"
+
+	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/state"
+)
+
+// DefaultFlapThreshold is the number of events in a window after
+// which a flapping summary is sent.
+const DefaultFlapThreshold = 3
+
+// Dedup suppresses repeated messages with the same fingerprint
+// within `window`, e.g. `10m`. `fingerprint` lists input JSON paths;
+// when empty, the handler default is used and, failing that, a hash
+// of the message. When repeats reach `flapThreshold`, a single
+// "flapping N times in M minutes" summary is sent when the window
+// closes.
+type Dedup struct {
+	Window        string   `json:"window"`
+	Fingerprint   []string `json:"fingerprint,omitempty"`
+	FlapThreshold int      `json:"flapThreshold,omitempty"`
+	window        time.Duration
+}
+
+type dedupEntry struct {
+	First time.Time `json:"first"`
+	Count int       `json:"count"`
+}
+
+// flapSummary is a pending flapping summary for one fingerprint and
+// window. It is sent to the destination of the latest repeat.
+type flapSummary struct {
+	Due         time.Time       `json:"due"`
+	First       time.Time       `json:"first"`
+	Last        time.Time       `json:"last"`
+	Count       int             `json:"count"`
+	Activity    string          `json:"activity,omitempty"`
+	Title       string          `json:"title,omitempty"`
+	IconEmoji   string          `json:"iconEmoji,omitempty"`
+	IconURL     string          `json:"iconUrl,omitempty"`
+	InputType   string          `json:"inputType,omitempty"`
+	OutputType  string          `json:"outputType,omitempty"`
+	OutputURL   string          `json:"outputUrl,omitempty"`
+	OutputNames []string        `json:"outputNames,omitempty"`
+	Outputs     []models.Output `json:"outputs,omitempty"`
+}
+
+// flapSummaries is keyed by dedup key and window.
+type flapSummaries map[string]*flapSummary
+
+func (dedup *Dedup) Compile() error {
+	window, err := time.ParseDuration(strings.TrimSpace(dedup.Window))
+	if err != nil || window <= 0 {
+		return fmt.Errorf("E_DEDUP_WINDOW_INVALID [%s]", dedup.Window)
+	}
+	dedup.window = window
+	if dedup.FlapThreshold < 0 {
+		return fmt.Errorf("E_DEDUP_FLAP_THRESHOLD_INVALID [%d]", dedup.FlapThreshold)
+	} else if dedup.FlapThreshold == 0 {
+		dedup.FlapThreshold = DefaultFlapThreshold
+	}
+	return nil
+}
+
+// check records an event for `key`. It returns the hook data to
+// deliver or, when the event is a repeat, the response to send
+// instead. Repeats from `flapThreshold` on update the pending summary
+// under `flapKey`. Store errors are logged and the event is delivered.
+func (dedup *Dedup) check(store state.Store, clock state.Clock, key, flapKey string, hookData models.HookData) (models.HookData, *models.ErrorInfo) {
+	now := clock.Now()
+	entry := dedupEntry{}
+	bytes, ok, err := store.Get(key)
+	if err != nil {
+		log.Warn().Err(err).Str("key", key).Msg("dedup state read failed")
+		return hookData, nil
+	}
+	if ok {
+		if err := json.Unmarshal(bytes, &entry); err != nil {
+			ok = false
+		}
+	}
+	ttl := entry.First.Add(dedup.window).Sub(now)
+	if !ok || ttl <= 0 {
+		entry = dedupEntry{First: now, Count: 1}
+		ttl = dedup.window
+	} else {
+		entry.Count++
+	}
+	if bytes, err = json.Marshal(entry); err == nil {
+		err = store.Set(key, bytes, ttl)
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("key", key).Msg("dedup state write failed")
+	}
+
+	if entry.Count == 1 {
+		return hookData, nil
+	} else if entry.Count >= dedup.FlapThreshold {
+		if err := dedup.addFlap(store, flapKey, key, entry, now, hookData); err != nil {
+			log.Warn().Err(err).Str("key", flapKey).Msg("dedup flap state write failed")
+		}
+	}
+	info := models.NewStatusInfo(models.StatusDeduplicated, []byte(key))
+	return hookData, &info
+}
+
+// addFlap records the count of a flapping fingerprint, to be sent
+// when its window closes.
+func (dedup *Dedup) addFlap(store state.Store, flapKey, key string, entry dedupEntry, now time.Time, hookData models.HookData) error {
+	flaps, err := readFlaps(store, flapKey)
+	if err != nil {
+		return err
+	}
+	ccMsg := hookData.CanonicalMessage
+	flaps[fmt.Sprintf("%s|%d", key, entry.First.UnixNano())] = &flapSummary{
+		Due:         entry.First.Add(dedup.window),
+		First:       entry.First,
+		Last:        now,
+		Count:       entry.Count,
+		Activity:    ccMsg.Activity,
+		Title:       ccMsg.Title,
+		IconEmoji:   ccMsg.IconEmoji,
+		IconURL:     ccMsg.IconURL,
+		InputType:   hookData.InputType,
+		OutputType:  hookData.OutputType,
+		OutputURL:   hookData.OutputURL,
+		OutputNames: hookData.OutputNames,
+		Outputs:     hookData.Outputs}
+	return writeFlaps(store, flapKey, flaps)
+}
+
+func (route *Route) flapKey() string {
+	return "flap|" + route.Name
+}
+
+// flushFlaps sends the flapping summaries whose window has closed, or
+// all summaries with `all`.
+func (route *Route) flushFlaps(send Sender, all bool) {
+	route.mutex.Lock()
+	flaps, err := readFlaps(route.store, route.flapKey())
+	due := []*flapSummary{}
+	if err == nil {
+		now := route.clock.Now()
+		for key, flap := range flaps {
+			if all || !now.Before(flap.Due) {
+				due = append(due, flap)
+				delete(flaps, key)
+			}
+		}
+		if len(due) > 0 {
+			err = writeFlaps(route.store, route.flapKey(), flaps)
+		}
+	}
+	route.mutex.Unlock()
+	if err != nil {
+		log.Warn().Err(err).Str("route", route.Name).Msg("dedup flap state failed")
+		return
+	}
+
+	for _, flap := range due {
+		hookData := models.HookData{
+			InputType:        flap.InputType,
+			OutputType:       flap.OutputType,
+			OutputURL:        flap.OutputURL,
+			OutputNames:      flap.OutputNames,
+			Outputs:          flap.Outputs,
+			CanonicalMessage: flap.message()}
+		for _, errInfo := range send(hookData) {
+			if errInfo.StatusCode >= 300 {
+				log.Warn().
+					Str("route", route.Name).
+					Int("http_status", errInfo.StatusCode).
+					Str("body", string(errInfo.Body)).
+					Msg("flapping summary delivery failed")
+			}
+		}
+	}
+}
+
+// message summarizes the repeats.
+func (flap *flapSummary) message() cc.Message {
+	minutes := int(math.Ceil(flap.Last.Sub(flap.First).Minutes()))
+	if minutes < 1 {
+		minutes = 1
+	}
+	summary := cc.NewMessage()
+	summary.Activity = flap.Activity
+	summary.IconEmoji = flap.IconEmoji
+	summary.IconURL = flap.IconURL
+	summary.Title = flap.Title
+	summary.Text = fmt.Sprintf("flapping %d times in %d minutes", flap.Count, minutes)
+	return summary
+}
+
+func readFlaps(store state.Store, flapKey string) (flapSummaries, error) {
+	flaps := flapSummaries{}
+	bytes, ok, err := store.Get(flapKey)
+	if err != nil || !ok {
+		return flaps, err
+	}
+	return flaps, json.Unmarshal(bytes, &flaps)
+}
+
+func writeFlaps(store state.Store, flapKey string, flaps flapSummaries) error {
+	if len(flaps) == 0 {
+		return store.Delete(flapKey)
+	}
+	bytes, err := json.Marshal(flaps)
+	if err != nil {
+		return err
+	}
+	return store.Set(flapKey, bytes, 0)
+}
@@ -0,0 +1,120 @@
+package routes
+
+import (
+	"testing"
+	"time"
+
+	cc "github.com/grokify/commonchat"
+
+	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/state"
+)
+
+type testClock struct{ now time.Time }
+
+func (c *testClock) Now() time.Time { return c.now }
+
+var DedupTests = []struct {
+	after  time.Duration
+	body   string
+	status string
+	text   string
+}{
+	{0, `{"check_id":1,"current_state":"DOWN"}`, "", "down"},
+	{time.Minute, `{"check_id":1,"current_state":"DOWN"}`, models.StatusDeduplicated, ""},
+	{time.Minute, `{"check_id":2,"current_state":"DOWN"}`, "", "down"},
+	{time.Minute, `{"check_id":1,"current_state":"DOWN"}`, models.StatusDeduplicated, ""},
+	{time.Minute, `{"check_id":1,"current_state":"DOWN"}`, models.StatusDeduplicated, ""},
+	{10 * time.Minute, `{"check_id":1,"current_state":"DOWN"}`, "", "down"}}
+
+func TestRouteDedup(t *testing.T) {
+	clock := &testClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	set := NewRouteSet()
+	set.SetState(state.NewMemoryStore(clock), clock)
+	if err := set.Add(&Route{Name: "pingdom", Dedup: &Dedup{Window: "10m"}}); err != nil {
+		t.Fatalf("RouteSet.Add(): want no error, got %v", err)
+	}
+	route, _ := set.Lookup("pingdom", "")
+
+	for i, tt := range DedupTests {
+		clock.now = clock.now.Add(tt.after)
+		ccMsg := cc.NewMessage()
+		ccMsg.Text = "down"
+		hookData, info := route.Process(models.HookData{
+			InputBody:        []byte(tt.body),
+			Fingerprint:      tt.body,
+			CanonicalMessage: ccMsg})
+		status := ""
+		if info != nil {
+			status = info.Status
+		}
+		if status != tt.status {
+			t.Errorf("Route.Process(%v:%v): want status [%v], got [%v]", i, tt.body, tt.status, status)
+		} else if info == nil && hookData.CanonicalMessage.Text != tt.text {
+			t.Errorf("Route.Process(%v:%v): want text [%v], got [%v]", i, tt.body, tt.text, hookData.CanonicalMessage.Text)
+		}
+	}
+}
+
+var flapTests = []struct {
+	events   int
+	after    time.Duration
+	wantText string
+}{
+	{6, 0, ""},
+	{0, 4 * time.Minute, "flapping 6 times in 5 minutes"},
+	{2, 10 * time.Minute, ""}}
+
+func TestRouteDedupFlapping(t *testing.T) {
+	clock := &testClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	set := NewRouteSet()
+	set.SetState(state.NewMemoryStore(clock), clock)
+	if err := set.Add(&Route{Name: "pingdom", Dedup: &Dedup{Window: "10m"}}); err != nil {
+		t.Fatalf("RouteSet.Add(): want no error, got %v", err)
+	}
+	route, _ := set.Lookup("pingdom", "")
+	output := models.Output{Type: "slack", URL: "https://hooks.slack.com/services/T/B/X"}
+
+	for i, tt := range flapTests {
+		for j := 0; j < tt.events; j++ {
+			ccMsg := cc.NewMessage()
+			ccMsg.Title = "check 1 down"
+			route.Process(models.HookData{
+				Fingerprint:      "check 1",
+				Outputs:          []models.Output{output},
+				CanonicalMessage: ccMsg})
+			clock.now = clock.now.Add(time.Minute)
+		}
+		clock.now = clock.now.Add(tt.after)
+		sent := []models.HookData{}
+		set.FlushDigests(func(hookData models.HookData) []models.ErrorInfo {
+			sent = append(sent, hookData)
+			return nil
+		})
+		if len(tt.wantText) == 0 && len(sent) > 0 {
+			t.Errorf("RouteSet.FlushDigests(%v): want nothing sent, got %v", i, sent)
+		} else if len(tt.wantText) > 0 && (len(sent) != 1 ||
+			sent[0].CanonicalMessage.Text != tt.wantText || sent[0].CanonicalMessage.Title != "check 1 down" ||
+			len(sent[0].Outputs) != 1 || sent[0].Outputs[0] != output) {
+			t.Errorf("RouteSet.FlushDigests(%v): want [%v] to %v, got %v", i, tt.wantText, output, sent)
+		}
+	}
+}
+
+func TestRouteDedupFingerprint(t *testing.T) {
+	route := &Route{Name: "test", Dedup: &Dedup{Window: "1h", Fingerprint: []string{"id"}}}
+	if err := route.Compile(); err != nil {
+		t.Fatalf("Route.Compile(): want no error, got %v", err)
+	}
+	_, info := route.Process(models.HookData{InputBody: []byte(`{"id":1,"value":1}`), Fingerprint: "a"})
+	if info != nil {
+		t.Errorf("Route.Process(first): want delivered, got %v", info.Status)
+	}
+	_, info = route.Process(models.HookData{InputBody: []byte(`{"id":1,"value":2}`), Fingerprint: "b"})
+	if info == nil || info.Status != models.StatusDeduplicated {
+		t.Errorf("Route.Process(repeat): want %v, got %v", models.StatusDeduplicated, info)
+	}
+	if err := (&Route{Name: "bad", Dedup: &Dedup{Window: "soon"}}).Compile(); err == nil {
+		t.Errorf("Route.Compile(window=soon): want error, got none")
+	}
+}
@@ -0,0 +1,228 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	cc "github.com/grokify/commonchat"
+	"github.com/rs/zerolog/log"
+
+	"github.com/grokify/chathooks/pkg/models"
+)
+
+// DefaultDigestTopN is the number of entries listed per group.
+const DefaultDigestTopN = 5
+
+// Digest batches a route's messages into one summary sent every
+// `interval`, e.g. `30m`, or at a `cron` time, e.g. `0 9 * * *`.
+// Messages are grouped by input type and activity, and each group
+// lists its count and latest `topN` entries.
+type Digest struct {
+	Interval string `json:"interval,omitempty"`
+	Cron     string `json:"cron,omitempty"`
+	TopN     int    `json:"topN,omitempty"`
+	schedule Schedule
+}
+
+func (digest *Digest) Compile() error {
+	schedule, err := ParseSchedule(digest.Interval, digest.Cron)
+	if err != nil {
+		return err
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return fmt.Errorf("E_SCHEDULE_NEVER [%s]", digest.Cron)
+	}
+	digest.schedule = schedule
+	if digest.TopN < 0 {
+		return fmt.Errorf("E_DIGEST_TOP_N_INVALID [%d]", digest.TopN)
+	} else if digest.TopN == 0 {
+		digest.TopN = DefaultDigestTopN
+	}
+	return nil
+}
+
+// digestBatch holds messages for one destination until `Due`.
+type digestBatch struct {
+	Due         time.Time       `json:"due"`
+	OutputType  string          `json:"outputType,omitempty"`
+	OutputURL   string          `json:"outputUrl,omitempty"`
+	OutputNames []string        `json:"outputNames,omitempty"`
+	Outputs     []models.Output `json:"outputs,omitempty"`
+	Groups      []digestGroup   `json:"groups"`
+}
+
+type digestGroup struct {
+	InputType string   `json:"inputType"`
+	Activity  string   `json:"activity"`
+	Count     int      `json:"count"`
+	Entries   []string `json:"entries"`
+}
+
+// digestBatches is keyed by destination.
+type digestBatches map[string]*digestBatch
+
+func (route *Route) digestKey() string {
+	return "digest|" + route.Name
+}
+
+// addDigest adds a message to the batch for its destination. The
+// caller must hold the route lock.
+func (route *Route) addDigest(hookData models.HookData) error {
+	batches, err := route.readDigest()
+	if err != nil {
+		return err
+	}
+	dest := digestBatch{
+		OutputType:  hookData.OutputType,
+		OutputURL:   hookData.OutputURL,
+		OutputNames: hookData.OutputNames,
+		Outputs:     hookData.Outputs}
+	destBytes, err := json.Marshal(dest)
+	if err != nil {
+		return err
+	}
+	batch, ok := batches[string(destBytes)]
+	if !ok {
+		dest.Due = route.Digest.schedule.Next(route.clock.Now())
+		batch = &dest
+		batches[string(destBytes)] = batch
+	}
+	batch.add(hookData.InputType, hookData.CanonicalMessage, route.Digest.TopN)
+	return route.writeDigest(batches)
+}
+
+func (batch *digestBatch) add(inputType string, ccMsg cc.Message, topN int) {
+	var group *digestGroup
+	for i := range batch.Groups {
+		if batch.Groups[i].InputType == inputType && batch.Groups[i].Activity == ccMsg.Activity {
+			group = &batch.Groups[i]
+			break
+		}
+	}
+	if group == nil {
+		batch.Groups = append(batch.Groups, digestGroup{InputType: inputType, Activity: ccMsg.Activity})
+		group = &batch.Groups[len(batch.Groups)-1]
+	}
+	group.Count++
+	group.Entries = append(group.Entries, digestEntry(ccMsg))
+	if len(group.Entries) > topN {
+		group.Entries = group.Entries[len(group.Entries)-topN:]
+	}
+}
+
+// digestEntry returns a one line description of a message.
+func digestEntry(ccMsg cc.Message) string {
+	entry := ccMsg.Title
+	if len(entry) == 0 {
+		for _, att := range ccMsg.Attachments {
+			if len(att.Title) > 0 {
+				entry = att.Title
+				break
+			}
+		}
+	}
+	if len(entry) == 0 {
+		entry = ccMsg.Text
+	}
+	entry = strings.Join(strings.Fields(entry), " ")
+	if runes := []rune(entry); len(runes) > 200 {
+		entry = string(runes[:197]) + "..."
+	}
+	return entry
+}
+
+// flushDigest sends the batches that are due, or all batches with
+// `all`.
+func (route *Route) flushDigest(send Sender, all bool) {
+	route.mutex.Lock()
+	batches, err := route.readDigest()
+	due := []*digestBatch{}
+	if err == nil {
+		now := route.clock.Now()
+		for key, batch := range batches {
+			if all || !now.Before(batch.Due) {
+				due = append(due, batch)
+				delete(batches, key)
+			}
+		}
+		if len(due) > 0 {
+			err = route.writeDigest(batches)
+		}
+	}
+	route.mutex.Unlock()
+	if err != nil {
+		log.Warn().Err(err).Str("route", route.Name).Msg("digest state failed")
+		return
+	}
+
+	for _, batch := range due {
+		hookData := models.HookData{
+			InputType:        "digest",
+			OutputType:       batch.OutputType,
+			OutputURL:        batch.OutputURL,
+			OutputNames:      batch.OutputNames,
+			Outputs:          batch.Outputs,
+			CanonicalMessage: batch.message(route.Name)}
+		for _, errInfo := range send(hookData) {
+			if errInfo.StatusCode >= 300 {
+				log.Warn().
+					Str("route", route.Name).
+					Int("http_status", errInfo.StatusCode).
+					Str("body", string(errInfo.Body)).
+					Msg("digest delivery failed")
+			}
+		}
+	}
+}
+
+// message builds the digest summary.
+func (batch *digestBatch) message(routeName string) cc.Message {
+	total := 0
+	for _, group := range batch.Groups {
+		total += group.Count
+	}
+	ccMsg := cc.NewMessage()
+	ccMsg.Activity = "Digest"
+	ccMsg.Title = fmt.Sprintf("%d events for %s", total, routeName)
+	for _, group := range batch.Groups {
+		title := group.InputType
+		if len(group.Activity) > 0 {
+			title += ": " + group.Activity
+		}
+		lines := []string{}
+		for i := len(group.Entries) - 1; i >= 0; i-- {
+			if len(group.Entries[i]) > 0 {
+				lines = append(lines, "* "+group.Entries[i])
+			}
+		}
+		if more := group.Count - len(group.Entries); more > 0 {
+			lines = append(lines, fmt.Sprintf("and %d more", more))
+		}
+		ccMsg.AddAttachment(cc.Attachment{
+			Title: fmt.Sprintf("%s (%d)", title, group.Count),
+			Text:  strings.Join(lines, "\n")})
+	}
+	return ccMsg
+}
+
+func (route *Route) readDigest() (digestBatches, error) {
+	batches := digestBatches{}
+	bytes, ok, err := route.store.Get(route.digestKey())
+	if err != nil || !ok {
+		return batches, err
+	}
+	return batches, json.Unmarshal(bytes, &batches)
+}
+
+func (route *Route) writeDigest(batches digestBatches) error {
+	if len(batches) == 0 {
+		return route.store.Delete(route.digestKey())
+	}
+	bytes, err := json.Marshal(batches)
+	if err != nil {
+		return err
+	}
+	return route.store.Set(route.digestKey(), bytes, 0)
+}
@@ -0,0 +1,118 @@
+package routes
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	cc "github.com/grokify/commonchat"
+
+	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/state"
+)
+
+var CronNextTests = []struct {
+	cron string
+	from string
+	want string
+}{
+	{"*/15 * * * *", "2020-01-01T10:07:30Z", "2020-01-01T10:15:00Z"},
+	{"0 9 * * *", "2020-01-01T09:00:00Z", "2020-01-02T09:00:00Z"},
+	{"0 9 * * 1-5", "2020-01-03T10:00:00Z", "2020-01-06T09:00:00Z"},
+	{"30 8,17 1 * *", "2020-01-01T09:00:00Z", "2020-01-01T17:30:00Z"},
+	{"0 0 1 1 7", "2020-01-01T01:00:00Z", "2020-01-05T00:00:00Z"},
+	{"0 0 29 2 *", "2021-01-01T00:00:00Z", "2024-02-29T00:00:00Z"}}
+
+func TestCronNext(t *testing.T) {
+	for _, tt := range CronNextTests {
+		schedule, err := ParseCron(tt.cron)
+		if err != nil {
+			t.Errorf("ParseCron(%v): want no error, got %v", tt.cron, err)
+			continue
+		}
+		from, _ := time.Parse(time.RFC3339, tt.from)
+		if got := schedule.Next(from).Format(time.RFC3339); got != tt.want {
+			t.Errorf("CronSchedule.Next(%v,%v): want %v, got %v", tt.cron, tt.from, tt.want, got)
+		}
+	}
+	for _, cron := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := ParseCron(cron); err == nil {
+			t.Errorf("ParseCron(%v): want error, got none", cron)
+		}
+	}
+}
+
+func TestRouteDigest(t *testing.T) {
+	clock := &testClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	set := NewRouteSet()
+	set.SetState(state.NewMemoryStore(clock), clock)
+	if err := set.Add(&Route{Name: "traffic", Digest: &Digest{Interval: "30m", TopN: 2}}); err != nil {
+		t.Fatalf("RouteSet.Add(): want no error, got %v", err)
+	}
+	route, _ := set.Lookup("traffic", "")
+
+	events := []struct{ inputType, activity, title string }{
+		{"gosquared", "Site traffic spike", "spike 1"},
+		{"gosquared", "Site traffic spike", "spike 2"},
+		{"gosquared", "Site traffic spike", "spike 3"},
+		{"wootric", "NPS response", "score 9"}}
+	for _, event := range events {
+		ccMsg := cc.NewMessage()
+		ccMsg.Activity = event.activity
+		ccMsg.Title = event.title
+		_, info := route.Process(models.HookData{
+			InputType:        event.inputType,
+			InputBody:        []byte(`{}`),
+			OutputType:       "glip",
+			OutputURL:        "https://example.com/digest",
+			CanonicalMessage: ccMsg})
+		if info == nil || info.Status != models.StatusDigested {
+			t.Errorf("Route.Process(%v): want %v, got %v", event.title, models.StatusDigested, info)
+		}
+	}
+
+	sent := []models.HookData{}
+	send := func(hookData models.HookData) []models.ErrorInfo {
+		sent = append(sent, hookData)
+		return nil
+	}
+	clock.now = clock.now.Add(29 * time.Minute)
+	set.FlushDigests(send)
+	if len(sent) != 0 {
+		t.Fatalf("RouteSet.FlushDigests(29m): want 0 messages, got %v", len(sent))
+	}
+	clock.now = clock.now.Add(time.Minute)
+	set.FlushDigests(send)
+	if len(sent) != 1 {
+		t.Fatalf("RouteSet.FlushDigests(30m): want 1 message, got %v", len(sent))
+	}
+
+	digest := sent[0]
+	if digest.OutputURL != "https://example.com/digest" {
+		t.Errorf("Digest.OutputURL: want %v, got %v", "https://example.com/digest", digest.OutputURL)
+	}
+	if digest.CanonicalMessage.Title != "4 events for traffic" || len(digest.CanonicalMessage.Attachments) != 2 {
+		t.Fatalf("Digest.CanonicalMessage: want 4 events in 2 groups, got %v", digest.CanonicalMessage)
+	}
+	att := digest.CanonicalMessage.Attachments[0]
+	wantText := "* spike 3\n* spike 2\nand 1 more"
+	if att.Title != "gosquared: Site traffic spike (3)" || att.Text != wantText {
+		t.Errorf("Digest attachment: want %v, got %v %v", wantText, att.Title, strings.Replace(att.Text, "\n", "|", -1))
+	}
+
+	set.FlushDigests(send)
+	if len(sent) != 1 {
+		t.Errorf("RouteSet.FlushDigests(again): want 1 message, got %v", len(sent))
+	}
+
+	route.Process(models.HookData{
+		InputType:        "gosquared",
+		InputBody:        []byte(`{}`),
+		OutputType:       "glip",
+		OutputURL:        "https://example.com/digest",
+		CanonicalMessage: cc.NewMessage()})
+	set.DrainDigests(send)
+	if len(sent) != 2 {
+		t.Errorf("RouteSet.DrainDigests(): want 2 messages, got %v", len(sent))
+	}
+}
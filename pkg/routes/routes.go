@@ -0,0 +1,409 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	cc "github.com/grokify/commonchat"
+	"github.com/rs/zerolog/log"
+
+	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/ratelimit"
+	"github.com/grokify/chathooks/pkg/rules"
+	"github.com/grokify/chathooks/pkg/state"
+)
+
+// Route configures how normalized messages are processed before
+// delivery. A request selects a route with the `route` query
+// parameter, or by matching one of `inputTypes`. `severity` rules
+// override the severity implied by message colors. With `digest`,
+// messages are batched into periodic summaries instead of being
+// sent directly. `rateLimit` limits the messages sent for the route.
+// With `verboseResponse`, responses include the redacted input.
+type Route struct {
+	Name        string               `json:"name"`
+	InputTypes  []string             `json:"inputTypes,omitempty"`
+	Filters     rules.FilterSet      `json:"filters,omitempty"`
+	Dedup       *Dedup               `json:"dedup,omitempty"`
+	Severity    []rules.SeverityRule `json:"severity,omitempty"`
+	Routing     rules.RoutingTable   `json:"routing,omitempty"`
+	Digest      *Digest              `json:"digest,omitempty"`
+	RateLimit   *ratelimit.Limit     `json:"rateLimit,omitempty"`
+	Verbose     bool                 `json:"verboseResponse,omitempty"`
+	MaxBodySize int64                `json:"maxBodySize,omitempty"` // bytes
+	mutex       sync.Mutex
+	store       state.Store
+	clock       state.Clock
+	limiter     *ratelimit.Limiter
+}
+
+// Sender delivers a message, e.g. `adapters.AdapterSet.SendWebhooks`.
+type Sender func(models.HookData) []models.ErrorInfo
+
+// Compile validates the route and prepares its rules.
+func (route *Route) Compile() error {
+	route.Name = strings.TrimSpace(route.Name)
+	if len(route.Name) == 0 {
+		return fmt.Errorf("E_ROUTE_NO_NAME")
+	}
+	if route.MaxBodySize < 0 {
+		return fmt.Errorf("E_ROUTE_MAX_BODY_SIZE_NOT_VALID [%s]", route.Name)
+	}
+	if err := route.Filters.Compile(); err != nil {
+		return fmt.Errorf("%s [%s]", err.Error(), route.Name)
+	}
+	for i := range route.Severity {
+		if err := route.Severity[i].Compile(); err != nil {
+			return fmt.Errorf("%s [%s]", err.Error(), route.Name)
+		}
+	}
+	if err := route.Routing.Compile(); err != nil {
+		return fmt.Errorf("%s [%s]", err.Error(), route.Name)
+	}
+	if route.Dedup != nil {
+		if err := route.Dedup.Compile(); err != nil {
+			return fmt.Errorf("%s [%s]", err.Error(), route.Name)
+		}
+	}
+	if route.Digest != nil {
+		if err := route.Digest.Compile(); err != nil {
+			return fmt.Errorf("%s [%s]", err.Error(), route.Name)
+		}
+	}
+	if route.RateLimit != nil {
+		if err := route.RateLimit.Compile(); err != nil {
+			return fmt.Errorf("%s [%s]", err.Error(), route.Name)
+		}
+	}
+	if route.clock == nil {
+		route.clock = state.SystemClock
+	}
+	if route.store == nil {
+		route.store = state.NewMemoryStore(route.clock)
+	}
+	if route.limiter == nil {
+		route.limiter = ratelimit.NewLimiter(route.clock)
+	}
+	return nil
+}
+
+// Process applies the route to a normalized message. It returns the
+// updated hook data and, when the message must not be delivered,
+// the response to send instead.
+func (route *Route) Process(hookData models.HookData) (models.HookData, *models.ErrorInfo) {
+	doc := rules.NewDocument(hookData.InputBody, hookData.CanonicalMessage)
+	hookData.Severity = rules.Severity(route.Severity, doc, hookData.CanonicalMessage)
+	decision := route.Filters.Evaluate(doc)
+	routed := false
+	switch decision.Action {
+	case rules.ActionDrop:
+		log.Info().
+			Str("route", route.Name).
+			Str("rule", decision.Rule).
+			Str("input_type", hookData.InputType).
+			Msg("message filtered")
+		info := models.NewStatusInfo(models.StatusFiltered, []byte(decision.Rule))
+		return hookData, &info
+	case rules.ActionRoute:
+		hookData = setOutputs(hookData, decision.Outputs)
+		routed = true
+	}
+	if !routed && !route.Routing.IsEmpty() {
+		if outputs := route.Routing.Outputs(hookData.Severity, doc); len(outputs) > 0 {
+			hookData = setOutputs(hookData, outputs)
+		}
+	}
+	if route.Dedup != nil {
+		var info *models.ErrorInfo
+		route.mutex.Lock()
+		hookData, info = route.Dedup.check(route.store, route.clock, route.dedupKey(hookData, doc), route.flapKey(), hookData)
+		route.mutex.Unlock()
+		if info != nil {
+			log.Info().
+				Str("route", route.Name).
+				Str("input_type", hookData.InputType).
+				Msg("message deduplicated")
+			return hookData, info
+		}
+	}
+	if route.Digest != nil {
+		route.mutex.Lock()
+		err := route.addDigest(hookData)
+		route.mutex.Unlock()
+		if err == nil {
+			info := models.NewStatusInfo(models.StatusDigested, []byte(route.Name))
+			return hookData, &info
+		}
+		log.Warn().Err(err).Str("route", route.Name).Msg("digest state failed")
+	}
+	if route.RateLimit != nil && !route.allow(hookData) {
+		log.Info().
+			Str("route", route.Name).
+			Str("input_type", hookData.InputType).
+			Msg("message rate limited")
+		info := models.NewStatusInfo(models.StatusRateLimited, []byte(route.Name))
+		return hookData, &info
+	}
+	return hookData, nil
+}
+
+// allow takes a rate limit token. Suppressed messages are counted by
+// destination, so that each gets its own summary. Only a copy of the
+// destination is kept, not the request body, headers or context.
+func (route *Route) allow(hookData models.HookData) bool {
+	dest := models.HookData{
+		InputType:   hookData.InputType,
+		Route:       hookData.Route,
+		Tenant:      hookData.Tenant,
+		OutputType:  hookData.OutputType,
+		OutputURL:   hookData.OutputURL,
+		OutputNames: append([]string{}, hookData.OutputNames...),
+		Outputs:     append([]models.Output{}, hookData.Outputs...)}
+	destBytes, _ := json.Marshal(dest)
+	return route.limiter.Allow(route.Name, route.RateLimit, string(destBytes), dest)
+}
+
+// dedupKey returns the dedup state key for a message.
+func (route *Route) dedupKey(hookData models.HookData, doc rules.Document) string {
+	fingerprint := rules.Fingerprint(route.Dedup.Fingerprint, doc)
+	if len(fingerprint) == 0 {
+		fingerprint = hookData.Fingerprint
+	}
+	if len(fingerprint) == 0 {
+		fingerprint = rules.MessageFingerprint(hookData.CanonicalMessage)
+	}
+	return "dedup|" + route.Name + "|" + fingerprint
+}
+
+// setOutputs replaces the request outputs.
+func setOutputs(hookData models.HookData, outputs []models.Output) models.HookData {
+	hookData.OutputType = ""
+	hookData.OutputURL = ""
+	hookData.OutputNames = []string{}
+	hookData.Outputs = outputs
+	return hookData
+}
+
+// RouteSet is the set of configured routes. It is safe for
+// concurrent use. Routes share the set's state store and clock.
+type RouteSet struct {
+	// OutputLimits are applied by the adapters to every output.
+	OutputLimits ratelimit.OutputLimits
+	mutex        sync.RWMutex
+	routes       map[string]*Route
+	order        []string
+	store        state.Store
+	clock        state.Clock
+	limiter      *ratelimit.Limiter
+}
+
+func NewRouteSet() *RouteSet {
+	return &RouteSet{
+		routes:  map[string]*Route{},
+		store:   state.NewMemoryStore(state.SystemClock),
+		clock:   state.SystemClock,
+		limiter: ratelimit.NewLimiter(state.SystemClock)}
+}
+
+// SetState replaces the state store and clock, e.g. with a
+// `state.FileStore` or, in tests, a fixed clock.
+func (set *RouteSet) SetState(store state.Store, clock state.Clock) {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	set.store = store
+	set.clock = clock
+	set.limiter = ratelimit.NewLimiter(clock)
+	for _, route := range set.routes {
+		route.store = store
+		route.clock = clock
+		route.limiter = set.limiter
+	}
+}
+
+// ReadRouteSetFile reads a JSON file of the form
+// `{"routes":[...],"outputLimits":{...}}`.
+func ReadRouteSetFile(filepath string) (*RouteSet, error) {
+	set := NewRouteSet()
+	bytes, err := ioutil.ReadFile(filepath)
+	if err != nil {
+		return set, err
+	}
+	file := struct {
+		Routes       []*Route               `json:"routes"`
+		OutputLimits ratelimit.OutputLimits `json:"outputLimits"`
+	}{}
+	if err := json.Unmarshal(bytes, &file); err != nil {
+		return set, err
+	}
+	if err := file.OutputLimits.Compile(); err != nil {
+		return set, err
+	}
+	set.OutputLimits = file.OutputLimits
+	for _, route := range file.Routes {
+		if err := set.Add(route); err != nil {
+			return set, err
+		}
+	}
+	return set, nil
+}
+
+// Add compiles and adds a route, replacing any route with
+// the same name.
+func (set *RouteSet) Add(route *Route) error {
+	if err := route.Compile(); err != nil {
+		return err
+	}
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	route.store = set.store
+	route.clock = set.clock
+	route.limiter = set.limiter
+	if _, ok := set.routes[route.Name]; !ok {
+		set.order = append(set.order, route.Name)
+	}
+	set.routes[route.Name] = route
+	return nil
+}
+
+// Remove deletes the route `name`. It reports whether it existed.
+func (set *RouteSet) Remove(name string) bool {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	if _, ok := set.routes[name]; !ok {
+		return false
+	}
+	delete(set.routes, name)
+	for i, try := range set.order {
+		if try == name {
+			set.order = append(set.order[:i], set.order[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// List returns the routes in the order they were added.
+func (set *RouteSet) List() []*Route {
+	set.mutex.RLock()
+	defer set.mutex.RUnlock()
+	list := []*Route{}
+	for _, name := range set.order {
+		list = append(list, set.routes[name])
+	}
+	return list
+}
+
+// Clone returns an uncompiled copy of the route for editing. Routes in
+// a set are shared by requests and must not be modified in place.
+func (route *Route) Clone() (*Route, error) {
+	bytes, err := json.Marshal(route)
+	if err != nil {
+		return nil, err
+	}
+	clone := &Route{}
+	return clone, json.Unmarshal(bytes, clone)
+}
+
+// Lookup returns the route named `name`. When `name` is empty, it
+// returns the first route listing `inputType`.
+func (set *RouteSet) Lookup(name, inputType string) (*Route, bool) {
+	if set == nil {
+		return nil, false
+	}
+	set.mutex.RLock()
+	defer set.mutex.RUnlock()
+	if len(name) > 0 {
+		route, ok := set.routes[name]
+		return route, ok
+	}
+	for _, try := range set.order {
+		for _, key := range set.routes[try].InputTypes {
+			if key == inputType {
+				return set.routes[try], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// HasDigests reports whether any route batches messages.
+func (set *RouteSet) HasDigests() bool {
+	set.mutex.RLock()
+	defer set.mutex.RUnlock()
+	for _, route := range set.routes {
+		if route.Digest != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// FlushDigests sends the digests and flapping summaries that are due.
+func (set *RouteSet) FlushDigests(send Sender) {
+	set.flushDigests(send, false)
+}
+
+// DrainDigests sends all pending digests and flapping summaries,
+// whether due or not. It is used on shutdown when their state is not
+// persisted.
+func (set *RouteSet) DrainDigests(send Sender) {
+	set.flushDigests(send, true)
+}
+
+func (set *RouteSet) flushDigests(send Sender, all bool) {
+	set.mutex.RLock()
+	scheduled := []*Route{}
+	for _, name := range set.order {
+		if route := set.routes[name]; route.Digest != nil || route.Dedup != nil {
+			scheduled = append(scheduled, route)
+		}
+	}
+	set.mutex.RUnlock()
+	for _, route := range scheduled {
+		if route.Dedup != nil {
+			route.flushFlaps(send, all)
+		}
+		if route.Digest != nil {
+			route.flushDigest(send, all)
+		}
+	}
+}
+
+// FlushOverflows sends an "N more events suppressed" summary for
+// each rate limited route that has a token again.
+func (set *RouteSet) FlushOverflows(send Sender) {
+	set.mutex.RLock()
+	limiter := set.limiter
+	set.mutex.RUnlock()
+	for _, overflow := range limiter.Overflows() {
+		hookData, ok := overflow.Pending.(models.HookData)
+		if !ok {
+			continue
+		}
+		ccMsg := cc.NewMessage()
+		ccMsg.Activity = "Rate limited"
+		ccMsg.Title = overflow.Key
+		ccMsg.Text = ratelimit.SuppressedText(overflow.Suppressed)
+		hookData.CanonicalMessage = ccMsg
+		for _, errInfo := range send(hookData) {
+			if errInfo.StatusCode >= 300 {
+				log.Warn().
+					Str("route", overflow.Key).
+					Int("http_status", errInfo.StatusCode).
+					Str("body", string(errInfo.Body)).
+					Msg("rate limit summary delivery failed")
+			}
+		}
+	}
+}
+
+// RateLimits returns the state of the route rate limits.
+func (set *RouteSet) RateLimits() []ratelimit.Status {
+	set.mutex.RLock()
+	limiter := set.limiter
+	set.mutex.RUnlock()
+	return limiter.Statuses()
+}
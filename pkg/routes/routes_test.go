@@ -0,0 +1,122 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/ratelimit"
+	"github.com/grokify/chathooks/pkg/rules"
+	"github.com/grokify/chathooks/pkg/state"
+)
+
+func TestRouteProcess(t *testing.T) {
+	set := NewRouteSet()
+	err := set.Add(&Route{
+		Name:       "pingdom",
+		InputTypes: []string{"pingdom"},
+		Filters: rules.FilterSet{Rules: []rules.FilterRule{
+			{Name: "up", When: []rules.Condition{{Path: "current_state", Value: "UP"}}, Action: rules.ActionDrop},
+			{Name: "down", When: []rules.Condition{{Path: "current_state", Value: "DOWN"}}, Action: rules.ActionRoute,
+				Outputs: []models.Output{{Type: "glip", URL: "https://example.com/oncall"}}}}}})
+	if err != nil {
+		t.Fatalf("RouteSet.Add(): want no error, got %v", err)
+	}
+
+	route, ok := set.Lookup("", "pingdom")
+	if !ok {
+		t.Fatalf("RouteSet.Lookup(pingdom): want route, got none")
+	}
+	if _, ok := set.Lookup("missing", "pingdom"); ok {
+		t.Errorf("RouteSet.Lookup(missing): want none, got route")
+	}
+
+	_, info := route.Process(models.HookData{InputBody: []byte(`{"current_state":"UP"}`)})
+	if info == nil || info.Status != models.StatusFiltered || info.StatusCode != 200 {
+		t.Errorf("Route.Process(UP): want %v, got %v", models.StatusFiltered, info)
+	}
+
+	hookData, info := route.Process(models.HookData{
+		InputBody:  []byte(`{"current_state":"DOWN"}`),
+		OutputType: "slack",
+		OutputURL:  "https://example.com/noise"})
+	if info != nil || len(hookData.Outputs) != 1 || len(hookData.OutputURL) > 0 {
+		t.Errorf("Route.Process(DOWN): want routed to 1 output, got %v outputs and %v", len(hookData.Outputs), info)
+	}
+}
+
+func TestRouteRateLimit(t *testing.T) {
+	clock := &testClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	set := NewRouteSet()
+	set.SetState(state.NewMemoryStore(clock), clock)
+	if err := set.Add(&Route{Name: "bugsnag", RateLimit: &ratelimit.Limit{Rate: 1, Per: "1m"}}); err != nil {
+		t.Fatalf("RouteSet.Add(): want no error, got %v", err)
+	}
+	route, _ := set.Lookup("bugsnag", "")
+	tests := []struct {
+		outputURL string
+		want      string
+	}{
+		{"https://example.com/team", ""},
+		{"https://example.com/team", models.StatusRateLimited},
+		{"https://example.com/oncall", models.StatusRateLimited},
+		{"https://example.com/team", models.StatusRateLimited}}
+	for i, tt := range tests {
+		_, info := route.Process(models.HookData{InputBody: []byte(`{}`), OutputURL: tt.outputURL,
+			InputHeaders: http.Header{"X-Test": []string{"1"}}, Context: context.Background()})
+		got := ""
+		if info != nil {
+			got = info.Status
+		}
+		if got != tt.want {
+			t.Errorf("Route.Process(%v): want status [%v], got [%v]", i, tt.want, got)
+		}
+	}
+
+	sent := []models.HookData{}
+	send := func(hookData models.HookData) []models.ErrorInfo {
+		sent = append(sent, hookData)
+		return nil
+	}
+	set.FlushOverflows(send)
+	if len(sent) != 0 {
+		t.Fatalf("RouteSet.FlushOverflows(0s): want 0 messages, got %v", len(sent))
+	}
+	clock.now = clock.now.Add(time.Minute)
+	set.FlushOverflows(send)
+	if len(sent) != 2 || sent[0].CanonicalMessage.Text != "1 more event suppressed" || sent[0].OutputURL != "https://example.com/oncall" ||
+		sent[1].CanonicalMessage.Text != "2 more events suppressed" || sent[1].OutputURL != "https://example.com/team" {
+		t.Fatalf("RouteSet.FlushOverflows(1m): want 1 summary per output, got %v", sent)
+	}
+	if sent[0].InputBody != nil || sent[0].InputHeaders != nil || sent[0].Context != nil {
+		t.Errorf("RouteSet.FlushOverflows(1m): want no request body, headers or context, got %v", sent[0])
+	}
+}
+
+func TestRouteRuleRateLimit(t *testing.T) {
+	clock := &testClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	set := NewRouteSet()
+	set.SetState(state.NewMemoryStore(clock), clock)
+	err := set.Add(&Route{
+		Name:      "pingdom",
+		RateLimit: &ratelimit.Limit{Rate: 1, Per: "1m"},
+		Filters: rules.FilterSet{Rules: []rules.FilterRule{
+			{Name: "down", When: []rules.Condition{{Path: "current_state", Value: "DOWN"}}, Action: rules.ActionRoute,
+				Outputs: []models.Output{{Type: "glip", URL: "https://example.com/oncall"}}}}}})
+	if err != nil {
+		t.Fatalf("RouteSet.Add(): want no error, got %v", err)
+	}
+	route, _ := set.Lookup("pingdom", "")
+	for i, want := range []string{"", models.StatusRateLimited} {
+		hookData, info := route.Process(models.HookData{InputBody: []byte(`{"current_state":"DOWN"}`)})
+		got := ""
+		if info != nil {
+			got = info.Status
+		}
+		if got != want || len(hookData.Outputs) != 1 {
+			t.Errorf("Route.Process(%v): want status [%v] and 1 output, got [%v] and %v outputs", i, want, got, len(hookData.Outputs))
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package routes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule returns the next run time after `t`.
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// ParseSchedule parses either an interval, e.g. `15m`, or a five
+// field cron expression, e.g. `0 9 * * 1-5`.
+func ParseSchedule(interval, cron string) (Schedule, error) {
+	interval = strings.TrimSpace(interval)
+	cron = strings.TrimSpace(cron)
+	if len(interval) > 0 && len(cron) > 0 {
+		return nil, fmt.Errorf("E_SCHEDULE_INTERVAL_AND_CRON")
+	} else if len(interval) > 0 {
+		dur, err := time.ParseDuration(interval)
+		if err != nil || dur < time.Minute {
+			return nil, fmt.Errorf("E_SCHEDULE_INTERVAL_INVALID [%s]", interval)
+		}
+		return intervalSchedule(dur), nil
+	} else if len(cron) > 0 {
+		return ParseCron(cron)
+	}
+	return nil, fmt.Errorf("E_SCHEDULE_MISSING")
+}
+
+type intervalSchedule time.Duration
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
+
+// CronSchedule is a standard five field cron expression: minute,
+// hour, day of month, month and day of week. Fields support `*`,
+// lists, ranges and steps. Times are in the location of the time
+// passed to `Next`.
+type CronSchedule struct {
+	minute, hour, dom, month, dow map[int]bool
+	domStar, dowStar              bool
+}
+
+var cronFieldBounds = [][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+
+func ParseCron(expr string) (*CronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("E_CRON_FIELD_COUNT [%s]", expr)
+	}
+	sets := []map[int]bool{}
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFieldBounds[i][0], cronFieldBounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("%s [%s]", err.Error(), expr)
+		}
+		sets = append(sets, set)
+	}
+	// Sunday may be written as 7.
+	if sets[4][7] {
+		sets[4][0] = true
+	}
+	return &CronSchedule{
+		minute: sets[0], hour: sets[1], dom: sets[2], month: sets[3], dow: sets[4],
+		domStar: fields[2] == "*", dowStar: fields[4] == "*"}, nil
+}
+
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	set := map[int]bool{}
+	if min == 0 && max == 6 {
+		max = 7
+	}
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return set, fmt.Errorf("E_CRON_STEP_INVALID")
+			}
+			step = n
+			part = part[:i]
+		}
+		lo, hi := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return set, fmt.Errorf("E_CRON_VALUE_INVALID")
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return set, fmt.Errorf("E_CRON_VALUE_INVALID")
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return set, fmt.Errorf("E_CRON_VALUE_OUT_OF_RANGE")
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// Next returns the first matching minute after `t`. It returns the
+// zero time if there is none within five years, e.g. `0 0 30 2 *`.
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !c.month[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		} else if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		} else if !c.hour[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		} else if !c.minute[t.Minute()] {
+			t = t.Add(time.Minute)
+		} else {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// matchDay follows cron: when both day fields are restricted, either
+// may match.
+func (c *CronSchedule) matchDay(t time.Time) bool {
+	dom := c.dom[t.Day()]
+	dow := c.dow[int(t.Weekday())]
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,188 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	cc "github.com/grokify/commonchat"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	SourceInput   = "input"   // raw input JSON
+	SourceMessage = "message" // canonical `cc.Message`
+
+	OpEq       = "eq"
+	OpNe       = "ne"
+	OpIn       = "in"
+	OpNotIn    = "notIn"
+	OpContains = "contains"
+	OpPrefix   = "prefix"
+	OpMatches  = "matches"
+	OpExists   = "exists"
+	OpMissing  = "missing"
+	OpGt       = "gt"
+	OpGte      = "gte"
+	OpLt       = "lt"
+	OpLte      = "lte"
+)
+
+// Condition tests a gjson `path` in the raw input or in the canonical
+// message, e.g. `{"path":"check.state","op":"eq","value":"down"}` or
+// `{"source":"message","path":"attachments.0.color","op":"in","value":["#f00","red"]}`.
+type Condition struct {
+	Source     string      `json:"source,omitempty"`
+	Path       string      `json:"path"`
+	Op         string      `json:"op,omitempty"`
+	Value      interface{} `json:"value,omitempty"`
+	IgnoreCase bool        `json:"ignoreCase,omitempty"`
+	regexp     *regexp.Regexp
+}
+
+// Compile validates the condition and prepares `matches` patterns.
+func (c *Condition) Compile() error {
+	if len(strings.TrimSpace(c.Path)) == 0 {
+		return fmt.Errorf("E_RULE_CONDITION_NO_PATH")
+	}
+	switch c.Source {
+	case "", SourceInput, SourceMessage:
+	default:
+		return fmt.Errorf("E_RULE_CONDITION_SOURCE_NOT_SUPPORTED [%s]", c.Source)
+	}
+	switch c.Op {
+	case "", OpEq, OpNe, OpContains, OpPrefix, OpExists, OpMissing:
+	case OpIn, OpNotIn:
+		if _, ok := c.Value.([]interface{}); !ok {
+			return fmt.Errorf("E_RULE_CONDITION_VALUE_NOT_ARRAY [%s]", c.Path)
+		}
+	case OpGt, OpGte, OpLt, OpLte:
+		if _, err := strconv.ParseFloat(valueString(c.Value), 64); err != nil {
+			return fmt.Errorf("E_RULE_CONDITION_VALUE_NOT_NUMBER [%s]", c.Path)
+		}
+	case OpMatches:
+		pattern := valueString(c.Value)
+		if c.IgnoreCase {
+			pattern = "(?i)" + pattern
+		}
+		rx, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		c.regexp = rx
+	default:
+		return fmt.Errorf("E_RULE_CONDITION_OP_NOT_SUPPORTED [%s]", c.Op)
+	}
+	return nil
+}
+
+// Match reports whether the condition holds for `doc`.
+func (c *Condition) Match(doc Document) bool {
+	result := doc.Get(c.Source, c.Path)
+	switch c.Op {
+	case OpExists:
+		return result.Exists()
+	case OpMissing:
+		return !result.Exists()
+	case OpNe:
+		return !c.equal(result.String(), valueString(c.Value))
+	case OpIn, OpNotIn:
+		found := false
+		values, _ := c.Value.([]interface{})
+		for _, try := range values {
+			if c.equal(result.String(), valueString(try)) {
+				found = true
+				break
+			}
+		}
+		return found == (c.Op == OpIn)
+	case OpContains:
+		return strings.Contains(c.fold(result.String()), c.fold(valueString(c.Value)))
+	case OpPrefix:
+		return strings.HasPrefix(c.fold(result.String()), c.fold(valueString(c.Value)))
+	case OpMatches:
+		return c.regexp != nil && result.Exists() && c.regexp.MatchString(result.String())
+	case OpGt, OpGte, OpLt, OpLte:
+		if !result.Exists() {
+			return false
+		}
+		want, err := strconv.ParseFloat(valueString(c.Value), 64)
+		if err != nil {
+			return false
+		}
+		have := result.Float()
+		switch c.Op {
+		case OpGt:
+			return have > want
+		case OpGte:
+			return have >= want
+		case OpLt:
+			return have < want
+		}
+		return have <= want
+	}
+	return result.Exists() && c.equal(result.String(), valueString(c.Value))
+}
+
+func (c *Condition) fold(s string) string {
+	if c.IgnoreCase {
+		return strings.ToLower(s)
+	}
+	return s
+}
+
+func (c *Condition) equal(a, b string) bool {
+	if c.IgnoreCase {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+func valueString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// MatchAll reports whether every condition holds. An empty
+// list always matches.
+func MatchAll(conds []Condition, doc Document) bool {
+	for i := range conds {
+		if !conds[i].Match(doc) {
+			return false
+		}
+	}
+	return true
+}
+
+// Document is the raw input and canonical message that
+// conditions are evaluated against.
+type Document struct {
+	Input   []byte
+	message []byte
+}
+
+func NewDocument(input []byte, ccMsg cc.Message) Document {
+	doc := Document{Input: input}
+	if bytes, err := json.Marshal(ccMsg); err == nil {
+		doc.message = bytes
+	}
+	return doc
+}
+
+func (doc Document) Get(source, path string) gjson.Result {
+	if source == SourceMessage {
+		return gjson.GetBytes(doc.message, path)
+	}
+	return gjson.GetBytes(doc.Input, path)
+}
@@ -0,0 +1,78 @@
+package rules
+
+import (
+	"fmt"
+
+	"github.com/grokify/chathooks/pkg/models"
+)
+
+const (
+	ActionAllow = "allow"
+	ActionDrop  = "drop"
+	ActionRoute = "route"
+)
+
+// FilterRule applies `action` when all `when` conditions match. The
+// `route` action delivers to `outputs` instead of the request outputs.
+type FilterRule struct {
+	Name    string          `json:"name,omitempty"`
+	When    []Condition     `json:"when,omitempty"`
+	Action  string          `json:"action"`
+	Outputs []models.Output `json:"outputs,omitempty"`
+}
+
+// FilterSet is an ordered list of rules. The first matching rule
+// decides. When no rule matches, `default` applies, which is `allow`
+// unless set.
+type FilterSet struct {
+	Rules   []FilterRule `json:"rules,omitempty"`
+	Default string       `json:"default,omitempty"`
+}
+
+// FilterDecision is the result of evaluating a `FilterSet`.
+type FilterDecision struct {
+	Action  string
+	Rule    string
+	Outputs []models.Output
+}
+
+func (fs *FilterSet) Compile() error {
+	switch fs.Default {
+	case "", ActionAllow, ActionDrop:
+	default:
+		return fmt.Errorf("E_FILTER_DEFAULT_NOT_SUPPORTED [%s]", fs.Default)
+	}
+	for i := range fs.Rules {
+		rule := &fs.Rules[i]
+		switch rule.Action {
+		case ActionAllow, ActionDrop:
+		case ActionRoute:
+			if len(rule.Outputs) == 0 {
+				return fmt.Errorf("E_FILTER_ROUTE_NO_OUTPUTS [%s]", rule.Name)
+			}
+		default:
+			return fmt.Errorf("E_FILTER_ACTION_NOT_SUPPORTED [%s]", rule.Action)
+		}
+		for j := range rule.When {
+			if err := rule.When[j].Compile(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (fs *FilterSet) Evaluate(doc Document) FilterDecision {
+	for _, rule := range fs.Rules {
+		if MatchAll(rule.When, doc) {
+			return FilterDecision{
+				Action:  rule.Action,
+				Rule:    rule.Name,
+				Outputs: rule.Outputs}
+		}
+	}
+	if fs.Default == ActionDrop {
+		return FilterDecision{Action: ActionDrop}
+	}
+	return FilterDecision{Action: ActionAllow}
+}
@@ -0,0 +1,73 @@
+package rules
+
+import (
+	"encoding/json"
+	"testing"
+
+	cc "github.com/grokify/commonchat"
+)
+
+const testFilterInput = `{"action":"Create","alert":{"priority":"P3","tags":["noise"],"count":4}}`
+
+const testFilterSet = `{
+  "rules": [
+    {"name":"noise","when":[{"path":"alert.tags","op":"contains","value":"noise"},{"path":"alert.priority","op":"ne","value":"P1"}],"action":"drop"},
+    {"name":"p1","when":[{"path":"alert.priority","op":"in","value":["P1","P2"]}],"action":"route","outputs":[{"type":"glip","url":"https://example.com/oncall"}]}
+  ]
+}`
+
+var FilterEvaluateTests = []struct {
+	input      string
+	title      string
+	wantAction string
+	wantRule   string
+}{
+	{testFilterInput, "", ActionDrop, "noise"},
+	{`{"alert":{"priority":"P1","tags":["noise"]}}`, "", ActionRoute, "p1"},
+	{`{"alert":{"priority":"P4"}}`, "", ActionAllow, ""}}
+
+func TestFilterEvaluate(t *testing.T) {
+	fs := FilterSet{}
+	if err := json.Unmarshal([]byte(testFilterSet), &fs); err != nil {
+		t.Fatalf("json.Unmarshal(FilterSet): want no error, got %v", err)
+	}
+	if err := fs.Compile(); err != nil {
+		t.Fatalf("FilterSet.Compile(): want no error, got %v", err)
+	}
+	for _, tt := range FilterEvaluateTests {
+		decision := fs.Evaluate(NewDocument([]byte(tt.input), cc.Message{Title: tt.title}))
+		if decision.Action != tt.wantAction || decision.Rule != tt.wantRule {
+			t.Errorf("FilterSet.Evaluate(%v): want %v/%v, got %v/%v",
+				tt.input, tt.wantAction, tt.wantRule, decision.Action, decision.Rule)
+		}
+	}
+}
+
+var ConditionMatchTests = []struct {
+	cond Condition
+	want bool
+}{
+	{Condition{Path: "action", Value: "Create"}, true},
+	{Condition{Path: "action", Value: "create"}, false},
+	{Condition{Path: "action", Value: "create", IgnoreCase: true}, true},
+	{Condition{Path: "alert.count", Op: OpGte, Value: 4.0}, true},
+	{Condition{Path: "alert.count", Op: OpLt, Value: "4"}, false},
+	{Condition{Path: "alert.missing", Op: OpMissing}, true},
+	{Condition{Path: "alert.priority", Op: OpMatches, Value: "^P[1-3]$"}, true},
+	{Condition{Path: "alert.priority", Op: OpNotIn, Value: []interface{}{"P1", "P2"}}, true},
+	{Condition{Source: SourceMessage, Path: "title", Op: OpPrefix, Value: "Alert"}, true},
+	{Condition{Source: SourceMessage, Path: "attachments.0.color", Op: OpExists}, false}}
+
+func TestConditionMatch(t *testing.T) {
+	doc := NewDocument([]byte(testFilterInput), cc.Message{Title: "Alert created"})
+	for _, tt := range ConditionMatchTests {
+		cond := tt.cond
+		if err := cond.Compile(); err != nil {
+			t.Errorf("Condition.Compile(%v): want no error, got %v", cond.Path, err)
+			continue
+		}
+		if got := cond.Match(doc); got != tt.want {
+			t.Errorf("Condition.Match(%v %v %v): want %v, got %v", cond.Path, cond.Op, cond.Value, tt.want, got)
+		}
+	}
+}
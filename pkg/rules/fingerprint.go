@@ -0,0 +1,39 @@
+package rules
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	cc "github.com/grokify/commonchat"
+)
+
+// Fingerprint joins the input values at `paths`. It returns an
+// empty string when none of the paths are present.
+func Fingerprint(paths []string, doc Document) string {
+	parts := []string{}
+	found := false
+	for _, path := range paths {
+		result := doc.Get(SourceInput, path)
+		if result.Exists() {
+			found = true
+		}
+		parts = append(parts, result.String())
+	}
+	if !found {
+		return ""
+	}
+	return strings.Join(parts, "|")
+}
+
+// MessageFingerprint hashes a canonical message. It is used when
+// neither the route nor the handler defines a fingerprint.
+func MessageFingerprint(ccMsg cc.Message) string {
+	bytes, err := json.Marshal(ccMsg)
+	if err != nil {
+		return ""
+	}
+	sum := sha1.Sum(bytes)
+	return hex.EncodeToString(sum[:])
+}
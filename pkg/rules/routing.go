@@ -0,0 +1,82 @@
+package rules
+
+import (
+	"fmt"
+
+	"github.com/grokify/chathooks/pkg/models"
+)
+
+// RoutingEntry sends to `outputs` when the severity is one of
+// `severities` and all `when` conditions match. Either may be
+// omitted.
+type RoutingEntry struct {
+	Name       string          `json:"name,omitempty"`
+	Severities []string        `json:"severities,omitempty"`
+	When       []Condition     `json:"when,omitempty"`
+	Outputs    []models.Output `json:"outputs"`
+}
+
+// RoutingTable selects outputs for a message. Outputs of every
+// matching entry are used. When no entry matches, `default` is used
+// and, if that is empty, the request outputs are kept.
+type RoutingTable struct {
+	Entries []RoutingEntry  `json:"entries,omitempty"`
+	Default []models.Output `json:"default,omitempty"`
+}
+
+func (table *RoutingTable) Compile() error {
+	for i := range table.Entries {
+		entry := &table.Entries[i]
+		if len(entry.Outputs) == 0 {
+			return fmt.Errorf("E_ROUTING_ENTRY_NO_OUTPUTS [%s]", entry.Name)
+		}
+		for _, severity := range entry.Severities {
+			if !IsSeverity(severity) {
+				return fmt.Errorf("E_SEVERITY_NOT_SUPPORTED [%s]", severity)
+			}
+		}
+		for j := range entry.When {
+			if err := entry.When[j].Compile(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (table *RoutingTable) IsEmpty() bool {
+	return len(table.Entries) == 0 && len(table.Default) == 0
+}
+
+// Outputs returns the outputs for a message with `severity`.
+func (table *RoutingTable) Outputs(severity string, doc Document) []models.Output {
+	outputs := []models.Output{}
+	seen := map[models.Output]bool{}
+	for _, entry := range table.Entries {
+		if !entry.matchSeverity(severity) || !MatchAll(entry.When, doc) {
+			continue
+		}
+		for _, output := range entry.Outputs {
+			if !seen[output] {
+				seen[output] = true
+				outputs = append(outputs, output)
+			}
+		}
+	}
+	if len(outputs) == 0 {
+		return table.Default
+	}
+	return outputs
+}
+
+func (entry *RoutingEntry) matchSeverity(severity string) bool {
+	if len(entry.Severities) == 0 {
+		return true
+	}
+	for _, try := range entry.Severities {
+		if try == severity {
+			return true
+		}
+	}
+	return false
+}
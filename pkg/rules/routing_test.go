@@ -0,0 +1,84 @@
+package rules
+
+import (
+	"encoding/json"
+	"testing"
+
+	cc "github.com/grokify/commonchat"
+	"github.com/grokify/simplego/html/htmlutil"
+)
+
+var SeverityFromColorTests = []struct {
+	color string
+	want  string
+}{
+	{htmlutil.Color2RedHex, SeverityCritical},
+	{htmlutil.Color2YellowHex, SeverityWarning},
+	{htmlutil.Color2GreenHex, SeverityOK},
+	{"#00ff00", SeverityOK},
+	{"#f60", SeverityWarning},
+	{"danger", SeverityCritical},
+	{"#0000ff", ""},
+	{"#cccccc", ""},
+	{"", ""}}
+
+func TestSeverityFromColor(t *testing.T) {
+	for _, tt := range SeverityFromColorTests {
+		if got := SeverityFromColor(tt.color); got != tt.want {
+			t.Errorf("SeverityFromColor(%v): want %v, got %v", tt.color, tt.want, got)
+		}
+	}
+}
+
+const testRoutingTable = `{
+  "entries": [
+    {"name":"oncall","severities":["critical","ok"],"outputs":[{"type":"glip","url":"https://example.com/oncall"}]},
+    {"name":"noise","severities":["warning","ok"],"outputs":[{"type":"glip","url":"https://example.com/noise"}]},
+    {"name":"prod","when":[{"path":"env","value":"prod"}],"outputs":[{"type":"glip","url":"https://example.com/oncall"}]}
+  ],
+  "default":[{"name":"glip"}]
+}`
+
+var RoutingTableTests = []struct {
+	color    string
+	input    string
+	severity []SeverityRule
+	want     []string
+}{
+	{htmlutil.Color2RedHex, `{}`, nil, []string{"https://example.com/oncall"}},
+	{htmlutil.Color2YellowHex, `{}`, nil, []string{"https://example.com/noise"}},
+	{htmlutil.Color2GreenHex, `{}`, nil, []string{"https://example.com/oncall", "https://example.com/noise"}},
+	{"", `{}`, nil, []string{""}},
+	{"", `{"env":"prod"}`, nil, []string{"https://example.com/oncall"}},
+	{"", `{"state":"DOWN"}`, []SeverityRule{{When: []Condition{{Path: "state", Value: "DOWN"}}, Severity: SeverityCritical}},
+		[]string{"https://example.com/oncall"}}}
+
+func TestRoutingTable(t *testing.T) {
+	table := RoutingTable{}
+	if err := json.Unmarshal([]byte(testRoutingTable), &table); err != nil {
+		t.Fatalf("json.Unmarshal(RoutingTable): want no error, got %v", err)
+	}
+	if err := table.Compile(); err != nil {
+		t.Fatalf("RoutingTable.Compile(): want no error, got %v", err)
+	}
+	for _, tt := range RoutingTableTests {
+		ccMsg := cc.NewMessage()
+		ccMsg.AddAttachment(cc.Attachment{Color: tt.color})
+		doc := NewDocument([]byte(tt.input), ccMsg)
+		severity := Severity(tt.severity, doc, ccMsg)
+		outputs := table.Outputs(severity, doc)
+		got := []string{}
+		for _, output := range outputs {
+			got = append(got, output.URL)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("RoutingTable.Outputs(%v,%v): want %v, got %v", severity, tt.input, tt.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("RoutingTable.Outputs(%v,%v): want %v, got %v", severity, tt.input, tt.want, got)
+			}
+		}
+	}
+}
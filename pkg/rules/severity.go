@@ -0,0 +1,138 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	cc "github.com/grokify/commonchat"
+)
+
+// Normalized severities. `ok` is used for recoveries and successes.
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+	SeverityOK       = "ok"
+)
+
+var severityRanks = map[string]int{
+	SeverityOK:       1,
+	SeverityInfo:     2,
+	SeverityWarning:  3,
+	SeverityCritical: 4}
+
+func IsSeverity(severity string) bool {
+	_, ok := severityRanks[severity]
+	return ok
+}
+
+// SeverityRule sets `severity` when all `when` conditions match.
+type SeverityRule struct {
+	When     []Condition `json:"when,omitempty"`
+	Severity string      `json:"severity"`
+}
+
+func (rule *SeverityRule) Compile() error {
+	if !IsSeverity(rule.Severity) {
+		return fmt.Errorf("E_SEVERITY_NOT_SUPPORTED [%s]", rule.Severity)
+	}
+	for i := range rule.When {
+		if err := rule.When[i].Compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Severity returns the severity of the first matching rule or, when
+// none match, the severity implied by the message colors.
+func Severity(severityRules []SeverityRule, doc Document, ccMsg cc.Message) string {
+	for _, rule := range severityRules {
+		if MatchAll(rule.When, doc) {
+			return rule.Severity
+		}
+	}
+	return SeverityFromMessage(ccMsg)
+}
+
+// SeverityFromMessage returns the highest severity of the attachment
+// colors. Handlers use red for failures, yellow for warnings and green
+// for successes and recoveries.
+func SeverityFromMessage(ccMsg cc.Message) string {
+	severity := ""
+	for _, att := range ccMsg.Attachments {
+		try := SeverityFromColor(att.Color)
+		if len(try) > 0 && (len(severity) == 0 || severityRanks[try] > severityRanks[severity]) {
+			severity = try
+		}
+	}
+	if len(severity) == 0 {
+		return SeverityInfo
+	}
+	return severity
+}
+
+// SeverityFromColor classifies a hex color, e.g. `#FF0000`, or a Slack
+// color name by hue. It returns an empty string for unknown or
+// unsaturated colors.
+func SeverityFromColor(color string) string {
+	color = strings.ToLower(strings.TrimSpace(color))
+	switch color {
+	case "":
+		return ""
+	case "danger", "red":
+		return SeverityCritical
+	case "warning", "yellow", "orange":
+		return SeverityWarning
+	case "good", "green":
+		return SeverityOK
+	}
+	color = strings.TrimPrefix(color, "#")
+	if len(color) == 3 {
+		color = string([]byte{color[0], color[0], color[1], color[1], color[2], color[2]})
+	}
+	if len(color) != 6 {
+		return ""
+	}
+	rgb, err := strconv.ParseUint(color, 16, 32)
+	if err != nil {
+		return ""
+	}
+	r := float64(rgb>>16&0xff) / 255
+	g := float64(rgb>>8&0xff) / 255
+	b := float64(rgb&0xff) / 255
+	max, min := r, r
+	for _, v := range []float64{g, b} {
+		if v > max {
+			max = v
+		}
+		if v < min {
+			min = v
+		}
+	}
+	if max == 0 || (max-min)/max < 0.3 {
+		return ""
+	}
+	var hue float64
+	switch max {
+	case r:
+		hue = 60 * (g - b) / (max - min)
+	case g:
+		hue = 60 * (2 + (b-r)/(max-min))
+	default:
+		hue = 60 * (4 + (r-g)/(max-min))
+	}
+	if hue < 0 {
+		hue += 360
+	}
+	switch {
+	case hue < 20 || hue >= 330:
+		return SeverityCritical
+	case hue < 70:
+		return SeverityWarning
+	case hue < 170:
+		return SeverityOK
+	}
+	return ""
+}
@@ -0,0 +1,156 @@
+// Package sealed encrypts hook parameters into opaque hook IDs, so
+// that hook URLs given to third parties do not reveal output URLs.
+package sealed
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/grokify/chathooks/pkg/models"
+)
+
+const (
+	// KeySize is the AES-256 key size in bytes.
+	KeySize = 32
+
+	version   byte = 1
+	keyIDSize      = 4
+)
+
+// Hook holds the parameters sealed in a hook ID. `Expires` is a Unix
+// time and zero for no expiry.
+type Hook struct {
+	InputType string          `json:"i"`
+	Outputs   []models.Output `json:"o,omitempty"`
+	Params    url.Values      `json:"p,omitempty"`
+	Route     string          `json:"r,omitempty"`
+	Token     string          `json:"t,omitempty"`
+	Expires   int64           `json:"e,omitempty"`
+}
+
+type key struct {
+	id   []byte
+	aead cipher.AEAD
+}
+
+// Keyring seals hook IDs with its first key and opens them with any
+// of its keys, so keys can be rotated by adding a new first key and
+// removing the old one once its hook IDs are no longer used.
+type Keyring struct {
+	keys []key
+}
+
+// NewKeyring returns a keyring for base64 encoded 32 byte keys.
+func NewKeyring(keys []string) (*Keyring, error) {
+	ring := &Keyring{}
+	for i, encoded := range keys {
+		encoded = strings.TrimSpace(encoded)
+		if len(encoded) == 0 {
+			continue
+		}
+		secret, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(secret) != KeySize {
+			return nil, fmt.Errorf("E_HOOK_KEY_NOT_VALID [%d]", i)
+		}
+		block, err := aes.NewCipher(secret)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(secret)
+		ring.keys = append(ring.keys, key{id: sum[:keyIDSize], aead: aead})
+	}
+	return ring, nil
+}
+
+// NewKey returns a random base64 encoded key.
+func NewKey() (string, error) {
+	secret := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(secret), nil
+}
+
+// Enabled returns whether the keyring has a key. It is nil-safe.
+func (ring *Keyring) Enabled() bool {
+	return ring != nil && len(ring.keys) > 0
+}
+
+// Seal returns the hook ID for `hook`.
+func (ring *Keyring) Seal(hook Hook) (string, error) {
+	if !ring.Enabled() {
+		return "", fmt.Errorf("E_HOOK_KEYS_NOT_CONFIGURED")
+	}
+	plaintext, err := json.Marshal(hook)
+	if err != nil {
+		return "", err
+	}
+	k := ring.keys[0]
+	header := append([]byte{version}, k.id...)
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	blob := append(append(header, nonce...), k.aead.Seal(nil, nonce, plaintext, header)...)
+	return base64.RawURLEncoding.EncodeToString(blob), nil
+}
+
+// Open returns the hook sealed in `id`. It fails for unknown keys,
+// modified IDs and hooks expired at `now`.
+func (ring *Keyring) Open(id string, now time.Time) (Hook, error) {
+	hook := Hook{}
+	if !ring.Enabled() {
+		return hook, fmt.Errorf("E_HOOK_KEYS_NOT_CONFIGURED")
+	}
+	blob, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(id))
+	headerSize := 1 + keyIDSize
+	if err != nil || len(blob) < headerSize || blob[0] != version {
+		return hook, fmt.Errorf("E_HOOK_ID_NOT_VALID")
+	}
+	header := blob[:headerSize]
+	for _, k := range ring.keys {
+		if string(k.id) != string(blob[1:headerSize]) || len(blob) < headerSize+k.aead.NonceSize() {
+			continue
+		}
+		nonce := blob[headerSize : headerSize+k.aead.NonceSize()]
+		plaintext, err := k.aead.Open(nil, nonce, blob[headerSize+len(nonce):], header)
+		if err != nil {
+			break
+		}
+		if err := json.Unmarshal(plaintext, &hook); err != nil {
+			return hook, fmt.Errorf("E_HOOK_ID_NOT_VALID")
+		}
+		if hook.Expires > 0 && now.Unix() >= hook.Expires {
+			return hook, fmt.Errorf("E_HOOK_ID_EXPIRED")
+		}
+		return hook, nil
+	}
+	return hook, fmt.Errorf("E_HOOK_ID_NOT_VALID")
+}
+
+// HookData returns the request parameters of the hook.
+func (hook Hook) HookData() models.HookData {
+	hookData := models.HookData{
+		InputType:         hook.InputType,
+		Outputs:           hook.Outputs,
+		Route:             hook.Route,
+		Token:             hook.Token,
+		CustomQueryParams: hook.Params}
+	if hookData.CustomQueryParams == nil {
+		hookData.CustomQueryParams = url.Values{}
+	}
+	return hookData
+}
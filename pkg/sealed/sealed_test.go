@@ -0,0 +1,85 @@
+package sealed
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grokify/chathooks/pkg/models"
+)
+
+const (
+	testKeyOld = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+	testKeyNew = "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+)
+
+func TestKeyringRotation(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	hook := Hook{
+		InputType: "pingdom",
+		Outputs:   []models.Output{{Type: "glip", URL: "https://hooks.glip.com/webhook/secret"}},
+		Expires:   now.Add(time.Hour).Unix()}
+
+	oldRing, _ := NewKeyring([]string{testKeyOld})
+	oldID, err := oldRing.Seal(hook)
+	if err != nil {
+		t.Fatalf("Keyring.Seal(): want no error, got %v", err)
+	}
+	if strings.Contains(oldID, "secret") || strings.ContainsAny(oldID, "+/=") {
+		t.Errorf("Keyring.Seal(): want opaque URL-safe ID, got %v", oldID)
+	}
+	rotated, _ := NewKeyring([]string{testKeyNew, testKeyOld})
+	newID, _ := rotated.Seal(hook)
+	newRing, _ := NewKeyring([]string{testKeyNew})
+
+	tampered := []byte(newID)
+	tampered[len(tampered)-3] ^= 1
+
+	tests := []struct {
+		ring    *Keyring
+		id      string
+		now     time.Time
+		wantErr string
+	}{
+		{rotated, oldID, now, ""},
+		{rotated, newID, now, ""},
+		{newRing, newID, now, ""},
+		{newRing, oldID, now, "E_HOOK_ID_NOT_VALID"},
+		{oldRing, newID, now, "E_HOOK_ID_NOT_VALID"},
+		{newRing, string(tampered), now, "E_HOOK_ID_NOT_VALID"},
+		{newRing, "not-a-hook", now, "E_HOOK_ID_NOT_VALID"},
+		{newRing, newID, now.Add(time.Hour), "E_HOOK_ID_EXPIRED"},
+		{&Keyring{}, newID, now, "E_HOOK_KEYS_NOT_CONFIGURED"}}
+
+	for i, tt := range tests {
+		got, err := tt.ring.Open(tt.id, tt.now)
+		if len(tt.wantErr) > 0 {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Keyring.Open(%v): want %v, got %v", i, tt.wantErr, err)
+			}
+		} else if err != nil || got.InputType != "pingdom" || got.Outputs[0].URL != hook.Outputs[0].URL {
+			t.Errorf("Keyring.Open(%v): want %v, got %v %v", i, hook, got, err)
+		}
+	}
+}
+
+var NewKeyringTests = []struct {
+	keys    []string
+	wantErr string
+}{
+	{[]string{testKeyNew, " "}, ""},
+	{[]string{"c2hvcnQ="}, "E_HOOK_KEY_NOT_VALID [0]"},
+	{[]string{testKeyNew, "not base64!"}, "E_HOOK_KEY_NOT_VALID [1]"}}
+
+func TestNewKeyring(t *testing.T) {
+	for _, tt := range NewKeyringTests {
+		_, err := NewKeyring(tt.keys)
+		if (len(tt.wantErr) == 0 && err != nil) || (len(tt.wantErr) > 0 && (err == nil || err.Error() != tt.wantErr)) {
+			t.Errorf("NewKeyring(%v): want %v, got %v", tt.keys, tt.wantErr, err)
+		}
+	}
+	key, err := NewKey()
+	if ring, _ := NewKeyring([]string{key}); err != nil || !ring.Enabled() {
+		t.Errorf("NewKey(): want usable key, got %v %v", key, err)
+	}
+}
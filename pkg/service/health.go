@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	clog "log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/grokify/simplego/net/anyhttp"
+	hum "github.com/grokify/simplego/net/httputilmore"
+	"github.com/rs/zerolog/log"
+	"github.com/valyala/fasthttp"
+
+	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/state"
+)
+
+const (
+	HealthPath = "/healthz"
+	ReadyPath  = "/readyz"
+
+	// DefaultMaxInFlight is the default number of hook requests in
+	// flight above which the service reports not ready.
+	DefaultMaxInFlight = 256
+)
+
+// deliveries tracks the hook requests in flight. It is shared by
+// copies of the `Service`.
+type deliveries struct {
+	mutex    sync.Mutex
+	inFlight int
+	draining bool
+	idle     chan struct{}
+	stop     chan struct{}
+}
+
+func newDeliveries() *deliveries {
+	return &deliveries{stop: make(chan struct{})}
+}
+
+// begin registers a hook request. It returns false once the service
+// is shutting down.
+func (d *deliveries) begin() bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.draining {
+		return false
+	}
+	d.inFlight++
+	return true
+}
+
+func (d *deliveries) end() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.inFlight--
+	if d.draining && d.inFlight == 0 {
+		close(d.idle)
+	}
+}
+
+// drain stops new hook requests and the scheduled sends, and returns
+// a channel that is closed when no hook requests are in flight.
+func (d *deliveries) drain() <-chan struct{} {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.draining {
+		return d.idle
+	}
+	d.draining = true
+	d.idle = make(chan struct{})
+	if d.inFlight == 0 {
+		close(d.idle)
+	}
+	close(d.stop)
+	return d.idle
+}
+
+// Readiness is served at `/readyz`. The service is ready when its
+// configuration is loaded, it is not shutting down and fewer than
+// `MaxInFlight` hook requests are in flight.
+type Readiness struct {
+	Ready        bool `json:"ready"`
+	ConfigLoaded bool `json:"configLoaded"`
+	Draining     bool `json:"draining"`
+	InFlight     int  `json:"inFlight"`
+	MaxInFlight  int  `json:"maxInFlight"`
+}
+
+// Readiness returns the current readiness.
+func (svc *Service) Readiness() Readiness {
+	rd := Readiness{MaxInFlight: DefaultMaxInFlight}
+	if rt, ok := svc.reloader.runtime.Load().(*Runtime); ok && rt != nil {
+		rd.ConfigLoaded = true
+		if rt.Config.MaxInFlight > 0 {
+			rd.MaxInFlight = rt.Config.MaxInFlight
+		}
+	}
+	d := svc.deliveries
+	d.mutex.Lock()
+	rd.Draining = d.draining
+	rd.InFlight = d.inFlight
+	d.mutex.Unlock()
+	rd.Ready = rd.ConfigLoaded && !rd.Draining && rd.InFlight < rd.MaxInFlight
+	return rd
+}
+
+// Shutdown stops accepting hook requests, waits for those in flight
+// and sends pending rate limit summaries. Digests and flapping
+// summaries that are not due are also sent unless
+// `CHATHOOKS_STATE_FILE` keeps them across restarts.
+// It returns an error if `ctx` is done first.
+func (svc *Service) Shutdown(ctx context.Context) error {
+	select {
+	case <-svc.deliveries.drain():
+	case <-ctx.Done():
+		return fmt.Errorf("E_SHUTDOWN_TIMEOUT [%d hook requests in flight]", svc.Readiness().InFlight)
+	}
+
+	flushed := make(chan struct{})
+	go func() {
+		rt := svc.Runtime()
+		if _, ok := svc.reloader.store.(*state.MemoryStore); ok {
+			rt.Routes.DrainDigests(rt.AdapterSet.SendWebhooks)
+			rt.Tenants.DrainDigests(rt.AdapterSet.SendWebhooks)
+		} else {
+			rt.Routes.FlushDigests(rt.AdapterSet.SendWebhooks)
+			rt.Tenants.FlushDigests(rt.AdapterSet.SendWebhooks)
+		}
+		flushOverflows(rt)
+		close(flushed)
+	}()
+	select {
+	case <-flushed:
+		return svc.shutdownTracing(ctx)
+	case <-ctx.Done():
+		return fmt.Errorf("E_SHUTDOWN_TIMEOUT [pending sends]")
+	}
+}
+
+// serveUntilSignal runs `serve` until it fails or SIGTERM or SIGINT is
+// received. On a signal, hook requests are refused, `shutdownServer`
+// stops the server and pending sends are flushed, all within
+// `CHATHOOKS_SHUTDOWN_TIMEOUT`.
+func (svc *Service) serveUntilSignal(serve func() error, shutdownServer func(ctx context.Context) error) {
+	errs := make(chan error, 1)
+	go func() { errs <- serve() }()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(sig)
+
+	select {
+	case err := <-errs:
+		clog.Fatal(err)
+	case s := <-sig:
+		timeout := svc.Runtime().Config.ShutdownTimeout
+		log.Info().
+			Str("signal", s.String()).
+			Dur("timeout", timeout).
+			Msg("SHUTTING_DOWN")
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		svc.deliveries.drain()
+		if err := shutdownServer(ctx); err != nil {
+			log.Error().Err(err).Msg("E_SERVER_SHUTDOWN")
+		}
+		if err := svc.Shutdown(ctx); err != nil {
+			log.Error().Err(err).Msg("E_SERVICE_SHUTDOWN")
+		}
+		log.Info().Msg("SHUTDOWN_COMPLETE")
+	}
+}
+
+// serveNetHTTP serves `handler` on `ln` until shutdown.
+func (svc *Service) serveNetHTTP(ln net.Listener, handler http.Handler) {
+	server := &http.Server{Handler: handler}
+	svc.serveUntilSignal(func() error {
+		if err := server.Serve(ln); err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	}, server.Shutdown)
+}
+
+// refuseDraining writes a 503 error and returns true once the service
+// is shutting down. Otherwise the request is counted as in flight
+// until `done` is called.
+func (svc *Service) refuseDraining(aRes anyhttp.Response) (done func(), refused bool) {
+	if !svc.deliveries.begin() {
+		aRes.SetHeader("Retry-After", "5")
+		writeError(aRes, models.ErrorInfo{StatusCode: http.StatusServiceUnavailable, Body: []byte("E_SHUTTING_DOWN")})
+		return nil, true
+	}
+	return svc.deliveries.end, false
+}
+
+// HandleHealthAnyRequest reports that the process is alive.
+func (svc *Service) HandleHealthAnyRequest(aRes anyhttp.Response, aReq anyhttp.Request) {
+	aRes.SetStatusCode(http.StatusOK)
+	aRes.SetContentType(hum.ContentTypeAppJsonUtf8)
+	aRes.SetBodyBytes([]byte(`{"status":"ok"}`))
+}
+
+// HandleReadyAnyRequest returns the `Readiness` as JSON, with status
+// 503 when not ready.
+func (svc *Service) HandleReadyAnyRequest(aRes anyhttp.Response, aReq anyhttp.Request) {
+	rd := svc.Readiness()
+	bytes, err := json.Marshal(rd)
+	if err != nil {
+		aRes.SetStatusCode(http.StatusInternalServerError)
+		return
+	}
+	aRes.SetContentType(hum.ContentTypeAppJsonUtf8)
+	if rd.Ready {
+		aRes.SetStatusCode(http.StatusOK)
+	} else {
+		aRes.SetStatusCode(http.StatusServiceUnavailable)
+	}
+	aRes.SetBodyBytes(bytes)
+}
+
+func (svc *Service) HandleHealthNetHTTP(res http.ResponseWriter, req *http.Request) {
+	svc.HandleHealthAnyRequest(anyhttp.NewResReqNetHttp(res, req))
+}
+
+func (svc *Service) HandleHealthFastHTTP(ctx *fasthttp.RequestCtx) {
+	svc.HandleHealthAnyRequest(anyhttp.NewResReqFastHttp(ctx))
+}
+
+func (svc *Service) HandleReadyNetHTTP(res http.ResponseWriter, req *http.Request) {
+	svc.HandleReadyAnyRequest(anyhttp.NewResReqNetHttp(res, req))
+}
+
+func (svc *Service) HandleReadyFastHTTP(ctx *fasthttp.RequestCtx) {
+	svc.HandleReadyAnyRequest(anyhttp.NewResReqFastHttp(ctx))
+}
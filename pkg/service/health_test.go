@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// serveBoth returns the status codes of `method path` on the
+// `net/http` and `fasthttp` routers.
+func serveBoth(svc Service, method, path string) (int, int) {
+	rec := httptest.NewRecorder()
+	svc.Router().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(`{}`)))
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(method)
+	ctx.Request.SetRequestURI(path)
+	ctx.Request.SetBodyString(`{}`)
+	svc.RouterFast().Handler(ctx)
+	return rec.Code, ctx.Response.StatusCode()
+}
+
+var healthTests = []struct {
+	step     string
+	method   string
+	path     string
+	wantCode int
+}{
+	{"start", http.MethodGet, HealthPath, http.StatusOK},
+	{"start", http.MethodGet, ReadyPath, http.StatusOK},
+	{"at capacity", http.MethodGet, HealthPath, http.StatusOK},
+	{"at capacity", http.MethodGet, ReadyPath, http.StatusServiceUnavailable},
+	{"draining", http.MethodGet, HealthPath, http.StatusOK},
+	{"draining", http.MethodGet, ReadyPath, http.StatusServiceUnavailable},
+	{"draining", http.MethodPost, "/hook?inputType=slack", http.StatusServiceUnavailable},
+	{"draining", http.MethodPost, SealedHookPath + "abc", http.StatusServiceUnavailable}}
+
+func TestHealthAndShutdown(t *testing.T) {
+	os.Setenv("CHATHOOKS_MAX_IN_FLIGHT", "1")
+	defer os.Unsetenv("CHATHOOKS_MAX_IN_FLIGHT")
+	svc := NewService(nil)
+
+	step := "start"
+	for _, tt := range healthTests {
+		if tt.step != step {
+			switch step = tt.step; step {
+			case "at capacity":
+				svc.deliveries.begin()
+			case "draining":
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+				err := svc.Shutdown(ctx)
+				cancel()
+				if err == nil || !strings.HasPrefix(err.Error(), "E_SHUTDOWN_TIMEOUT") {
+					t.Errorf("Service.Shutdown(in flight): want E_SHUTDOWN_TIMEOUT, got %v", err)
+				}
+			}
+		}
+		gotNet, gotFast := serveBoth(svc, tt.method, tt.path)
+		if gotNet != tt.wantCode || gotFast != tt.wantCode {
+			t.Errorf("%v %v (%v): want %v, got %v/%v", tt.method, tt.path, tt.step, tt.wantCode, gotNet, gotFast)
+		}
+	}
+
+	svc.deliveries.end()
+	if err := svc.Shutdown(context.Background()); err != nil {
+		t.Errorf("Service.Shutdown(idle): want no error, got %v", err)
+	}
+	if rd := svc.Readiness(); rd.Ready || !rd.Draining || rd.InFlight != 0 {
+		t.Errorf("Service.Readiness(): want draining, got %v", rd)
+	}
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"crypto/tls"
+	"net"
+	"os"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/grokify/chathooks/pkg/config"
+)
+
+// listen opens the listener for `cfg`'s listen address, wrapped in TLS
+// when a certificate is configured. A stale Unix socket file from an
+// earlier run is removed first.
+func listen(cfg config.Configuration) (net.Listener, error) {
+	tlsConfig, err := cfg.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	network, address := cfg.Listener()
+	if network == "unix" {
+		if fi, err := os.Stat(address); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			os.Remove(address)
+		}
+	}
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+	return ln, nil
+}
+
+// mustListen opens the listener for `engine` and logs where it
+// listens, exiting on error.
+func mustListen(cfg config.Configuration, engine string) net.Listener {
+	network, address := cfg.Listener()
+	ln, err := listen(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Str("address", address).Msg("E_LISTEN")
+	}
+	log.Info().
+		Str("network", network).
+		Str("address", address).
+		Bool("tls", len(cfg.TLSCertFile) > 0).
+		Bool("mtls", len(cfg.TLSClientCAFile) > 0).
+		Msg("STARTING_" + engine)
+	return ln
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grokify/chathooks/pkg/config"
+)
+
+// testCert issues a certificate signed by `parent`, or a self-signed
+// CA certificate when `parent` is nil.
+func testCert(t *testing.T, name string, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}}
+	signer, signerKey := tmpl, interface{}(key)
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+	} else {
+		signer = parent.Leaf
+		signerKey = parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := x509.ParseCertificate(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// writeCert writes `cert` and its key as PEM files.
+func writeCert(t *testing.T, cert tls.Certificate, certFile, keyFile string) {
+	keyDER, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+}
+
+func TestListen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chathooks-listen")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): want no error, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := testCert(t, "ca", nil)
+	writeCert(t, ca, filepath.Join(dir, "ca.pem"), filepath.Join(dir, "ca-key.pem"))
+	writeCert(t, testCert(t, "server", &ca), filepath.Join(dir, "server.pem"), filepath.Join(dir, "server-key.pem"))
+	client := testCert(t, "producer", &ca)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+
+	// A socket file left by an earlier run is replaced.
+	socket := filepath.Join(dir, "chathooks.sock")
+	stale, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("net.Listen(unix): want no error, got %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	unixClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		}}}
+	tlsClient := func(certs []tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs}}}
+	}
+
+	svc := NewService(nil)
+	listenTests := []struct {
+		cfg     config.Configuration
+		scheme  string
+		client  *http.Client
+		wantErr bool
+	}{
+		{config.Configuration{ListenAddress: "unix:" + socket}, "http", unixClient, false},
+		{config.Configuration{ListenAddress: "127.0.0.1:0",
+			TLSCertFile: filepath.Join(dir, "server.pem"), TLSKeyFile: filepath.Join(dir, "server-key.pem")},
+			"https", tlsClient(nil), false},
+		{config.Configuration{ListenAddress: "127.0.0.1:0",
+			TLSCertFile: filepath.Join(dir, "server.pem"), TLSKeyFile: filepath.Join(dir, "server-key.pem"),
+			TLSClientCAFile: filepath.Join(dir, "ca.pem")},
+			"https", tlsClient([]tls.Certificate{client}), false},
+		{config.Configuration{ListenAddress: "127.0.0.1:0",
+			TLSCertFile: filepath.Join(dir, "server.pem"), TLSKeyFile: filepath.Join(dir, "server-key.pem"),
+			TLSClientCAFile: filepath.Join(dir, "ca.pem")},
+			"https", tlsClient(nil), true}}
+
+	for _, engine := range []string{"nethttp", "fasthttp"} {
+		for _, tt := range listenTests {
+			ln, err := listen(tt.cfg)
+			if err != nil {
+				t.Fatalf("listen(%v): want no error, got %v", tt.cfg.ListenAddress, err)
+			}
+			if engine == "nethttp" {
+				go (&http.Server{Handler: svc.Router()}).Serve(ln)
+			} else {
+				go svc.fastHTTPServer().Serve(ln)
+			}
+			host := ln.Addr().String()
+			if strings.HasPrefix(tt.cfg.ListenAddress, config.UnixSocketPrefix) {
+				host = "chathooks"
+			}
+			res, err := tt.client.Get(tt.scheme + "://" + host + HealthPath)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("%v GET %v: want error, got %v", engine, tt.cfg.ListenAddress, res.StatusCode)
+				}
+			} else if err != nil || res.StatusCode != http.StatusOK {
+				t.Errorf("%v GET %v: want 200, got %v", engine, tt.cfg.ListenAddress, err)
+			}
+			if err == nil {
+				res.Body.Close()
+			}
+			ln.Close()
+		}
+	}
+}
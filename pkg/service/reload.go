@@ -0,0 +1,224 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/grokify/chathooks/pkg/config"
+	"github.com/grokify/chathooks/pkg/state"
+)
+
+// ReloadInterval is how often the config files are checked for
+// changes.
+const ReloadInterval = 2 * time.Second
+
+// ReloadStatus describes the current runtime and the last reload
+// attempt. It is served at `/admin/api/reload`.
+type ReloadStatus struct {
+	Loaded        time.Time  `json:"loaded"`
+	Reloads       int        `json:"reloads"`
+	Failures      int        `json:"failures"`
+	LastTrigger   string     `json:"lastTrigger,omitempty"`
+	LastError     string     `json:"lastError,omitempty"`
+	LastErrorTime *time.Time `json:"lastErrorTime,omitempty"`
+	Shadowed      []string   `json:"shadowed,omitempty"`
+	Files         []string   `json:"files"`
+}
+
+// reloader holds the current runtime. It is shared by copies of the
+// `Service`.
+type reloader struct {
+	mutex   sync.Mutex
+	runtime atomic.Value
+	store   state.Store
+	status  ReloadStatus
+	files   map[string]string // see `fileConfig`
+}
+
+func newReloader(store state.Store) *reloader {
+	return &reloader{store: store}
+}
+
+func (r *reloader) current() *Runtime {
+	return r.runtime.Load().(*Runtime)
+}
+
+func (r *reloader) swap(rt *Runtime) {
+	r.runtime.Store(rt)
+	r.status.Loaded = time.Now()
+}
+
+// Reload loads the `.env` files and the environment again and swaps in
+// a new runtime. On error, the current runtime is kept. `PORT`,
+// `CHATHOOKS_ENGINE`, the listen address and TLS files,
+// `CHATHOOKS_STATE_FILE`, `CHATHOOKS_ADMIN_FILE` and the tracing
+// settings require a restart.
+func (svc *Service) Reload(trigger string) error {
+	r := svc.reloader
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.status.LastTrigger = trigger
+
+	err := svc.reload()
+	if err != nil {
+		now := time.Now()
+		r.status.Failures++
+		r.status.LastError = err.Error()
+		r.status.LastErrorTime = &now
+		log.Error().Err(err).Str("trigger", trigger).Msg("E_CONFIG_RELOAD")
+		return err
+	}
+	r.status.Reloads++
+	r.status.LastError = ""
+	r.status.LastErrorTime = nil
+	log.Info().Str("trigger", trigger).Msg("configuration reloaded")
+	return nil
+}
+
+func (svc *Service) reload() (err error) {
+	if svc.EnvFiles != nil {
+		if err := svc.EnvFiles.Load(); err != nil {
+			return err
+		}
+		// The environment stays that of the current runtime on error.
+		defer func() {
+			if err != nil {
+				svc.EnvFiles.Restore()
+			}
+		}()
+	}
+	cfgData, err := config.NewConfigurationEnv()
+	if err != nil {
+		return err
+	}
+	rt, err := NewRuntime(cfgData, svc.reloader.store)
+	if err != nil {
+		return err
+	}
+	files := fileConfig(rt)
+	applied, err := svc.Admin.Use(cfgData.AdminToken, rt.Routes, rt.Tokens, rt.AdapterSet.Destinations)
+	if err != nil {
+		return err
+	}
+	shadowed := []string{}
+	for _, key := range []string{"routes", "tokens"} {
+		if applied && files[key] != svc.reloader.files[key] {
+			shadowed = append(shadowed, key)
+		}
+	}
+	if len(shadowed) > 0 {
+		log.Warn().
+			Strs("config", shadowed).
+			Str("file", cfgData.AdminFile).
+			Msg("E_CONFIG_SHADOWED_BY_ADMIN_FILE")
+	}
+	svc.reloader.files = files
+	svc.reloader.status.Shadowed = shadowed
+	old := svc.Runtime()
+	if cfgData.Port != old.Config.Port || cfgData.Engine != old.Config.Engine ||
+		cfgData.ListenAddress != old.Config.ListenAddress || cfgData.TLSCertFile != old.Config.TLSCertFile ||
+		cfgData.TLSKeyFile != old.Config.TLSKeyFile || cfgData.TLSClientCAFile != old.Config.TLSClientCAFile ||
+		cfgData.StateFile != old.Config.StateFile || cfgData.AdminFile != old.Config.AdminFile ||
+		cfgData.TraceExporter != old.Config.TraceExporter || cfgData.TraceEndpoint != old.Config.TraceEndpoint {
+		log.Warn().Msg("E_CONFIG_RESTART_REQUIRED")
+	}
+	svc.reloader.swap(rt)
+	// Rate limit summaries pending in the old limiters are sent now,
+	// as the new runtime starts with empty limiters.
+	flushOverflows(old)
+	return nil
+}
+
+// fileConfig returns the routes and tokens built from the files and the
+// environment, before the admin file snapshot replaces them.
+func fileConfig(rt *Runtime) map[string]string {
+	routes, _ := json.Marshal(rt.Routes.List())
+	return map[string]string{
+		"routes": string(routes),
+		"tokens": strings.Join(rt.Tokens.List(), ",")}
+}
+
+// ReloadStatus returns the reload status.
+func (svc *Service) ReloadStatus() ReloadStatus {
+	r := svc.reloader
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	status := r.status
+	status.Files = svc.watchedFiles()
+	return status
+}
+
+// watchedFiles returns the `.env` files and the routes, tenants and
+// templates files of the current runtime.
+func (svc *Service) watchedFiles() []string {
+	files := []string{}
+	if svc.EnvFiles != nil {
+		files = append(files, svc.EnvFiles.Paths...)
+	}
+	cfg := svc.Runtime().Config
+	for _, file := range []string{cfg.RoutesFile, cfg.TenantsFile, cfg.TemplatesFile} {
+		if file = strings.TrimSpace(file); len(file) > 0 {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// fileStamp identifies a version of a file. Missing files have a zero
+// stamp, so creating a file is also a change.
+type fileStamp struct {
+	ModTime time.Time
+	Size    int64
+}
+
+func (svc *Service) fileStamps() map[string]fileStamp {
+	stamps := map[string]fileStamp{}
+	for _, file := range svc.watchedFiles() {
+		if fi, err := os.Stat(file); err == nil {
+			stamps[file] = fileStamp{ModTime: fi.ModTime(), Size: fi.Size()}
+		} else {
+			stamps[file] = fileStamp{}
+		}
+	}
+	return stamps
+}
+
+// Watch reloads the configuration on SIGHUP and when a watched file
+// changes, until `stop` is closed. Files are polled every
+// `ReloadInterval`.
+func (svc *Service) Watch(stop <-chan struct{}) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	ticker := time.NewTicker(ReloadInterval)
+	defer ticker.Stop()
+
+	stamps := svc.fileStamps()
+	for {
+		select {
+		case <-hup:
+			svc.Reload("SIGHUP")
+			stamps = svc.fileStamps()
+		case <-ticker.C:
+			latest := svc.fileStamps()
+			for file, stamp := range latest {
+				if stamp != stamps[file] {
+					svc.Reload("file " + file)
+					latest = svc.fileStamps()
+					break
+				}
+			}
+			stamps = latest
+		case <-stop:
+			return
+		}
+	}
+}
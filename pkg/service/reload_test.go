@@ -0,0 +1,137 @@
+package service
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grokify/chathooks/pkg/config"
+)
+
+var ReloadTests = []struct {
+	routes    string
+	token     string
+	wantErr   bool
+	wantRoute string
+	wantToken string
+}{
+	{`{"routes":[{"name":"ops","inputTypes":["pingdom"]}]}`, "first", false, "ops", "first"},
+	{`{"routes":[{"name":""}]}`, "second", true, "ops", "first"},
+	{`{"routes":[{"name":"dev","inputTypes":["pingdom"]}]}`, "third", false, "dev", "third"}}
+
+func TestServiceReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chathooks-reload")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): want no error, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+	envPath := filepath.Join(dir, ".env")
+	routesPath := filepath.Join(dir, "routes.json")
+	defer os.Unsetenv("CHATHOOKS_ROUTES_FILE")
+	defer os.Unsetenv("CHATHOOKS_TOKENS")
+
+	var svc Service
+	envFiles := config.NewEnvFiles(envPath)
+	for i, tt := range ReloadTests {
+		ioutil.WriteFile(routesPath, []byte(tt.routes), 0600)
+		ioutil.WriteFile(envPath, []byte("CHATHOOKS_ROUTES_FILE="+routesPath+"\nCHATHOOKS_TOKENS="+tt.token+"\n"), 0600)
+		if i == 0 {
+			envFiles.Load()
+			svc = NewService(envFiles)
+		} else if err := svc.Reload("test"); (err != nil) != tt.wantErr {
+			t.Errorf("Service.Reload(%v): want error %v, got %v", tt.routes, tt.wantErr, err)
+		}
+		rt := svc.Runtime()
+		if route, ok := rt.Routes.Lookup("", "pingdom"); !ok || route.Name != tt.wantRoute {
+			t.Errorf("Service.Reload(%v): want route %v, got %v", tt.routes, tt.wantRoute, route)
+		}
+		if !rt.Tokens.Has(tt.wantToken) {
+			t.Errorf("Service.Reload(%v): want token %v, got %v", tt.routes, tt.wantToken, rt.Tokens.List())
+		}
+		if env := os.Getenv("CHATHOOKS_TOKENS"); env != tt.wantToken {
+			t.Errorf("Service.Reload(%v): want CHATHOOKS_TOKENS %v, got %v", tt.routes, tt.wantToken, env)
+		}
+	}
+
+	status := svc.ReloadStatus()
+	if status.Reloads != 1 || status.Failures != 1 || len(status.LastError) > 0 {
+		t.Errorf("Service.ReloadStatus(): want 1 reload and 1 cleared failure, got %v", status)
+	}
+}
+
+func TestServiceReloadShadowed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chathooks-reload")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): want no error, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+	routesPath := filepath.Join(dir, "routes.json")
+	adminPath := filepath.Join(dir, "admin.json")
+	ioutil.WriteFile(routesPath, []byte(`{"routes":[{"name":"ops","inputTypes":["pingdom"]}]}`), 0600)
+	ioutil.WriteFile(adminPath, []byte(`{"routes":[{"name":"admin","inputTypes":["pingdom"]}],"tokens":["admin-token"]}`), 0600)
+	env := map[string]string{
+		"CHATHOOKS_ROUTES_FILE": routesPath,
+		"CHATHOOKS_ADMIN_FILE":  adminPath,
+		"CHATHOOKS_TOKENS":      "hook-token"}
+	for key, val := range env {
+		os.Setenv(key, val)
+		defer os.Unsetenv(key)
+	}
+	svc := NewService(nil)
+
+	tests := []struct {
+		routes       string
+		wantShadowed []string
+	}{
+		{`{"routes":[{"name":"ops","inputTypes":["pingdom"]}]}`, []string{}},
+		{`{"routes":[{"name":"dev","inputTypes":["pingdom"]}]}`, []string{"routes"}}}
+	for _, tt := range tests {
+		ioutil.WriteFile(routesPath, []byte(tt.routes), 0600)
+		if err := svc.Reload("test"); err != nil {
+			t.Fatalf("Service.Reload(%v): want no error, got %v", tt.routes, err)
+		}
+		status := svc.ReloadStatus()
+		if strings.Join(status.Shadowed, ",") != strings.Join(tt.wantShadowed, ",") {
+			t.Errorf("Service.Reload(%v): want shadowed %v, got %v", tt.routes, tt.wantShadowed, status.Shadowed)
+		}
+		if route, ok := svc.Runtime().Routes.Lookup("", "pingdom"); !ok || route.Name != "admin" {
+			t.Errorf("Service.Reload(%v): want admin file route, got %v", tt.routes, route)
+		}
+	}
+}
+
+var reloadStatusTests = []struct {
+	adminToken string
+	path       string
+	bearer     string
+	wantStatus int
+}{
+	{"admin-secret", "/admin/api/reload", "admin-secret", http.StatusOK},
+	{"admin-secret", "/admin/api/reload?token=hook-token", "", http.StatusUnauthorized},
+	{"admin-secret", "/admin/api/reload", "hook-token", http.StatusUnauthorized},
+	{"", "/admin/api/reload?token=hook-token", "", http.StatusNotFound}}
+
+func TestReloadStatusAuth(t *testing.T) {
+	os.Setenv("CHATHOOKS_TOKENS", "hook-token")
+	defer os.Unsetenv("CHATHOOKS_TOKENS")
+	defer os.Unsetenv("CHATHOOKS_ADMIN_TOKEN")
+	for _, tt := range reloadStatusTests {
+		os.Setenv("CHATHOOKS_ADMIN_TOKEN", tt.adminToken)
+		svc := NewService(nil)
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if len(tt.bearer) > 0 {
+			req.Header.Set("Authorization", "Bearer "+tt.bearer)
+		}
+		rec := httptest.NewRecorder()
+		svc.Router().ServeHTTP(rec, req)
+		if rec.Code != tt.wantStatus {
+			t.Errorf("GET %v (admin %v, bearer %v): want %v, got %v", tt.path, tt.adminToken, tt.bearer, tt.wantStatus, rec.Code)
+		} else if tt.wantStatus == http.StatusOK && !strings.Contains(rec.Body.String(), `"reloads"`) {
+			t.Errorf("GET %v: want reload status, got %v", tt.path, rec.Body.String())
+		}
+	}
+}
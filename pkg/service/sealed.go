@@ -0,0 +1,182 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/grokify/simplego/net/anyhttp"
+	hum "github.com/grokify/simplego/net/httputilmore"
+	"github.com/rs/zerolog/log"
+	"github.com/valyala/fasthttp"
+
+	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/sealed"
+	"github.com/grokify/chathooks/pkg/tracing"
+)
+
+// SealedHookPath is the path prefix of sealed hook IDs. `POST` to the
+// path without an ID creates one.
+const SealedHookPath = "/hook/e/"
+
+// SealRequest is the body of a request to create a sealed hook ID.
+// `ttl` is a duration such as `720h`. Without it, the ID does not
+// expire.
+type SealRequest struct {
+	InputType string          `json:"inputType"`
+	Outputs   []models.Output `json:"outputs"`
+	Params    url.Values      `json:"params,omitempty"`
+	Route     string          `json:"route,omitempty"`
+	Token     string          `json:"token,omitempty"`
+	TTL       string          `json:"ttl,omitempty"`
+}
+
+// SealResponse is the created hook ID and its URL.
+type SealResponse struct {
+	ID      string     `json:"id"`
+	URL     string     `json:"url"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// Seal checks `req` and returns a sealed hook ID for it.
+func (rt *Runtime) Seal(req SealRequest, now time.Time) (SealResponse, *models.ErrorInfo) {
+	if !rt.HookKeys.Enabled() {
+		return SealResponse{}, &models.ErrorInfo{StatusCode: http.StatusNotFound, Body: []byte("E_HOOK_KEYS_NOT_CONFIGURED")}
+	} else if rt.RequiresToken() && !rt.ValidToken(req.Token) {
+		return SealResponse{}, &models.ErrorInfo{StatusCode: http.StatusUnauthorized, Body: []byte(ErrRequiredTokenNotValid)}
+	} else if _, ok := rt.HandlerSet.Handlers[req.InputType]; !ok {
+		return SealResponse{}, &models.ErrorInfo{StatusCode: http.StatusBadRequest, Body: []byte("E_INPUT_TYPE_NOT_FOUND [" + req.InputType + "]")}
+	} else if len(req.Outputs) == 0 {
+		return SealResponse{}, &models.ErrorInfo{StatusCode: http.StatusBadRequest, Body: []byte("E_NO_OUTPUTS")}
+	}
+	for _, output := range req.Outputs {
+		if len(output.Name) == 0 && (len(output.Type) == 0 || (len(output.URL) == 0 && len(output.Channel) == 0)) {
+			return SealResponse{}, &models.ErrorInfo{StatusCode: http.StatusBadRequest, Body: []byte("E_OUTPUT_NOT_VALID [" + output.Type + "]")}
+		}
+		if info := rt.AdapterSet.Outbound.Check(output.Type, output.URL); info != nil {
+			return SealResponse{}, info
+		}
+	}
+	hook := sealed.Hook{
+		InputType: req.InputType,
+		Outputs:   req.Outputs,
+		Params:    req.Params,
+		Route:     req.Route,
+		Token:     strings.TrimSpace(req.Token)}
+	res := SealResponse{}
+	if len(strings.TrimSpace(req.TTL)) > 0 {
+		ttl, err := time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 {
+			return res, &models.ErrorInfo{StatusCode: http.StatusBadRequest, Body: []byte("E_TTL_NOT_VALID [" + req.TTL + "]")}
+		}
+		expires := now.Add(ttl).Truncate(time.Second)
+		hook.Expires = expires.Unix()
+		res.Expires = &expires
+	}
+	id, err := rt.HookKeys.Seal(hook)
+	if err != nil {
+		return res, &models.ErrorInfo{StatusCode: http.StatusInternalServerError, Body: []byte(err.Error())}
+	}
+	res.ID = id
+	res.URL = SealedHookPath + id
+	if webhookURL := strings.TrimRight(rt.Config.WebhookUrl, "/"); len(webhookURL) > 0 {
+		res.URL = webhookURL + "/e/" + id
+	}
+	return res, nil
+}
+
+// HandleSealAnyRequest creates a sealed hook ID from a `SealRequest`.
+func (svc *Service) HandleSealAnyRequest(aRes anyhttp.Response, aReq anyhttp.Request) {
+	if string(aReq.Method()) != http.MethodPost {
+		aRes.SetStatusCode(http.StatusMethodNotAllowed)
+		return
+	}
+	req := SealRequest{}
+	body, err := aReq.PostBody()
+	if err == nil {
+		err = json.Unmarshal(body, &req)
+	}
+	if err != nil {
+		writeError(aRes, models.ErrorInfo{StatusCode: http.StatusBadRequest, Body: []byte("E_BODY_NOT_VALID")})
+		return
+	}
+	res, info := svc.Runtime().Seal(req, time.Now())
+	if info != nil {
+		writeError(aRes, *info)
+		return
+	}
+	bytes, err := json.Marshal(res)
+	if err != nil {
+		aRes.SetStatusCode(http.StatusInternalServerError)
+		return
+	}
+	aRes.SetStatusCode(http.StatusCreated)
+	aRes.SetContentType(hum.ContentTypeAppJsonUtf8)
+	aRes.SetBodyBytes(bytes)
+}
+
+// HandleSealedHookAnyRequest handles a webhook sent to a sealed hook
+// ID. Query parameters are ignored. Tokens sealed in the ID must still
+// be valid.
+func (svc *Service) HandleSealedHookAnyRequest(aRes anyhttp.Response, aReq anyhttp.Request) {
+	aRes, req, span := startServerSpan("Service.HandleSealedHookAnyRequest", aRes, aReq)
+	defer span.End()
+	done, refused := svc.refuseDraining(aRes)
+	if refused {
+		return
+	}
+	defer done()
+	rt := svc.Runtime()
+	path := string(aReq.RequestURI())
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+	hook, err := rt.HookKeys.Open(path[strings.LastIndex(path, "/")+1:], time.Now())
+	if err != nil {
+		log.Warn().Err(err).Msg("E_SEALED_HOOK")
+		status := http.StatusNotFound
+		if err.Error() == "E_HOOK_ID_EXPIRED" {
+			status = http.StatusGone
+		}
+		writeError(aRes, models.ErrorInfo{StatusCode: status, Body: []byte(err.Error())})
+		return
+	}
+	if rt.RequiresToken() && !rt.ValidToken(hook.Token) {
+		log.Warn().Msg("E_INCORRECT_TOKEN")
+		aRes.SetStatusCode(http.StatusUnauthorized)
+		return
+	}
+	handler, ok := rt.HandlerSet.Handlers[hook.InputType]
+	if !ok {
+		writeError(aRes, models.ErrorInfo{StatusCode: http.StatusNotFound, Body: []byte("E_INPUT_TYPE_NOT_FOUND [" + hook.InputType + "]")})
+		return
+	}
+	span.SetAttributes(tracing.AttrInputType.String(hook.InputType))
+	handler.HandleHookData(aRes, req, hook.HookData())
+}
+
+// writeError writes `{"error": "E_..."}` with the status of `info`.
+func writeError(aRes anyhttp.Response, info models.ErrorInfo) {
+	bytes, _ := json.Marshal(map[string]string{"error": string(info.Body)})
+	aRes.SetContentType(hum.ContentTypeAppJsonUtf8)
+	aRes.SetStatusCode(info.StatusCode)
+	aRes.SetBodyBytes(bytes)
+}
+
+func (svc *Service) HandleSealNetHTTP(res http.ResponseWriter, req *http.Request) {
+	svc.HandleSealAnyRequest(anyhttp.NewResReqNetHttp(res, req))
+}
+
+func (svc *Service) HandleSealFastHTTP(ctx *fasthttp.RequestCtx) {
+	svc.HandleSealAnyRequest(anyhttp.NewResReqFastHttp(ctx))
+}
+
+func (svc *Service) HandleSealedHookNetHTTP(res http.ResponseWriter, req *http.Request) {
+	svc.HandleSealedHookAnyRequest(anyhttp.NewResReqNetHttp(res, req))
+}
+
+func (svc *Service) HandleSealedHookFastHTTP(ctx *fasthttp.RequestCtx) {
+	svc.HandleSealedHookAnyRequest(anyhttp.NewResReqFastHttp(ctx))
+}
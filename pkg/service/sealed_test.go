@@ -0,0 +1,81 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSealedHook(t *testing.T) {
+	var posted []string
+	output := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted = append(posted, r.URL.Path)
+		w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer output.Close()
+
+	env := map[string]string{
+		"CHATHOOKS_HOOK_KEYS":            "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+		"CHATHOOKS_TOKENS":               "hook-token",
+		"CHATHOOKS_OUTPUT_HOSTS":         "discord:127.0.0.1",
+		"CHATHOOKS_OUTPUT_ALLOW_PRIVATE": "true"}
+	for key, val := range env {
+		os.Setenv(key, val)
+		defer os.Unsetenv(key)
+	}
+	svc := NewService(nil)
+	router := svc.Router()
+	svc.RouterFast()
+
+	body, err := ioutil.ReadFile("../../docs/handlers/pingdom/event-example_http-check.json")
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile(): want no error, got %v", err)
+	}
+	sealReq := `{"inputType":"pingdom","token":"hook-token","ttl":"1h","outputs":[{"type":"discord","url":"` +
+		output.URL + `/api/webhooks/1/secret"}]}`
+
+	tests := []struct {
+		sealReq  string
+		wantSeal int
+		tamper   bool
+		wantHook int
+	}{
+		{sealReq, http.StatusCreated, false, http.StatusOK},
+		{sealReq, http.StatusCreated, true, http.StatusNotFound},
+		{strings.Replace(sealReq, "hook-token", "other", 1), http.StatusUnauthorized, false, 0},
+		{strings.Replace(sealReq, "pingdom", "missing", 1), http.StatusBadRequest, false, 0},
+		{strings.Replace(sealReq, "discord", "slack", 1), http.StatusForbidden, false, 0},
+		{strings.Replace(sealReq, "1h", "soon", 1), http.StatusBadRequest, false, 0}}
+
+	for i, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/hook/e", strings.NewReader(tt.sealReq)))
+		if rec.Code != tt.wantSeal {
+			t.Errorf("POST /hook/e (%v): want %v, got %v %v", i, tt.wantSeal, rec.Code, rec.Body.String())
+			continue
+		} else if rec.Code != http.StatusCreated {
+			continue
+		}
+		res := SealResponse{}
+		json.Unmarshal(rec.Body.Bytes(), &res)
+		if !strings.HasPrefix(res.URL, SealedHookPath) || strings.Contains(res.URL, "secret") || res.Expires == nil {
+			t.Errorf("POST /hook/e (%v): want opaque URL with expiry, got %v", i, res)
+		}
+		if tt.tamper {
+			res.URL = res.URL[:len(res.URL)-2] + "xx"
+		}
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, res.URL+"?url=http://169.254.169.254/", bytes.NewReader(body)))
+		if rec.Code != tt.wantHook {
+			t.Errorf("POST %v (%v): want %v, got %v %v", res.URL, i, tt.wantHook, rec.Code, rec.Body.String())
+		}
+	}
+	if len(posted) != 1 || posted[0] != "/api/webhooks/1/secret" {
+		t.Errorf("Sealed hook outputs: want 1 post, got %v", posted)
+	}
+}
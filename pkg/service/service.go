@@ -2,15 +2,13 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	clog "log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/apex/gateway"
-	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/buaazp/fasthttprouter"
 	ccglip "github.com/grokify/commonchat/glip"
 	ccslack "github.com/grokify/commonchat/slack"
@@ -18,41 +16,28 @@ import (
 	hum "github.com/grokify/simplego/net/httputilmore"
 	"github.com/rs/zerolog/log"
 	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+	"go.opentelemetry.io/otel/codes"
+	"golang.org/x/oauth2"
 
 	"github.com/grokify/chathooks/pkg/adapters"
+	"github.com/grokify/chathooks/pkg/admin"
+	"github.com/grokify/chathooks/pkg/awslambda"
+	"github.com/grokify/chathooks/pkg/azurefunc"
 	"github.com/grokify/chathooks/pkg/config"
+	"github.com/grokify/chathooks/pkg/gcf"
 	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/ratelimit"
+	"github.com/grokify/chathooks/pkg/redact"
+	"github.com/grokify/chathooks/pkg/routes"
+	"github.com/grokify/chathooks/pkg/sealed"
+	"github.com/grokify/chathooks/pkg/state"
 	"github.com/grokify/chathooks/pkg/templates"
+	"github.com/grokify/chathooks/pkg/tenants"
+	"github.com/grokify/chathooks/pkg/tracing"
 
 	"github.com/grokify/chathooks/pkg/handlers"
-	"github.com/grokify/chathooks/pkg/handlers/aha"
-	"github.com/grokify/chathooks/pkg/handlers/appsignal"
-	"github.com/grokify/chathooks/pkg/handlers/apteligent"
-	"github.com/grokify/chathooks/pkg/handlers/bugsnag"
-	"github.com/grokify/chathooks/pkg/handlers/circleci"
-	"github.com/grokify/chathooks/pkg/handlers/codeship"
-	"github.com/grokify/chathooks/pkg/handlers/confluence"
-	"github.com/grokify/chathooks/pkg/handlers/datadog"
-	"github.com/grokify/chathooks/pkg/handlers/deskdotcom"
-	"github.com/grokify/chathooks/pkg/handlers/enchant"
-	"github.com/grokify/chathooks/pkg/handlers/gosquared"
-	"github.com/grokify/chathooks/pkg/handlers/gosquared2"
-	"github.com/grokify/chathooks/pkg/handlers/heroku"
-	"github.com/grokify/chathooks/pkg/handlers/librato"
-	"github.com/grokify/chathooks/pkg/handlers/magnumci"
-	"github.com/grokify/chathooks/pkg/handlers/marketo"
-	"github.com/grokify/chathooks/pkg/handlers/opsgenie"
-	"github.com/grokify/chathooks/pkg/handlers/papertrail"
-	"github.com/grokify/chathooks/pkg/handlers/pingdom"
-	"github.com/grokify/chathooks/pkg/handlers/raygun"
-	"github.com/grokify/chathooks/pkg/handlers/runscope"
-	"github.com/grokify/chathooks/pkg/handlers/semaphore"
-	"github.com/grokify/chathooks/pkg/handlers/slack"
-	"github.com/grokify/chathooks/pkg/handlers/statuspage"
-	"github.com/grokify/chathooks/pkg/handlers/travisci"
-	"github.com/grokify/chathooks/pkg/handlers/userlike"
-	"github.com/grokify/chathooks/pkg/handlers/victorops"
-	"github.com/grokify/chathooks/pkg/handlers/wootric"
+	_ "github.com/grokify/chathooks/pkg/handlers/all"
 )
 
 /*
@@ -70,7 +55,9 @@ const (
 	ParamNameURL             = "url"
 	ParamNameToken           = "token"
 	EnvPath                  = "ENV_PATH"
-	EnvEngine                = "CHATHOOKS_ENGINE" // awslambda, nethttp, fasthttp
+	EnvEngine                = "CHATHOOKS_ENGINE" // awslambda, nethttp, fasthttp, gcf, azurefunctions
+	EngineGCF                = "gcf"
+	EngineAzureFunctions     = "azurefunctions"
 	EnvTokens                = "CHATHOOKS_TOKENS"
 	EnvWebhookUrl            = "CHATHOOKS_URL"
 	EnvHomeUrl               = "CHATHOOKS_HOME_URL"
@@ -84,171 +71,362 @@ type HandlerSet struct {
 
 type Handler interface {
 	HandleCanonical(hookData models.HookData) []models.ErrorInfo
-	HandleAwsLambda(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
 	HandleFastHTTP(ctx *fasthttp.RequestCtx)
 	HandleNetHTTP(res http.ResponseWriter, req *http.Request)
 	HandleAnyHTTP(aRes anyhttp.Response, aReq anyhttp.Request)
+	HandleRequestAnyHTTP(aRes anyhttp.Response, req *models.Request)
+	HandleHookData(aRes anyhttp.Response, req *models.Request, hookData models.HookData)
 }
 
 type Service struct {
+	Admin      *admin.API
+	EnvFiles   *config.EnvFiles
+	reloader   *reloader
+	deliveries *deliveries
+	tracing    func(context.Context) error
+}
+
+// Runtime is the configuration built from the environment and the
+// config files. A reload builds a new runtime and swaps it in, while
+// requests in flight finish with the runtime they started with.
+type Runtime struct {
 	Config       config.Configuration
 	AdapterSet   adapters.AdapterSet
+	Routes       *routes.RouteSet
 	HandlerSet   HandlerSet
-	RequireToken bool
-	Tokens       map[string]int
+	HandlerInfos []handlers.HandlerInfo
+	Tokens       *admin.TokenSet
+	Tenants      *tenants.TenantSet
+	HookKeys     *sealed.Keyring
 }
 
 type HandlerFactory struct {
 	Config     config.Configuration
 	AdapterSet adapters.AdapterSet
+	Routes     *routes.RouteSet
+	Tenants    *tenants.TenantSet
 }
 
 func (hf *HandlerFactory) NewHandler(normalize handlers.Normalize) handlers.Handler {
 	return handlers.Handler{
 		Config:     hf.Config,
 		AdapterSet: hf.AdapterSet,
+		Routes:     hf.Routes,
+		Tenants:    hf.Tenants,
 		Normalize:  normalize}
 }
 
 func (hf *HandlerFactory) InflateHandler(handler handlers.Handler) handlers.Handler {
 	handler.Config = hf.Config
 	handler.AdapterSet = hf.AdapterSet
+	handler.Routes = hf.Routes
+	handler.Tenants = hf.Tenants
 	return handler
 }
 
-func NewService() Service {
+// NewService builds the service from the environment. `envFiles` are
+// loaded again on reload and may be nil.
+func NewService(envFiles *config.EnvFiles) Service {
 	cfgData, err := config.NewConfigurationEnv()
 	if err != nil {
 		log.Fatal().Err(err)
 	}
+	var stateStore state.Store = state.NewMemoryStore(state.SystemClock)
+	if len(strings.TrimSpace(cfgData.StateFile)) > 0 {
+		stateStore, err = state.NewFileStore(cfgData.StateFile, state.SystemClock)
+		if err != nil {
+			log.Fatal().Err(err).Str("file", cfgData.StateFile).Msg("E_STATE_FILE_READ")
+		}
+	}
 
+	rt, err := NewRuntime(cfgData, stateStore)
+	if err != nil {
+		log.Fatal().Err(err).Msg("E_CONFIG_LOAD")
+	}
+	shutdownTracing, err := tracing.Setup(traceOptions(cfgData))
+	if err != nil {
+		log.Fatal().Err(err).Msg("E_TRACING_SETUP")
+	}
+	svc := Service{
+		Admin:      &admin.API{File: cfgData.AdminFile},
+		EnvFiles:   envFiles,
+		reloader:   newReloader(stateStore),
+		deliveries: newDeliveries(),
+		tracing:    shutdownTracing}
+	svc.Admin.Statuses = map[string]func() interface{}{
+		"ratelimits": svc.rateLimitStatus,
+		"reload":     func() interface{} { return svc.ReloadStatus() },
+		"tenants":    func() interface{} { return svc.Runtime().Tenants.Statuses() }}
+	svc.reloader.files = fileConfig(rt)
+	if _, err := svc.Admin.Use(cfgData.AdminToken, rt.Routes, rt.Tokens, rt.AdapterSet.Destinations); err != nil {
+		log.Fatal().Err(err).Str("file", cfgData.AdminFile).Msg("E_ADMIN_FILE_APPLY")
+	}
+	svc.reloader.swap(rt)
+	go runScheduled(svc.Runtime, svc.deliveries.stop)
+	return svc
+}
+
+// NewRuntime builds the adapters, routes, handlers and tokens for
+// `cfgData`. Routes and adapters share `stateStore`, which outlives
+// reloads.
+func NewRuntime(cfgData config.Configuration, stateStore state.Store) (*Runtime, error) {
 	adapterSet := adapters.NewAdapterSet()
 	glipAdapter, err := ccglip.NewGlipAdapter("")
 	if err != nil {
-		log.Fatal().Err(err)
+		return nil, err
 	}
 	adapterSet.Adapters["glip"] = glipAdapter
 	slackAdapter, err := ccslack.NewSlackAdapter("")
 	if err != nil {
-		log.Fatal().Err(err)
+		return nil, err
 	}
 	adapterSet.Adapters["slack"] = slackAdapter
+	discordAdapter, err := adapters.NewDiscordAdapter("")
+	if err != nil {
+		return nil, err
+	}
+	adapterSet.Adapters["discord"] = discordAdapter
+	if len(cfgData.SlackBotToken) > 0 {
+		slackBotAdapter, err := adapters.NewSlackBotAdapter(cfgData.SlackBotToken, cfgData.SlackChannel)
+		if err != nil {
+			return nil, err
+		}
+		adapterSet.Adapters["slackbot"] = slackBotAdapter
+	}
+	if len(cfgData.TeamsClientID) > 0 || len(cfgData.TeamsToken) > 0 {
+		var tokens oauth2.TokenSource
+		if len(cfgData.TeamsClientID) > 0 {
+			tokens, err = adapters.TeamsCredentials{
+				TenantID:     cfgData.TeamsTenantID,
+				ClientID:     cfgData.TeamsClientID,
+				ClientSecret: cfgData.TeamsSecret,
+				RefreshToken: cfgData.TeamsRefresh}.TokenSource()
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			tokens = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: cfgData.TeamsToken})
+		}
+		teamsAdapter, err := adapters.NewTeamsAdapter(tokens, cfgData.TeamsChannel)
+		if err != nil {
+			return nil, err
+		}
+		adapterSet.Adapters["teams"] = teamsAdapter
+	}
+	adapterSet.Outbound, err = adapters.NewOutboundPolicy(cfgData.OutputHosts, cfgData.OutputPrivate)
+	if err != nil {
+		return nil, err
+	}
+	for _, adapter := range adapterSet.Adapters {
+		adapterSet.Outbound.Guard(adapter)
+	}
+
+	routeSet := routes.NewRouteSet()
+	if len(strings.TrimSpace(cfgData.RoutesFile)) > 0 {
+		routeSet, err = routes.ReadRouteSetFile(cfgData.RoutesFile)
+		if err != nil {
+			return nil, fmt.Errorf("E_ROUTES_FILE_READ [%s] [%s]", cfgData.RoutesFile, err.Error())
+		}
+	}
+	routeSet.SetState(stateStore, state.SystemClock)
+
+	tenantSet := tenants.NewTenantSet(state.SystemClock)
+	if len(strings.TrimSpace(cfgData.TenantsFile)) > 0 {
+		tenantSet, err = tenants.ReadTenantSetFile(cfgData.TenantsFile, stateStore, state.SystemClock)
+		if err != nil {
+			return nil, fmt.Errorf("E_TENANTS_FILE_READ [%s] [%s]", cfgData.TenantsFile, err.Error())
+		}
+	}
+
+	hookKeys, err := sealed.NewKeyring(cfgData.HookKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	adapterSet.OutputLimits = &routeSet.OutputLimits
+	adapterSet.Threads = stateStore
+
+	hf := HandlerFactory{Config: cfgData, AdapterSet: adapterSet, Routes: routeSet, Tenants: tenantSet}
 
-	hf := HandlerFactory{Config: cfgData, AdapterSet: adapterSet}
-
-	handlerSet := HandlerSet{Handlers: map[string]Handler{
-		"aha":        hf.InflateHandler(aha.NewHandler()),
-		"appsignal":  hf.InflateHandler(appsignal.NewHandler()),
-		"apteligent": hf.InflateHandler(apteligent.NewHandler()),
-		"bugsnag":    hf.InflateHandler(bugsnag.NewHandler()),
-		"circleci":   hf.InflateHandler(circleci.NewHandler()),
-		"codeship":   hf.InflateHandler(codeship.NewHandler()),
-		"confluence": hf.InflateHandler(confluence.NewHandler()),
-		"datadog":    hf.InflateHandler(datadog.NewHandler()),
-		"deskdotcom": hf.InflateHandler(deskdotcom.NewHandler()),
-		"enchant":    hf.InflateHandler(enchant.NewHandler()),
-		"gosquared":  hf.InflateHandler(gosquared.NewHandler()),
-		"gosquared2": hf.InflateHandler(gosquared2.NewHandler()),
-		"heroku":     hf.InflateHandler(heroku.NewHandler()),
-		"librato":    hf.InflateHandler(librato.NewHandler()),
-		"magnumci":   hf.InflateHandler(magnumci.NewHandler()),
-		"marketo":    hf.InflateHandler(marketo.NewHandler()),
-		"opsgenie":   hf.InflateHandler(opsgenie.NewHandler()),
-		"papertrail": hf.InflateHandler(papertrail.NewHandler()),
-		"pingdom":    hf.InflateHandler(pingdom.NewHandler()),
-		"raygun":     hf.InflateHandler(raygun.NewHandler()),
-		"runscope":   hf.InflateHandler(runscope.NewHandler()),
-		"semaphore":  hf.InflateHandler(semaphore.NewHandler()),
-		"slack":      hf.InflateHandler(slack.NewHandler()),
-		"statuspage": hf.InflateHandler(statuspage.NewHandler()),
-		"travisci":   hf.InflateHandler(travisci.NewHandler()),
-		"userlike":   hf.InflateHandler(userlike.NewHandler()),
-		"victorops":  hf.InflateHandler(victorops.NewHandler()),
-		"wootric":    hf.InflateHandler(wootric.NewHandler())}}
-
-	svcInfo := Service{
+	handlerSet := HandlerSet{Handlers: map[string]Handler{}}
+	handlerInfos := handlers.Registered()
+	for _, info := range handlerInfos {
+		handler := hf.InflateHandler(info.NewHandler())
+		handler.Fingerprint = info.Fingerprint
+		handler.Correlation = info.Correlation
+		handlerSet.Handlers[info.Key] = handler
+	}
+
+	if len(strings.TrimSpace(cfgData.TemplatesFile)) > 0 {
+		templatedInfos, err := loadTemplatedHandlers(hf, handlerSet, cfgData.TemplatesFile)
+		if err != nil {
+			return nil, err
+		}
+		handlerInfos = append(handlerInfos, templatedInfos...)
+	}
+
+	return &Runtime{
 		Config:       cfgData,
 		AdapterSet:   adapterSet,
+		Routes:       routeSet,
 		HandlerSet:   handlerSet,
-		RequireToken: false,
-		Tokens:       map[string]int{}}
+		HandlerInfos: handlerInfos,
+		Tokens:       admin.NewTokenSet(cfgData.Tokens),
+		Tenants:      tenantSet,
+		HookKeys:     hookKeys}, nil
+}
+
+// Runtime returns the current runtime. Callers should use one runtime
+// for the whole request.
+func (svc *Service) Runtime() *Runtime {
+	return svc.reloader.current()
+}
 
-	for _, token := range cfgData.Tokens {
-		token = strings.TrimSpace(token)
-		if len(token) > 0 {
-			svcInfo.Tokens[token] = 1
+// ScheduledInterval is how often digests and rate limit summaries
+// are checked.
+const ScheduledInterval = 15 * time.Second
+
+// runScheduled sends due digests and rate limit summaries for the
+// current runtime until `stop` is closed.
+func runScheduled(runtime func() *Runtime, stop <-chan struct{}) {
+	ticker := time.NewTicker(ScheduledInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			rt := runtime()
+			rt.Routes.FlushDigests(rt.AdapterSet.SendWebhooks)
+			rt.Tenants.FlushDigests(rt.AdapterSet.SendWebhooks)
+			flushOverflows(rt)
+		case <-stop:
+			return
 		}
 	}
+}
 
-	return svcInfo
+func flushOverflows(rt *Runtime) {
+	rt.Routes.FlushOverflows(rt.AdapterSet.SendWebhooks)
+	rt.Tenants.FlushOverflows(rt.AdapterSet.SendWebhooks)
+	rt.AdapterSet.FlushOverflows()
 }
 
-func (svc *Service) HandleAwsLambda(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	log.Info().Msg("FUNC_HandleAwsLambda__BEGIN")
-	if len(svc.Tokens) > 0 {
-		token, ok := req.QueryStringParameters[ParamNameToken]
-		if !ok {
-			return events.APIGatewayProxyResponse{
-				StatusCode: http.StatusUnauthorized,
-				Body:       ErrRequiredTokenNotFound}, nil
+// loadTemplatedHandlers adds the user-defined input types in `filepath`.
+// Names that are already in use are skipped.
+func loadTemplatedHandlers(hf HandlerFactory, handlerSet HandlerSet, filepath string) ([]handlers.HandlerInfo, error) {
+	infos := []handlers.HandlerInfo{}
+	defs, err := handlers.ReadTemplatedHandlerDefinitions(filepath)
+	if err != nil {
+		return infos, fmt.Errorf("E_TEMPLATES_FILE_READ [%s] [%s]", filepath, err.Error())
+	}
+	for _, def := range defs {
+		if _, ok := handlerSet.Handlers[def.Name]; ok {
+			log.Warn().Str("inputType", def.Name).Msg("E_TEMPLATED_HANDLER_NAME_IN_USE")
+			continue
 		}
-		if _, ok := svc.Tokens[token]; !ok {
-			return events.APIGatewayProxyResponse{
-				StatusCode: http.StatusUnauthorized,
-				Body:       ErrRequiredTokenNotValid}, nil
+		handler, err := def.NewHandler()
+		if err != nil {
+			return infos, fmt.Errorf("E_TEMPLATED_HANDLER_PARSE [%s] [%s]", filepath, err.Error())
 		}
+		handlerSet.Handlers[def.Name] = hf.InflateHandler(handler)
+		infos = append(infos, handlers.HandlerInfo{
+			Key:              def.Name,
+			DisplayName:      def.Name,
+			MessageDirection: "out",
+			MessageBodyType:  def.MessageBodyType})
+		log.Info().
+			Str("inputType", def.Name).
+			Str("messageBodyType", def.MessageBodyType.String()).
+			Msg("templated handler loaded")
+	}
+	return infos, nil
+}
+
+func (svc *Service) HandleAnyRequest(aRes anyhttp.Response, aReq anyhttp.Request) {
+	log.Info().Msg("FUNC_HandleAnyRequest__BEGIN")
+	aRes, req, span := startServerSpan("Service.HandleAnyRequest", aRes, aReq)
+	defer span.End()
+
+	// The form is not parsed here, as handlers read the raw body with
+	// its size limit.
+	done, refused := svc.refuseDraining(aRes)
+	if refused {
+		return
 	}
-	inputType, ok := req.QueryStringParameters[models.QueryParamInputType]
-	if !ok || len(strings.TrimSpace(inputType)) == 0 {
-		return events.APIGatewayProxyResponse{
-			StatusCode: 400,
-			Body:       "InputType not found"}, nil
+	defer done()
+	rt := svc.Runtime()
+	if !checkToken(rt, aRes, aReq, true) {
+		return
 	}
 
-	handler, ok := svc.HandlerSet.Handlers[inputType]
-	if !ok {
-		return events.APIGatewayProxyResponse{
-			StatusCode: 400,
-			Body:       fmt.Sprintf("Input Handler Not found for: %v\n", inputType)}, nil
+	inputType := aReq.QueryArgs().GetString(ParamNameInputType)
+	span.SetAttributes(tracing.AttrInputType.String(inputType))
+
+	if handler, ok := rt.HandlerSet.Handlers[inputType]; ok {
+		log.Info().
+			Str("handler_input_type", inputType).
+			Msg("Input_Handler_Found_Processing")
+		handler.HandleRequestAnyHTTP(aRes, req)
+	} else {
+		span.SetStatus(codes.Error, "Input_Handler_Not_Found")
+		fmt.Printf("Input_Handler_Not_Found [%v]\n", inputType)
 	}
+}
 
-	return handler.HandleAwsLambda(ctx, req)
+// MaxBodySize returns the largest request body limit of the
+// configuration, its routes and tenant routes.
+func (rt *Runtime) MaxBodySize() int64 {
+	max := rt.Config.MaxBodySize
+	if max <= 0 {
+		max = models.DefaultMaxBodySize
+	}
+	routeSets := []*routes.RouteSet{rt.Routes}
+	for _, tenant := range rt.Tenants.List() {
+		routeSets = append(routeSets, tenant.RouteSet())
+	}
+	for _, routeSet := range routeSets {
+		for _, route := range routeSet.List() {
+			if route.MaxBodySize > max {
+				max = route.MaxBodySize
+			}
+		}
+	}
+	return max
 }
 
-func (svc *Service) HandleAnyRequest(aRes anyhttp.Response, aReq anyhttp.Request) {
-	log.Info().Msg("FUNC_HandleAnyRequest__BEGIN")
+// RequiresToken reports whether tokens or tenants are configured.
+func (rt *Runtime) RequiresToken() bool {
+	return rt.Tokens.Len() > 0 || rt.Tenants.Len() > 0
+}
 
-	if err := aReq.ParseForm(); err != nil {
-		aRes.SetStatusCode(http.StatusInternalServerError)
-		log.Warn().Msg("E_CANNOT_PARSE_FORM")
-		return
+// ValidToken reports whether `token` is a service or tenant token.
+func (rt *Runtime) ValidToken(token string) bool {
+	token = strings.TrimSpace(token)
+	if rt.Tokens.Has(token) {
+		return true
 	}
+	_, ok := rt.Tenants.ForToken(token)
+	return ok
+}
 
-	if len(svc.Tokens) > 0 {
+// checkToken verifies the `token` query parameter when tokens or
+// tenants are configured. Tenant tokens are only accepted with
+// `allowTenants`. It sets a 401 status and returns false on failure.
+func checkToken(rt *Runtime, aRes anyhttp.Response, aReq anyhttp.Request, allowTenants bool) bool {
+	if rt.RequiresToken() {
 		token := strings.TrimSpace(aReq.QueryArgs().GetString(ParamNameToken))
 
 		if len(token) == 0 {
 			aRes.SetStatusCode(http.StatusUnauthorized)
 			log.Warn().Msg("E_NO_TOKEN")
-			return
+			return false
 		}
-		if _, ok := svc.Tokens[token]; !ok {
+		if _, ok := rt.Tenants.ForToken(token); !rt.Tokens.Has(token) && !(ok && allowTenants) {
 			aRes.SetStatusCode(http.StatusUnauthorized)
 			log.Warn().Msg("E_INCORRECT_TOKEN")
-			return
+			return false
 		}
 	}
-
-	inputType := aReq.QueryArgs().GetString(ParamNameInputType)
-
-	if handler, ok := svc.HandlerSet.Handlers[inputType]; ok {
-		log.Info().
-			Str("handler_input_type", inputType).
-			Msg("Input_Handler_Found_Processing")
-		handler.HandleAnyHTTP(aRes, aReq)
-	} else {
-		fmt.Printf("Input_Handler_Not_Found [%v]\n", inputType)
-	}
+	return true
 }
 
 func (svc *Service) HandleHookNetHTTP(res http.ResponseWriter, req *http.Request) {
@@ -263,10 +441,11 @@ func (svc *Service) HandleHookFastHTTP(ctx *fasthttp.RequestCtx) {
 
 func (svc *Service) HandleHomeAnyRequest(aRes anyhttp.Response, aReq anyhttp.Request) {
 	log.Info().Msg("HANDLE_HOME_AnyHTTP")
-	fmt.Println(svc.Config.WebhookUrl)
+	cfg := svc.Runtime().Config
+	fmt.Println(cfg.WebhookUrl)
 	data := templates.HomeData{
-		HomeUrl:    svc.Config.HomeUrl,
-		WebhookUrl: svc.Config.WebhookUrl}
+		HomeUrl:    cfg.HomeUrl,
+		WebhookUrl: cfg.WebhookUrl}
 	if _, err := aRes.SetBodyBytes([]byte(templates.HomePage(data))); err != nil {
 		aRes.SetStatusCode(http.StatusInternalServerError)
 	} else {
@@ -275,6 +454,81 @@ func (svc *Service) HandleHomeAnyRequest(aRes anyhttp.Response, aReq anyhttp.Req
 	}
 }
 
+// HandleHandlersAnyRequest returns the handler catalog as JSON.
+func (svc *Service) HandleHandlersAnyRequest(aRes anyhttp.Response, aReq anyhttp.Request) {
+	bytes, err := json.Marshal(svc.Runtime().HandlerInfos)
+	if err != nil {
+		aRes.SetStatusCode(http.StatusInternalServerError)
+		return
+	}
+	aRes.SetStatusCode(http.StatusOK)
+	aRes.SetContentType(hum.ContentTypeAppJsonUtf8)
+	aRes.SetBodyBytes(bytes)
+}
+
+func (svc *Service) HandleHandlersNetHTTP(res http.ResponseWriter, req *http.Request) {
+	svc.HandleHandlersAnyRequest(anyhttp.NewResReqNetHttp(res, req))
+}
+
+func (svc *Service) HandleHandlersFastHTTP(ctx *fasthttp.RequestCtx) {
+	svc.HandleHandlersAnyRequest(anyhttp.NewResReqFastHttp(ctx))
+}
+
+// rateLimitStatus returns the route and output rate limit state,
+// with output URLs masked. It is served at `/admin/api/ratelimits`.
+func (svc Service) rateLimitStatus() interface{} {
+	rt := svc.Runtime()
+	outputs := []ratelimit.Status{}
+	if rt.AdapterSet.Limiter != nil {
+		outputs = rt.AdapterSet.Limiter.Statuses()
+	}
+	for i := range outputs {
+		outputs[i].Key = redact.URL(outputs[i].Key)
+	}
+	return map[string][]ratelimit.Status{
+		"routes":  rt.Routes.RateLimits(),
+		"outputs": outputs}
+}
+
+// HandleTenantsAnyRequest returns the status and metrics of the
+// tenant of the `token` query parameter as JSON. All tenants are
+// served at `/admin/api/tenants`.
+func (svc *Service) HandleTenantsAnyRequest(aRes anyhttp.Response, aReq anyhttp.Request) {
+	if err := aReq.ParseForm(); err != nil {
+		aRes.SetStatusCode(http.StatusBadRequest)
+		return
+	}
+	rt := svc.Runtime()
+	tenant, ok := rt.Tenants.ForToken(aReq.QueryArgs().GetString(ParamNameToken))
+	if !ok {
+		aRes.SetStatusCode(http.StatusUnauthorized)
+		log.Warn().Msg("E_INCORRECT_TOKEN")
+		return
+	}
+	statuses := []tenants.Status{}
+	for _, status := range rt.Tenants.Statuses() {
+		if status.ID == tenant.ID {
+			statuses = append(statuses, status)
+		}
+	}
+	bytes, err := json.Marshal(statuses)
+	if err != nil {
+		aRes.SetStatusCode(http.StatusInternalServerError)
+		return
+	}
+	aRes.SetStatusCode(http.StatusOK)
+	aRes.SetContentType(hum.ContentTypeAppJsonUtf8)
+	aRes.SetBodyBytes(bytes)
+}
+
+func (svc *Service) HandleTenantsNetHTTP(res http.ResponseWriter, req *http.Request) {
+	svc.HandleTenantsAnyRequest(anyhttp.NewResReqNetHttp(res, req))
+}
+
+func (svc *Service) HandleTenantsFastHTTP(ctx *fasthttp.RequestCtx) {
+	svc.HandleTenantsAnyRequest(anyhttp.NewResReqFastHttp(ctx))
+}
+
 func (svc *Service) HandleHomeNetHTTP(res http.ResponseWriter, req *http.Request) {
 	log.Debug().Msg("HANDLE_NetHTTP")
 	svc.HandleHomeAnyRequest(anyhttp.NewResReqNetHttp(res, req))
@@ -286,11 +540,11 @@ func (svc *Service) HandleHomeFastHTTP(ctx *fasthttp.RequestCtx) {
 }
 
 func (svc Service) PortInt() int {
-	return svc.Config.Port
+	return svc.Runtime().Config.Port
 }
 
 func (svc Service) HttpEngine() string {
-	return svc.Config.Engine
+	return svc.Runtime().Config.Engine
 }
 
 func (svc Service) Router() http.Handler {
@@ -300,41 +554,121 @@ func (svc Service) Router() http.Handler {
 func (svc Service) RouterFast() *fasthttprouter.Router {
 	router := fasthttprouter.New()
 	router.GET("/", svc.HandleHomeFastHTTP)
+	router.GET(HealthPath, svc.HandleHealthFastHTTP)
+	router.GET(ReadyPath, svc.HandleReadyFastHTTP)
+	router.GET("/handlers", svc.HandleHandlersFastHTTP)
+	router.GET("/admin/tenants", svc.HandleTenantsFastHTTP)
 	router.POST("/hook", svc.HandleHookFastHTTP)
 	router.POST("/hook/", svc.HandleHookFastHTTP)
+	router.POST(strings.TrimSuffix(SealedHookPath, "/"), svc.HandleSealFastHTTP)
+	router.POST(SealedHookPath+":id", svc.HandleSealedHookFastHTTP)
 	router.POST("/webhook", svc.HandleHookFastHTTP)
 	router.POST("/webhook/", svc.HandleHookFastHTTP)
+	adminHandler := fasthttpadaptor.NewFastHTTPHandler(svc.Admin)
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete} {
+		router.Handle(method, admin.BasePath+"/*path", adminHandler)
+	}
 	return router
 }
 
 func getHttpServeMux(svc Service) *http.ServeMux {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", http.HandlerFunc(svc.HandleHomeNetHTTP))
+	mux.HandleFunc(HealthPath, http.HandlerFunc(svc.HandleHealthNetHTTP))
+	mux.HandleFunc(ReadyPath, http.HandlerFunc(svc.HandleReadyNetHTTP))
+	mux.HandleFunc("/handlers", http.HandlerFunc(svc.HandleHandlersNetHTTP))
+	mux.HandleFunc("/admin/tenants", http.HandlerFunc(svc.HandleTenantsNetHTTP))
 	mux.HandleFunc("/hook", http.HandlerFunc(svc.HandleHookNetHTTP))
 	mux.HandleFunc("/hook/", http.HandlerFunc(svc.HandleHookNetHTTP))
+	mux.HandleFunc(strings.TrimSuffix(SealedHookPath, "/"), http.HandlerFunc(svc.HandleSealNetHTTP))
+	mux.HandleFunc(SealedHookPath, http.HandlerFunc(svc.HandleSealedHookNetHTTP))
 	mux.HandleFunc("/webhook", http.HandlerFunc(svc.HandleHookNetHTTP))
 	mux.HandleFunc("/webhook/", http.HandlerFunc(svc.HandleHookNetHTTP))
+	mux.Handle(admin.BasePath+"/", svc.Admin)
 	return mux
 }
 
+// ServeNetHttp serves the `net/http` router on the listen address,
+// with TLS when configured.
 func ServeNetHttp(svc Service) {
-	log.Info().
-		Int("port", svc.Config.Port).
-		Msg("STARTING_NET_HTTP")
-	http.ListenAndServe(portAddress(svc.Config.Port), getHttpServeMux(svc))
+	ln := mustListen(svc.Runtime().Config, "NET_HTTP")
+	svc.serveNetHTTP(ln, getHttpServeMux(svc))
 }
 
+// ServeFastHttp serves the `fasthttp` router on the listen address,
+// with TLS when configured.
 func ServeFastHttp(svc Service) {
-	log.Info().
-		Int("port", svc.Config.Port).
-		Msg("STARTING_FAST_HTTP")
-	router := svc.RouterFast()
-	clog.Fatal(fasthttp.ListenAndServe(portAddress(svc.Config.Port), router.Handler))
+	ln := mustListen(svc.Runtime().Config, "FAST_HTTP")
+	server := svc.fastHTTPServer()
+	svc.serveUntilSignal(func() error {
+		return server.Serve(ln)
+	}, func(ctx context.Context) error {
+		done := make(chan error, 1)
+		go func() { done <- server.Shutdown() }()
+		select {
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// fastHTTPServer returns the `fasthttp` server. Its body limit is the
+// largest configured limit, and at least the `fasthttp` default, as
+// it is fixed until a restart.
+func (svc Service) fastHTTPServer() *fasthttp.Server {
+	limit := svc.Runtime().MaxBodySize()
+	if limit < fasthttp.DefaultMaxRequestBodySize {
+		limit = fasthttp.DefaultMaxRequestBodySize
+	}
+	return &fasthttp.Server{Handler: svc.RouterFast().Handler, MaxRequestBodySize: int(limit)}
 }
 
+// ServeAwsLambda serves API Gateway v1 and v2, Function URL and ALB
+// events on AWS Lambda.
 func ServeAwsLambda(svc Service) {
-	clog.Fatal(gateway.ListenAndServe(portAddress(svc.Config.Port), getHttpServeMux(svc)))
+	awslambda.Start(getHttpServeMux(svc))
+}
+
+// ServeGCF serves the `net/http` router as a Google Cloud Functions
+// HTTP function, stripping `CHATHOOKS_PATH_PREFIX` or else the function
+// name from request paths.
+func ServeGCF(svc Service) {
+	cfg := svc.Runtime().Config
+	prefix := cfg.PathPrefix
+	if len(prefix) == 0 {
+		prefix = gcf.PathPrefix()
+	}
+	log.Info().
+		Str("prefix", prefix).
+		Msg("GCF_PATH_PREFIX")
+	ln := mustListen(platformConfig(cfg, portAddress(cfg.Port)), "GCF")
+	svc.serveNetHTTP(ln, gcf.NewHandler(getHttpServeMux(svc), prefix))
+}
+
+// ServeAzureFunctions serves the `net/http` router as an Azure Functions
+// custom handler, stripping `CHATHOOKS_PATH_PREFIX` or else the default
+// `/api` route prefix from request paths.
+func ServeAzureFunctions(svc Service) {
+	cfg := svc.Runtime().Config
+	prefix := cfg.PathPrefix
+	if len(prefix) == 0 {
+		prefix = azurefunc.DefaultRoutePrefix
+	}
+	log.Info().
+		Str("prefix", prefix).
+		Msg("AZURE_FUNCTIONS_ROUTE_PREFIX")
+	ln := mustListen(platformConfig(cfg, azurefunc.Address(cfg.Port)), "AZURE_FUNCTIONS")
+	svc.serveNetHTTP(ln, azurefunc.NewHandler(getHttpServeMux(svc), prefix))
+}
+
+// platformConfig returns `cfg` listening on the address required by a
+// functions platform, which also terminates TLS.
+func platformConfig(cfg config.Configuration, address string) config.Configuration {
+	cfg.ListenAddress = address
+	cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSClientCAFile = "", "", ""
+	return cfg
 }
 
-func serveAwsLambdaSimple(svc Service) { lambda.Start(svc.HandleAwsLambda) }
-func portAddress(port int) string      { return ":" + strconv.Itoa(port) }
+func portAddress(port int) string { return ":" + strconv.Itoa(port) }
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+const testTenantsFile = `{"tenants":[
+  {"id":"payments","tokens":["pay-token"],"outputs":["https://hooks.slack.com/services/T1/secret"]},
+  {"id":"search","tokens":["search-token"]}]}`
+
+var tenantsStatusTests = []struct {
+	path       string
+	bearer     string
+	wantStatus int
+	wantBody   string
+	noBody     string
+}{
+	{"/admin/tenants?token=pay-token", "", http.StatusOK, `"id":"payments"`, `"search"`},
+	{"/admin/tenants?token=hook-token", "", http.StatusUnauthorized, "", ""},
+	{"/admin/tenants", "admin-secret", http.StatusUnauthorized, "", ""},
+	{"/admin/api/tenants?token=pay-token", "", http.StatusUnauthorized, "", ""},
+	{"/admin/api/tenants", "admin-secret", http.StatusOK, `"id":"search"`, "secret"}}
+
+func TestTenantsStatusAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chathooks-tenants")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): want no error, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+	tenantsPath := filepath.Join(dir, "tenants.json")
+	ioutil.WriteFile(tenantsPath, []byte(testTenantsFile), 0600)
+
+	env := map[string]string{
+		"CHATHOOKS_TENANTS_FILE": tenantsPath,
+		"CHATHOOKS_TOKENS":       "hook-token",
+		"CHATHOOKS_ADMIN_TOKEN":  "admin-secret"}
+	for key, val := range env {
+		os.Setenv(key, val)
+		defer os.Unsetenv(key)
+	}
+	svc := NewService(nil)
+
+	for _, tt := range tenantsStatusTests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if len(tt.bearer) > 0 {
+			req.Header.Set("Authorization", "Bearer "+tt.bearer)
+		}
+		rec := httptest.NewRecorder()
+		svc.Router().ServeHTTP(rec, req)
+		body := rec.Body.String()
+		if rec.Code != tt.wantStatus || !strings.Contains(body, tt.wantBody) ||
+			(len(tt.noBody) > 0 && strings.Contains(body, tt.noBody)) {
+			t.Errorf("GET %v (bearer %v): want %v %v without [%v], got %v %v",
+				tt.path, tt.bearer, tt.wantStatus, tt.wantBody, tt.noBody, rec.Code, body)
+		}
+	}
+}
+
+var fastHTTPServerTests = []struct {
+	maxBodySize string
+	routes      string
+	wantLimit   int
+}{
+	{"", `{"routes":[{"name":"ops","inputTypes":["pingdom"]}]}`, fasthttp.DefaultMaxRequestBodySize},
+	{"8388608", `{"routes":[{"name":"ops","inputTypes":["pingdom"]}]}`, 8 << 20},
+	{"", `{"routes":[{"name":"ops","inputTypes":["pingdom"],"maxBodySize":16777216}]}`, 16 << 20}}
+
+func TestFastHTTPServerMaxBodySize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chathooks-fasthttp")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): want no error, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+	routesPath := filepath.Join(dir, "routes.json")
+	os.Setenv("CHATHOOKS_ROUTES_FILE", routesPath)
+	defer os.Unsetenv("CHATHOOKS_ROUTES_FILE")
+	defer os.Unsetenv("CHATHOOKS_MAX_BODY_SIZE")
+
+	for _, tt := range fastHTTPServerTests {
+		ioutil.WriteFile(routesPath, []byte(tt.routes), 0600)
+		os.Setenv("CHATHOOKS_MAX_BODY_SIZE", tt.maxBodySize)
+		svc := NewService(nil)
+		if limit := svc.fastHTTPServer().MaxRequestBodySize; limit != tt.wantLimit {
+			t.Errorf("Service.fastHTTPServer(%v, %v): want MaxRequestBodySize %v, got %v",
+				tt.maxBodySize, tt.routes, tt.wantLimit, limit)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/grokify/simplego/net/anyhttp"
+	"github.com/grokify/simplego/net/http/httpsimple"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/grokify/chathooks/pkg/config"
+	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/tracing"
+)
+
+// traceOptions returns the tracing options of `cfg`. Serverless
+// engines export spans as they end, as the process may be frozen
+// between invocations.
+func traceOptions(cfg config.Configuration) tracing.Options {
+	opts := tracing.Options{
+		Exporter:    cfg.TraceExporter,
+		Endpoint:    cfg.TraceEndpoint,
+		Headers:     cfg.TraceHeaders,
+		ServiceName: cfg.TraceService}
+	switch strings.ToLower(strings.TrimSpace(cfg.Engine)) {
+	case httpsimple.EngineAwsLambda, EngineGCF, EngineAzureFunctions:
+		opts.Sync = true
+	}
+	return opts
+}
+
+// shutdownTracing flushes and stops the span exporter.
+func (svc *Service) shutdownTracing(ctx context.Context) error {
+	if svc.tracing == nil {
+		return nil
+	}
+	return svc.tracing(ctx)
+}
+
+// startServerSpan maps `aReq` and starts its server span, continuing
+// the trace of its `traceparent` header. The returned response records
+// its status code on the span.
+func startServerSpan(name string, aRes anyhttp.Response, aReq anyhttp.Request) (anyhttp.Response, *models.Request, trace.Span) {
+	req := models.NewRequestAnyHTTP(aReq)
+	ctx, span := tracing.Start(tracing.Extract(req.Context, req.Headers), name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			tracing.AttrMethod.String(req.Method),
+			tracing.AttrPath.String(req.Path)))
+	req.Context = ctx
+	return tracedResponse{Response: aRes, span: span}, req, span
+}
+
+// tracedResponse records the status code of a response on its server
+// span.
+type tracedResponse struct {
+	anyhttp.Response
+	span trace.Span
+}
+
+func (res tracedResponse) SetStatusCode(statusCode int) {
+	tracing.SetStatusCode(res.span, statusCode, 500)
+	res.Response.SetStatusCode(statusCode)
+}
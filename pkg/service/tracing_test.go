@@ -0,0 +1,119 @@
+package service
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/grokify/chathooks/pkg/tracing"
+)
+
+const (
+	testTraceID    = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testParentSpan = "00f067aa0ba902b7"
+)
+
+func TestTracing(t *testing.T) {
+	var outbound []string
+	output := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outbound = append(outbound, r.Header.Get("traceparent"))
+	}))
+	defer output.Close()
+
+	env := map[string]string{
+		"CHATHOOKS_OUTPUT_HOSTS":         "discord:127.0.0.1",
+		"CHATHOOKS_OUTPUT_ALLOW_PRIVATE": "true"}
+	for key, val := range env {
+		os.Setenv(key, val)
+		defer os.Unsetenv(key)
+	}
+	svc := NewService(nil)
+	exporter := tracetest.NewInMemoryExporter()
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	body, err := ioutil.ReadFile("../../docs/handlers/pingdom/event-example_http-check.json")
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile(): want no error, got %v", err)
+	}
+	path := "/hook?inputType=pingdom&outputType=discord&url=" + output.URL + "/api/webhooks/1/secret"
+	traceparent := "00-" + testTraceID + "-" + testParentSpan + "-01"
+
+	for _, engine := range []string{"nethttp", "fasthttp"} {
+		exporter.Reset()
+		outbound = nil
+		status := 0
+		if engine == "nethttp" {
+			req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+			req.Header.Set("traceparent", traceparent)
+			rec := httptest.NewRecorder()
+			svc.Router().ServeHTTP(rec, req)
+			status = rec.Code
+		} else {
+			ctx := &fasthttp.RequestCtx{}
+			ctx.Request.Header.SetMethod(http.MethodPost)
+			ctx.Request.SetRequestURI(path)
+			ctx.Request.Header.Set("traceparent", traceparent)
+			ctx.Request.SetBody(body)
+			svc.RouterFast().Handler(ctx)
+			status = ctx.Response.StatusCode()
+		}
+		if status != http.StatusOK {
+			t.Errorf("%v POST /hook: want 200, got %v", engine, status)
+		}
+
+		spans := map[string]tracetest.SpanStub{}
+		for _, span := range exporter.GetSpans() {
+			spans[span.Name] = span
+		}
+		// Each span is a child of the one before it, starting with
+		// the incoming `traceparent`.
+		parent := testParentSpan
+		for _, name := range []string{"Service.HandleAnyRequest", "Handler.HandleCanonical", "AdapterSet.SendWebhooks", "send discord"} {
+			span, ok := spans[name]
+			if !ok {
+				t.Errorf("%v span %v: want recorded, got %v", engine, name, exporter.GetSpans().Snapshots())
+				break
+			}
+			if span.SpanContext.TraceID().String() != testTraceID || span.Parent.SpanID().String() != parent {
+				t.Errorf("%v span %v: want child of %v in %v, got %v in %v", engine, name,
+					parent, testTraceID, span.Parent.SpanID(), span.SpanContext.TraceID())
+			}
+			parent = span.SpanContext.SpanID().String()
+		}
+
+		attrs := map[string]string{}
+		for _, name := range []string{"Service.HandleAnyRequest", "send discord"} {
+			for _, attr := range spans[name].Attributes {
+				attrs[name+" "+string(attr.Key)] = attr.Value.Emit()
+			}
+		}
+		wantAttrs := map[string]string{
+			"Service.HandleAnyRequest " + string(tracing.AttrInputType):  "pingdom",
+			"Service.HandleAnyRequest " + string(tracing.AttrStatusCode): "200",
+			"send discord " + string(tracing.AttrOutputType):             "discord",
+			"send discord " + string(tracing.AttrStatusCode):             "200"}
+		for key, want := range wantAttrs {
+			if attrs[key] != want {
+				t.Errorf("%v span attribute %v: want %v, got %v", engine, key, want, attrs[key])
+			}
+		}
+		if spans["send discord"].SpanKind != trace.SpanKindClient || spans["Service.HandleAnyRequest"].SpanKind != trace.SpanKindServer {
+			t.Errorf("%v span kinds: want server and client, got %v", engine, spans)
+		}
+
+		wantOutbound := "00-" + testTraceID + "-" + parent + "-01"
+		if len(outbound) != 1 || outbound[0] != wantOutbound {
+			t.Errorf("%v outbound traceparent: want %v, got %v", engine, wantOutbound, outbound)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package state
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileStore is a `MemoryStore` that is saved to a JSON file on each
+// change so state survives restarts. It suits a single instance.
+type FileStore struct {
+	*MemoryStore
+	path string
+}
+
+// NewFileStore loads `path` if it exists.
+func NewFileStore(path string, clock Clock) (*FileStore, error) {
+	store := &FileStore{MemoryStore: NewMemoryStore(clock), path: path}
+	bytes, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	} else if err != nil {
+		return store, err
+	}
+	if len(bytes) > 0 {
+		if err := json.Unmarshal(bytes, &store.items); err != nil {
+			return store, err
+		}
+	}
+	store.prune()
+	return store, nil
+}
+
+func (store *FileStore) Set(key string, value []byte, ttl time.Duration) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.set(key, value, ttl)
+	return store.save()
+}
+
+func (store *FileStore) Delete(key string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	delete(store.items, key)
+	return store.save()
+}
+
+// save writes the items to `path`. The caller must hold the lock.
+func (store *FileStore) save() error {
+	store.prune()
+	bytes, err := json.Marshal(store.items)
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(store.path, bytes)
+}
+
+// WriteFileAtomic writes to a temporary file and renames it over
+// `path` so readers never see a partial file. The file is only
+// readable by the owner.
+func WriteFileAtomic(path string, bytes []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(bytes); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
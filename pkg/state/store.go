@@ -0,0 +1,94 @@
+package state
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock returns the current time. Use a fixed clock in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// SystemClock is the wall clock.
+var SystemClock Clock = systemClock{}
+
+// Store holds state shared between requests, e.g. dedup windows.
+// Values expire after their TTL. A zero TTL does not expire.
+type Store interface {
+	Get(key string) ([]byte, bool, error)
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(key string) error
+}
+
+type item struct {
+	Value   []byte    `json:"value"`
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+func (it item) expired(now time.Time) bool {
+	return !it.Expires.IsZero() && !now.Before(it.Expires)
+}
+
+// MemoryStore is an in-memory `Store`.
+type MemoryStore struct {
+	mutex sync.Mutex
+	clock Clock
+	items map[string]item
+}
+
+func NewMemoryStore(clock Clock) *MemoryStore {
+	if clock == nil {
+		clock = SystemClock
+	}
+	return &MemoryStore{clock: clock, items: map[string]item{}}
+}
+
+func (store *MemoryStore) Get(key string) ([]byte, bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	it, ok := store.items[key]
+	if !ok {
+		return nil, false, nil
+	} else if it.expired(store.clock.Now()) {
+		delete(store.items, key)
+		return nil, false, nil
+	}
+	return it.Value, true, nil
+}
+
+func (store *MemoryStore) Set(key string, value []byte, ttl time.Duration) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.set(key, value, ttl)
+	return nil
+}
+
+func (store *MemoryStore) set(key string, value []byte, ttl time.Duration) {
+	it := item{Value: value}
+	if ttl > 0 {
+		it.Expires = store.clock.Now().Add(ttl)
+	}
+	store.items[key] = it
+}
+
+func (store *MemoryStore) Delete(key string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	delete(store.items, key)
+	return nil
+}
+
+// prune removes expired items. The caller must hold the lock.
+func (store *MemoryStore) prune() {
+	now := store.clock.Now()
+	for key, it := range store.items {
+		if it.expired(now) {
+			delete(store.items, key)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testClock struct{ now time.Time }
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func TestFileStore(t *testing.T) {
+	clock := &testClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	store, err := NewFileStore(path, clock)
+	if err != nil {
+		t.Fatalf("NewFileStore(%v): want no error, got %v", path, err)
+	}
+	if err := store.Set("short", []byte("1"), time.Minute); err != nil {
+		t.Fatalf("FileStore.Set(short): want no error, got %v", err)
+	}
+	if err := store.Set("long", []byte("2"), time.Hour); err != nil {
+		t.Fatalf("FileStore.Set(long): want no error, got %v", err)
+	}
+
+	clock.now = clock.now.Add(2 * time.Minute)
+	reloaded, err := NewFileStore(path, clock)
+	if err != nil {
+		t.Fatalf("NewFileStore(%v): want no error, got %v", path, err)
+	}
+	if _, ok, _ := reloaded.Get("short"); ok {
+		t.Errorf("FileStore.Get(short): want expired, got value")
+	}
+	if value, ok, _ := reloaded.Get("long"); !ok || string(value) != "2" {
+		t.Errorf("FileStore.Get(long): want 2, got %v", string(value))
+	}
+}
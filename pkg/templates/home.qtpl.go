@@ -50,27 +50,82 @@ function buildWebhookUrl() {
   return proxyUrl + queryString
 }
 
-function buildAndShowRedirectUrl() {
-  var webhookUrl = buildWebhookUrl();
+// loadHandlers fills the input dropdown from the `)
+	//line home.qtpl:17
+	qw422016.N().S("`")
+	//line home.qtpl:17
+	qw422016.N().S(`/handlers`)
+	//line home.qtpl:17
+	qw422016.N().S("`")
+	//line home.qtpl:17
+	qw422016.N().S(` catalog.
+function loadHandlers() {
+  var request = new XMLHttpRequest();
+  request.open('GET', '/handlers');
+  request.onload = function() {
+    if (request.status !== 200) {
+      return;
+    }
+    var select = document.getElementById('input');
+    var infos = JSON.parse(request.responseText) || [];
+    infos.forEach(function(info) {
+      var option = document.createElement('option');
+      option.value = info.key;
+      option.text = info.messageDirection === 'in' ? info.key + ' (inbound)' : info.key;
+      select.appendChild(option);
+    });
+    buildAndShowRedirectUrl();
+  };
+  request.send();
+}
+
+function showProxyUrl(proxyUrl) {
   var span = document.getElementById('proxyUrl');
 
   while( span.firstChild ) {
     span.removeChild( span.firstChild );
   }
-  span.appendChild( document.createTextNode(webhookUrl) );
+  span.appendChild( document.createTextNode(proxyUrl) );
+}
+
+// buildAndShowRedirectUrl requests a sealed hook URL, which does not
+// reveal the Glip webhook URL. Servers without hook keys return 404,
+// so the URL is built with query parameters instead.
+function buildAndShowRedirectUrl() {
+  var webhookUrl = document.getElementById('webhookUrlOrGuid').value;
+  if (!webhookUrl) {
+    showProxyUrl(buildWebhookUrl());
+    return;
+  }
+  var request = new XMLHttpRequest();
+  request.open('POST', '/hook/e');
+  request.onload = function() {
+    if (request.status === 201) {
+      var url = JSON.parse(request.responseText).url;
+      showProxyUrl(url.charAt(0) === '/' ? window.location.origin + url : url);
+    } else if (request.status === 404) {
+      showProxyUrl(buildWebhookUrl());
+    } else {
+      showProxyUrl('Error: ' + request.responseText);
+    }
+  };
+  request.send(JSON.stringify({
+    inputType: document.getElementById('input').value,
+    token: document.getElementById('token').value,
+    outputs: [{type: 'glip', url: webhookUrl}]}));
 }
 
   </script>
   <body>
     <img src="https://raw.githubusercontent.com/grokify/chathooks/master/docs/logos/logo_chathooks_long_600x150.png" />
     <p><a href="`)
-	//line home.qtpl:40
+	//line home.qtpl:87
 	qw422016.E().S(data.HomeUrl)
-	//line home.qtpl:40
+	//line home.qtpl:87
 	qw422016.N().S(`">`)
-	//line home.qtpl:40
+	//line home.qtpl:87
 	qw422016.E().S(data.HomeUrl)
-	//line home.qtpl:40
+	//line home.qtpl:87
 	qw422016.N().S(`</a></p>
 
     <p>Easily connect your webhooks to <a href="https://glip.com">Glip</a>!</p>
@@ -83,33 +138,7 @@ function buildAndShowRedirectUrl() {
 
     <form action="/button" method="post">
 
-      <p><select id="input" name="source" onchange="buildAndShowWebhookUrl()">
-        <option value="slack">slack (inbound)</option>
-        <option>aha</option>
-        <option>appsignal</option>
-        <option>apteligent</option>
-        <option>circleci</option>
-        <option>codeship</option>
-        <option>confluence</option>
-        <option>datadog</option>
-        <option>deskdotcom</option>
-        <option>enchant</option>
-        <option>gosquared</option>
-        <option>gosquared2</option>
-        <option>heroku</option>
-        <option>librato</option>
-        <option>magnumci</option>
-        <option>marketo</option>
-        <option>opsgenie</option>
-        <option>papertrail</option>
-        <option>pingdom</option>
-        <option>raygun</option>
-        <option>runscope</option>
-        <option>semaphore</option>
-        <option>statuspage</option>
-        <option>travisci</option>
-        <option>userlike</option>
-        <option>victorops</option>
+      <p><select id="input" name="source" onchange="buildAndShowRedirectUrl()">
       </select></p>
 
       <p><input type="text" id="webhookUrlOrGuid" name="webhookUrlOrGuid" value="" placeholder="Your Glip Webhook URL" style="width:400px" onchange="buildAndShowRedirectUrl()" /> Required</p>
@@ -131,35 +160,36 @@ function buildAndShowRedirectUrl() {
     <p><a href="https://github.com/grokify/chathooks">https://github.com/grokify/chathooks</a></p>
   <script>
     buildAndShowRedirectUrl();
+    loadHandlers();
   </script>
   </body>
 </html>
 `)
-//line home.qtpl:103
+//line home.qtpl:125
 }
 
-//line home.qtpl:103
+//line home.qtpl:125
 func WriteHomePage(qq422016 qtio422016.Writer, data HomeData) {
-	//line home.qtpl:103
+	//line home.qtpl:125
 	qw422016 := qt422016.AcquireWriter(qq422016)
-	//line home.qtpl:103
+	//line home.qtpl:125
 	StreamHomePage(qw422016, data)
-	//line home.qtpl:103
+	//line home.qtpl:125
 	qt422016.ReleaseWriter(qw422016)
-//line home.qtpl:103
+//line home.qtpl:125
 }
 
-//line home.qtpl:103
+//line home.qtpl:125
 func HomePage(data HomeData) string {
-	//line home.qtpl:103
+	//line home.qtpl:125
 	qb422016 := qt422016.AcquireByteBuffer()
-	//line home.qtpl:103
+	//line home.qtpl:125
 	WriteHomePage(qb422016, data)
-	//line home.qtpl:103
+	//line home.qtpl:125
 	qs422016 := string(qb422016.B)
-	//line home.qtpl:103
+	//line home.qtpl:125
 	qt422016.ReleaseByteBuffer(qb422016)
-	//line home.qtpl:103
+	//line home.qtpl:125
 	return qs422016
-//line home.qtpl:103
+//line home.qtpl:125
 }
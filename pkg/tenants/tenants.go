@@ -0,0 +1,400 @@
+package tenants
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/ratelimit"
+	"github.com/grokify/chathooks/pkg/redact"
+	"github.com/grokify/chathooks/pkg/routes"
+	"github.com/grokify/chathooks/pkg/state"
+)
+
+// Tenant is a team sharing the instance. Requests with one of its
+// `tokens` may only use its `inputTypes` and `outputs` patterns and its
+// own routes, within its `quota`. Empty `inputTypes` or `outputs`
+// allow all.
+//
+// Output patterns match the webhook URL, `<type>:<channel>` for API
+// adapters, or the name of a named output. `*` matches any text, e.g.
+// `https://hooks.slack.com/services/T0123/*`.
+type Tenant struct {
+	ID         string           `json:"id"`
+	Tokens     []string         `json:"tokens"`
+	InputTypes []string         `json:"inputTypes,omitempty"`
+	Outputs    []string         `json:"outputs,omitempty"`
+	Routes     []*routes.Route  `json:"routes,omitempty"`
+	Quota      *ratelimit.Limit `json:"quota,omitempty"`
+	routeSet   *routes.RouteSet
+	metrics    Metrics
+}
+
+// Metrics counts a tenant's requests by outcome.
+type Metrics struct {
+	Requests      int64 `json:"requests"`
+	Accepted      int64 `json:"accepted"`
+	Failed        int64 `json:"failed"`
+	Rejected      int64 `json:"rejected"`
+	QuotaExceeded int64 `json:"quotaExceeded"`
+}
+
+// Compile checks the tenant and builds its route set. Route names are
+// prefixed with `<id>/` so that state of different tenants does not
+// collide.
+func (t *Tenant) Compile(store state.Store, clock state.Clock) error {
+	t.ID = strings.TrimSpace(t.ID)
+	if len(t.ID) == 0 {
+		return fmt.Errorf("E_TENANT_NO_ID")
+	} else if len(t.Tokens) == 0 {
+		return fmt.Errorf("E_TENANT_NO_TOKENS [%s]", t.ID)
+	}
+	if t.Quota != nil {
+		if err := t.Quota.Compile(); err != nil {
+			return fmt.Errorf("%s [%s]", err.Error(), t.ID)
+		}
+	}
+	t.routeSet = routes.NewRouteSet()
+	t.routeSet.SetState(store, clock)
+	for _, route := range t.Routes {
+		if !strings.HasPrefix(route.Name, t.ID+"/") {
+			route.Name = t.ID + "/" + strings.TrimSpace(route.Name)
+		}
+		if err := t.routeSet.Add(route); err != nil {
+			return err
+		}
+		for _, output := range routeOutputs(route) {
+			if !t.AllowsOutput(OutputTarget(output)) {
+				return fmt.Errorf("E_TENANT_ROUTE_OUTPUT_NOT_ALLOWED [%s]", route.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// routeOutputs returns the outputs a route can select. Request outputs
+// are checked before routing, so these are checked once here.
+func routeOutputs(route *routes.Route) []models.Output {
+	outputs := []models.Output{}
+	for _, rule := range route.Filters.Rules {
+		outputs = append(outputs, rule.Outputs...)
+	}
+	for _, entry := range route.Routing.Entries {
+		outputs = append(outputs, entry.Outputs...)
+	}
+	return append(outputs, route.Routing.Default...)
+}
+
+// RouteSet returns the tenant's routes.
+func (t *Tenant) RouteSet() *routes.RouteSet {
+	return t.routeSet
+}
+
+// LookupRoute returns the tenant route `name`, without the tenant
+// prefix, or the first route for `inputType`.
+func (t *Tenant) LookupRoute(name, inputType string) (*routes.Route, bool) {
+	if len(name) > 0 {
+		name = t.ID + "/" + name
+	}
+	return t.routeSet.Lookup(name, inputType)
+}
+
+// AllowsInputType reports whether the tenant may use `inputType`.
+func (t *Tenant) AllowsInputType(inputType string) bool {
+	if len(t.InputTypes) == 0 {
+		return true
+	}
+	for _, try := range t.InputTypes {
+		if try == inputType {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsOutput reports whether the tenant may send to `target`, a URL,
+// `<type>:<channel>` or output name.
+func (t *Tenant) AllowsOutput(target string) bool {
+	if len(t.Outputs) == 0 {
+		return true
+	}
+	for _, pattern := range t.Outputs {
+		if MatchPattern(pattern, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterOutputs removes the outputs the tenant may not use and returns
+// a 403 for each.
+func (t *Tenant) FilterOutputs(hookData models.HookData) (models.HookData, []models.ErrorInfo) {
+	errs := []models.ErrorInfo{}
+	denied := func() {
+		errs = append(errs, models.ErrorInfo{
+			StatusCode: http.StatusForbidden,
+			Body:       []byte("E_TENANT_OUTPUT_NOT_ALLOWED [" + t.ID + "]")})
+	}
+	if len(hookData.OutputURL) > 0 && !t.AllowsOutput(hookData.OutputURL) {
+		denied()
+		hookData.OutputURL = ""
+	}
+	names := []string{}
+	for _, name := range hookData.OutputNames {
+		if len(name) == 0 {
+			continue
+		} else if t.AllowsOutput(name) {
+			names = append(names, name)
+		} else {
+			denied()
+		}
+	}
+	hookData.OutputNames = names
+	outputs := []models.Output{}
+	for _, output := range hookData.Outputs {
+		if t.AllowsOutput(OutputTarget(output)) {
+			outputs = append(outputs, output)
+		} else {
+			denied()
+		}
+	}
+	hookData.Outputs = outputs
+	return hookData, errs
+}
+
+// Count records the outcome of an admitted request from its
+// responses.
+func (t *Tenant) Count(errs []models.ErrorInfo) {
+	atomic.AddInt64(&t.metrics.Requests, 1)
+	switch status := models.GetMaxStatusCode(errs...); {
+	case status == http.StatusForbidden:
+		atomic.AddInt64(&t.metrics.Rejected, 1)
+	case status >= 300:
+		atomic.AddInt64(&t.metrics.Failed, 1)
+	default:
+		atomic.AddInt64(&t.metrics.Accepted, 1)
+	}
+}
+
+// Metrics returns the tenant's counters.
+func (t *Tenant) Metrics() Metrics {
+	return Metrics{
+		Requests:      atomic.LoadInt64(&t.metrics.Requests),
+		Accepted:      atomic.LoadInt64(&t.metrics.Accepted),
+		Failed:        atomic.LoadInt64(&t.metrics.Failed),
+		Rejected:      atomic.LoadInt64(&t.metrics.Rejected),
+		QuotaExceeded: atomic.LoadInt64(&t.metrics.QuotaExceeded)}
+}
+
+// OutputTarget is the string output patterns are matched against.
+func OutputTarget(output models.Output) string {
+	if len(output.Name) > 0 {
+		return output.Name
+	} else if len(output.URL) > 0 {
+		return output.URL
+	}
+	return output.Type + ":" + output.Channel
+}
+
+// MatchPattern matches `s` against a pattern where `*` matches any
+// text, including `/`.
+func MatchPattern(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	} else if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, last)
+}
+
+// TenantSet holds the tenants by ID and token. It is read-only once
+// built, apart from quotas and metrics.
+type TenantSet struct {
+	tenants map[string]*Tenant
+	tokens  map[string]*Tenant
+	limiter *ratelimit.Limiter
+}
+
+func NewTenantSet(clock state.Clock) *TenantSet {
+	return &TenantSet{
+		tenants: map[string]*Tenant{},
+		tokens:  map[string]*Tenant{},
+		limiter: ratelimit.NewLimiter(clock)}
+}
+
+// ReadTenantSetFile reads a JSON file of the form `{"tenants":[...]}`.
+func ReadTenantSetFile(filepath string, store state.Store, clock state.Clock) (*TenantSet, error) {
+	set := NewTenantSet(clock)
+	bytes, err := ioutil.ReadFile(filepath)
+	if err != nil {
+		return set, err
+	}
+	file := struct {
+		Tenants []*Tenant `json:"tenants"`
+	}{}
+	if err := json.Unmarshal(bytes, &file); err != nil {
+		return set, err
+	}
+	for _, tenant := range file.Tenants {
+		if err := set.Add(tenant, store, clock); err != nil {
+			return set, err
+		}
+	}
+	return set, nil
+}
+
+// Add compiles and adds a tenant. IDs and tokens must be unique.
+func (set *TenantSet) Add(tenant *Tenant, store state.Store, clock state.Clock) error {
+	if err := tenant.Compile(store, clock); err != nil {
+		return err
+	}
+	if _, ok := set.tenants[tenant.ID]; ok {
+		return fmt.Errorf("E_TENANT_ID_IN_USE [%s]", tenant.ID)
+	}
+	for _, token := range tenant.Tokens {
+		if _, ok := set.tokens[strings.TrimSpace(token)]; ok || len(strings.TrimSpace(token)) == 0 {
+			return fmt.Errorf("E_TENANT_TOKEN_INVALID [%s]", tenant.ID)
+		}
+		set.tokens[strings.TrimSpace(token)] = tenant
+	}
+	set.tenants[tenant.ID] = tenant
+	return nil
+}
+
+func (set *TenantSet) Len() int {
+	if set == nil {
+		return 0
+	}
+	return len(set.tenants)
+}
+
+// ForToken returns the tenant owning `token`. Surrounding spaces are
+// ignored, as in the token check.
+func (set *TenantSet) ForToken(token string) (*Tenant, bool) {
+	token = strings.TrimSpace(token)
+	if set == nil || len(token) == 0 {
+		return nil, false
+	}
+	tenant, ok := set.tokens[token]
+	return tenant, ok
+}
+
+// Get returns the tenant `id`.
+func (set *TenantSet) Get(id string) (*Tenant, bool) {
+	if set == nil {
+		return nil, false
+	}
+	tenant, ok := set.tenants[id]
+	return tenant, ok
+}
+
+// List returns the tenants sorted by ID.
+func (set *TenantSet) List() []*Tenant {
+	list := []*Tenant{}
+	if set == nil {
+		return list
+	}
+	for _, tenant := range set.tenants {
+		list = append(list, tenant)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// Admit checks the input type and takes a quota token for a request.
+// It counts and returns a 403 or 429 on rejection.
+func (set *TenantSet) Admit(tenant *Tenant, inputType string) *models.ErrorInfo {
+	if !tenant.AllowsInputType(inputType) {
+		atomic.AddInt64(&tenant.metrics.Requests, 1)
+		atomic.AddInt64(&tenant.metrics.Rejected, 1)
+		return &models.ErrorInfo{
+			StatusCode: http.StatusForbidden,
+			Body:       []byte("E_TENANT_INPUT_TYPE_NOT_ALLOWED [" + inputType + "]")}
+	}
+	if tenant.Quota != nil && !set.limiter.Allow(tenant.ID, tenant.Quota, "", nil) {
+		atomic.AddInt64(&tenant.metrics.Requests, 1)
+		atomic.AddInt64(&tenant.metrics.QuotaExceeded, 1)
+		return &models.ErrorInfo{
+			StatusCode: http.StatusTooManyRequests,
+			Body:       []byte("E_TENANT_QUOTA_EXCEEDED [" + tenant.ID + "]")}
+	}
+	return nil
+}
+
+// Status describes a tenant for the admin endpoint.
+type Status struct {
+	ID         string            `json:"id"`
+	Tokens     int               `json:"tokens"`
+	InputTypes []string          `json:"inputTypes,omitempty"`
+	Outputs    []string          `json:"outputs,omitempty"`
+	Routes     []string          `json:"routes"`
+	Quota      *ratelimit.Status `json:"quota,omitempty"`
+	Metrics    Metrics           `json:"metrics"`
+}
+
+// Statuses returns the status of the tenants sorted by ID. Output
+// URL patterns are masked.
+func (set *TenantSet) Statuses() []Status {
+	quotas := map[string]ratelimit.Status{}
+	if set != nil {
+		for _, quota := range set.limiter.Statuses() {
+			quotas[quota.Key] = quota
+		}
+	}
+	statuses := []Status{}
+	for _, tenant := range set.List() {
+		status := Status{
+			ID:         tenant.ID,
+			Tokens:     len(tenant.Tokens),
+			InputTypes: tenant.InputTypes,
+			Routes:     []string{},
+			Metrics:    tenant.Metrics()}
+		for _, output := range tenant.Outputs {
+			status.Outputs = append(status.Outputs, redact.URL(output))
+		}
+		for _, route := range tenant.routeSet.List() {
+			status.Routes = append(status.Routes, route.Name)
+		}
+		if quota, ok := quotas[tenant.ID]; ok {
+			status.Quota = &quota
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// FlushDigests sends due digests of all tenant routes.
+func (set *TenantSet) FlushDigests(send routes.Sender) {
+	for _, tenant := range set.List() {
+		tenant.routeSet.FlushDigests(send)
+	}
+}
+
+// DrainDigests sends all pending digests of all tenant routes.
+func (set *TenantSet) DrainDigests(send routes.Sender) {
+	for _, tenant := range set.List() {
+		tenant.routeSet.DrainDigests(send)
+	}
+}
+
+// FlushOverflows sends rate limit summaries of all tenant routes.
+func (set *TenantSet) FlushOverflows(send routes.Sender) {
+	for _, tenant := range set.List() {
+		tenant.routeSet.FlushOverflows(send)
+	}
+}
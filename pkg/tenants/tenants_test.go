@@ -0,0 +1,123 @@
+package tenants
+
+import (
+	"fmt"
+	"testing"
+
+	cc "github.com/grokify/commonchat"
+
+	"github.com/grokify/chathooks/pkg/models"
+	"github.com/grokify/chathooks/pkg/ratelimit"
+	"github.com/grokify/chathooks/pkg/routes"
+	"github.com/grokify/chathooks/pkg/rules"
+	"github.com/grokify/chathooks/pkg/state"
+)
+
+var MatchPatternTests = []struct {
+	pattern string
+	s       string
+	want    bool
+}{
+	{"https://hooks.slack.com/services/T1/*", "https://hooks.slack.com/services/T1/B2/x", true},
+	{"https://hooks.slack.com/services/T1/*", "https://hooks.slack.com/services/T2/B2/x", false},
+	{"https://hooks.slack.com/services/T1/*", "https://hooks.slack.com/services/T1", false},
+	{"https://*.glip.com/webhook/*", "https://hooks.glip.com/webhook/abc", true},
+	{"https://*.glip.com/webhook/*", "https://evil.com/?.glip.com/webhook/", true},
+	{"slackbot:C1", "slackbot:C1", true},
+	{"slackbot:C1", "slackbot:C12", false},
+	{"*", "anything", true},
+	{"a*b*c", "abbc", true},
+	{"a*b*c", "acb", false}}
+
+func TestMatchPattern(t *testing.T) {
+	for _, tt := range MatchPatternTests {
+		if got := MatchPattern(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("MatchPattern(%v, %v): want %v, got %v", tt.pattern, tt.s, tt.want, got)
+		}
+	}
+}
+
+func newTestTenantSet(t *testing.T) *TenantSet {
+	set := NewTenantSet(nil)
+	store := state.NewMemoryStore(nil)
+	err := set.Add(&Tenant{
+		ID:         "payments",
+		Tokens:     []string{"pay-token"},
+		InputTypes: []string{"pingdom"},
+		Outputs:    []string{"https://hooks.slack.com/services/T1/*", "slackbot:C1"},
+		Routes:     []*routes.Route{{Name: "ops", InputTypes: []string{"pingdom"}}},
+		Quota:      &ratelimit.Limit{Rate: 2, Per: "1h"}}, store, nil)
+	if err != nil {
+		t.Fatalf("TenantSet.Add(payments): want no error, got %v", err)
+	}
+	return set
+}
+
+var TenantSetAddTests = []struct {
+	tenant  Tenant
+	wantErr string
+}{
+	{Tenant{Tokens: []string{"x"}}, "E_TENANT_NO_ID"},
+	{Tenant{ID: "ops"}, "E_TENANT_NO_TOKENS [ops]"},
+	{Tenant{ID: "payments", Tokens: []string{"x"}}, "E_TENANT_ID_IN_USE [payments]"},
+	{Tenant{ID: "ops", Tokens: []string{"pay-token"}}, "E_TENANT_TOKEN_INVALID [ops]"},
+	{Tenant{ID: "ops", Tokens: []string{"x"}, Outputs: []string{"slackbot:C9"},
+		Routes: []*routes.Route{{Name: "all", Routing: rules.RoutingTable{
+			Default: []models.Output{{Type: "slackbot", Channel: "C1"}}}}}},
+		"E_TENANT_ROUTE_OUTPUT_NOT_ALLOWED [ops/all]"}}
+
+func TestTenantSetAdd(t *testing.T) {
+	set := newTestTenantSet(t)
+	for _, tt := range TenantSetAddTests {
+		tenant := tt.tenant
+		err := set.Add(&tenant, state.NewMemoryStore(nil), nil)
+		if err == nil || err.Error() != tt.wantErr {
+			t.Errorf("TenantSet.Add(%v): want %v, got %v", tt.tenant.ID, tt.wantErr, err)
+		}
+	}
+	if route, ok := set.tenants["payments"].LookupRoute("ops", ""); !ok || route.Name != "payments/ops" {
+		t.Errorf("Tenant.LookupRoute(ops): want payments/ops, got %v", route)
+	}
+}
+
+func TestTenantAdmitAndFilter(t *testing.T) {
+	set := newTestTenantSet(t)
+	tenant, ok := set.ForToken(" pay-token ")
+	if !ok {
+		t.Fatalf("TenantSet.ForToken(pay-token): want tenant, got none")
+	}
+	if info := set.Admit(tenant, "travisci"); info == nil || info.StatusCode != 403 {
+		t.Errorf("TenantSet.Admit(travisci): want 403, got %v", info)
+	}
+	for i, want := range []int{0, 0, 429} {
+		info := set.Admit(tenant, "pingdom")
+		if (info == nil && want != 0) || (info != nil && info.StatusCode != want) {
+			t.Errorf("TenantSet.Admit(pingdom) %v: want %v, got %v", i, want, info)
+		}
+	}
+
+	hookData, errs := tenant.FilterOutputs(models.HookData{
+		OutputType:       "slack",
+		OutputURL:        "http://169.254.169.254/latest",
+		OutputNames:      []string{"", "ops"},
+		CanonicalMessage: cc.NewMessage(),
+		Outputs: []models.Output{
+			{Type: "slack", URL: "https://hooks.slack.com/services/T1/B1/x"},
+			{Type: "slackbot", Channel: "C1"},
+			{Type: "slackbot", Channel: "C2"}}})
+	if len(errs) != 3 || len(hookData.OutputURL) > 0 || len(hookData.OutputNames) > 0 || len(hookData.Outputs) != 2 {
+		t.Errorf("Tenant.FilterOutputs(): want 3 denied and 2 outputs, got %v %v", errs, hookData.Outputs)
+	}
+
+	tenant.Count(errs)
+	want := Metrics{Requests: 3, Rejected: 2, QuotaExceeded: 1}
+	if got := tenant.Metrics(); got != want {
+		t.Errorf("Tenant.Metrics(): want %v, got %v", want, got)
+	}
+
+	statuses := set.Statuses()
+	wantOutputs := "[https://hooks.slack.com/...T1/* slackbot:C1]"
+	if len(statuses) != 1 || fmt.Sprint(statuses[0].Outputs) != wantOutputs || statuses[0].Metrics != want {
+		t.Errorf("TenantSet.Statuses(): want outputs %v, got %v", wantOutputs, statuses)
+	}
+}
@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+)
+
+// DefaultOTLPEndpoint is the local collector's OTLP/HTTP endpoint.
+const DefaultOTLPEndpoint = "http://localhost:4318"
+
+// NewOTLPExporter returns an OTLP/HTTP exporter posting to the
+// `/v1/traces` path of `endpoint`, e.g. of an OpenTelemetry Collector
+// or the Datadog Agent. `headers` are `key=value` pairs, as in
+// `OTEL_EXPORTER_OTLP_HEADERS`.
+func NewOTLPExporter(endpoint string, headers []string) (*otlptrace.Exporter, error) {
+	opts, err := otlpOptions(endpoint, headers)
+	if err != nil {
+		return nil, err
+	}
+	return otlptracehttp.New(context.Background(), opts...)
+}
+
+func otlpOptions(endpoint string, headers []string) ([]otlptracehttp.Option, error) {
+	endpoint = strings.TrimSpace(endpoint)
+	if len(endpoint) == 0 {
+		endpoint = DefaultOTLPEndpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return nil, fmt.Errorf("E_OTLP_ENDPOINT_NOT_VALID [%s]", endpoint)
+	}
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(u.Host),
+		otlptracehttp.WithURLPath(strings.TrimSuffix(u.Path, "/") + "/v1/traces")}
+	if u.Scheme == "http" {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if len(headers) > 0 {
+		values := map[string]string{}
+		for _, header := range headers {
+			parts := strings.SplitN(header, "=", 2)
+			if len(parts) != 2 || len(strings.TrimSpace(parts[0])) == 0 {
+				return nil, fmt.Errorf("E_OTLP_HEADER_NOT_VALID [%s]", parts[0])
+			}
+			val, err := url.QueryUnescape(strings.TrimSpace(parts[1]))
+			if err != nil {
+				return nil, fmt.Errorf("E_OTLP_HEADER_NOT_VALID [%s]", parts[0])
+			}
+			values[strings.TrimSpace(parts[0])] = val
+		}
+		opts = append(opts, otlptracehttp.WithHeaders(values))
+	}
+	return opts, nil
+}
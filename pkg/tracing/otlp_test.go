@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+var otlpExporterTests = []struct {
+	endpoint string
+	headers  []string
+	wantErr  bool
+}{
+	{"", nil, false},
+	{"https://collector:4318/", []string{"api-key=a%20b"}, false},
+	{"collector:4318", nil, true},
+	{"http://agent", []string{"api-key"}, true},
+	{"http://agent", []string{"api-key=%zz"}, true}}
+
+func TestNewOTLPExporter(t *testing.T) {
+	for _, tt := range otlpExporterTests {
+		_, err := NewOTLPExporter(tt.endpoint, tt.headers)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewOTLPExporter(%v, %v): want error %v, got %v", tt.endpoint, tt.headers, tt.wantErr, err)
+		}
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	spans := map[string]*tracepb.Span{}
+	scopes := map[string]bool{}
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/otlp/v1/traces" || r.Header.Get("Api-Key") != "a b" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		req := &collectortrace.ExportTraceServiceRequest{}
+		if err := proto.Unmarshal(body, req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				scopes[ss.Scope.Name] = true
+				for _, span := range ss.Spans {
+					spans[span.Name] = span
+				}
+			}
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer collector.Close()
+
+	exporter, err := NewOTLPExporter(collector.URL+"/otlp/", []string{"api-key=a%20b"})
+	if err != nil {
+		t.Fatalf("NewOTLPExporter(): want no error, got %v", err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracer := provider.Tracer(TracerName)
+	ctx, parent := tracer.Start(context.Background(), "parent", trace.WithSpanKind(trace.SpanKindServer))
+	_, child := tracer.Start(ctx, "child", trace.WithSpanKind(trace.SpanKindClient))
+	SetStatusCode(child, http.StatusBadGateway, 400)
+	child.End()
+	parent.End()
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatalf("TracerProvider.Shutdown(): want no error, got %v", err)
+	}
+
+	if len(spans) != 2 || !scopes[TracerName] {
+		t.Fatalf("OTLP export: want 2 spans of %v, got %v %v", TracerName, scopes, spans)
+	}
+	span := spans["child"]
+	parentID := parent.SpanContext().SpanID().String()
+	if hex.EncodeToString(span.TraceId) != child.SpanContext().TraceID().String() ||
+		hex.EncodeToString(span.ParentSpanId) != parentID ||
+		span.Kind != tracepb.Span_SPAN_KIND_CLIENT ||
+		span.Status.Code != tracepb.Status_STATUS_CODE_ERROR {
+		t.Errorf("OTLP export: want client child of %v with error status, got %v", parentID, span)
+	}
+	if len(span.Attributes) != 1 || span.Attributes[0].Value.GetIntValue() != http.StatusBadGateway {
+		t.Errorf("OTLP export: want status code attribute 502, got %v", span.Attributes)
+	}
+	if spans["parent"].Kind != tracepb.Span_SPAN_KIND_SERVER || len(spans["parent"].ParentSpanId) != 0 {
+		t.Errorf("OTLP export: want root server span, got %v", spans["parent"])
+	}
+}
@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Options configures `Setup`.
+type Options struct {
+	Exporter    string   // `none` (default), `stdout` or `otlp`
+	Endpoint    string   // OTLP/HTTP base URL
+	Headers     []string // OTLP request headers as `key=value`
+	ServiceName string
+	Sync        bool // export each span as it ends, e.g. on serverless engines
+}
+
+// Setup installs the W3C trace context and baggage propagators and, if
+// an exporter is configured, a global tracer provider. Without an
+// exporter, incoming trace context is still propagated to outputs. The
+// returned function flushes and stops the exporter.
+func Setup(opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch strings.ToLower(strings.TrimSpace(opts.Exporter)) {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		stdout, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, err
+		}
+		exporter = stdout
+	case ExporterOTLP:
+		otlp, err := NewOTLPExporter(opts.Endpoint, opts.Headers)
+		if err != nil {
+			return nil, err
+		}
+		exporter = otlp
+	default:
+		return nil, fmt.Errorf("E_TRACE_EXPORTER_NOT_SUPPORTED [%s]", opts.Exporter)
+	}
+
+	serviceName := strings.TrimSpace(opts.ServiceName)
+	if len(serviceName) == 0 {
+		serviceName = "chathooks"
+	}
+	res, err := resource.Merge(resource.Default(),
+		resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return nil, err
+	}
+	processor := sdktrace.NewBatchSpanProcessor(exporter)
+	if opts.Sync {
+		processor = sdktrace.NewSimpleSpanProcessor(exporter)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithResource(res),
+		sdktrace.WithSpanProcessor(processor))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
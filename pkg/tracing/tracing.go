@@ -0,0 +1,93 @@
+// Package tracing records OpenTelemetry spans for hook requests and
+// their deliveries, and propagates the W3C trace context across them.
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation scope of the spans.
+const TracerName = "github.com/grokify/chathooks"
+
+// Span attributes.
+const (
+	AttrInputType  = attribute.Key("chathooks.input_type")
+	AttrTenant     = attribute.Key("chathooks.tenant")
+	AttrRoute      = attribute.Key("chathooks.route")
+	AttrOutputType = attribute.Key("chathooks.output.type")
+	AttrOutputName = attribute.Key("chathooks.output.name")
+	AttrMethod     = attribute.Key("http.request.method")
+	AttrPath       = attribute.Key("url.path")
+	AttrStatusCode = attribute.Key("http.response.status_code")
+)
+
+// Tracer returns the tracer of the global tracer provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// Start starts a span. A nil `ctx` starts a new trace.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// Extract returns `ctx` with the trace context of incoming `headers`,
+// e.g. from `traceparent`.
+func Extract(ctx context.Context, headers http.Header) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(headers))
+}
+
+// InjectFastHTTP sets the trace context headers of `ctx` on an
+// outgoing request.
+func InjectFastHTTP(ctx context.Context, header *fasthttp.RequestHeader) {
+	if ctx == nil {
+		return
+	}
+	otel.GetTextMapPropagator().Inject(ctx, fastHTTPCarrier{header})
+}
+
+// SetStatusCode records an HTTP status code. Codes of `errorFrom` and
+// above mark the span as failed: 500 for server spans and 400 for
+// client spans.
+func SetStatusCode(span trace.Span, statusCode, errorFrom int) {
+	span.SetAttributes(AttrStatusCode.Int(statusCode))
+	if statusCode >= errorFrom {
+		span.SetStatus(codes.Error, http.StatusText(statusCode))
+	}
+}
+
+// fastHTTPCarrier adapts a `fasthttp` request header to
+// `propagation.TextMapCarrier`.
+type fastHTTPCarrier struct {
+	header *fasthttp.RequestHeader
+}
+
+func (c fastHTTPCarrier) Get(key string) string {
+	return string(c.header.Peek(key))
+}
+
+func (c fastHTTPCarrier) Set(key, val string) {
+	c.header.Set(key, val)
+}
+
+func (c fastHTTPCarrier) Keys() []string {
+	keys := []string{}
+	c.header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
@@ -0,0 +1,76 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ShortCommit returns the abbreviated 7 character form of a commit SHA.
+func ShortCommit(commit string) string {
+	if len(commit) < 8 {
+		return commit
+	}
+	return commit[0:7]
+}
+
+// DurationDisplay formats a build duration in seconds as minutes and seconds.
+func DurationDisplay(seconds int64) string {
+	if seconds == 0 {
+		return "0 sec"
+	}
+	dur, err := time.ParseDuration(fmt.Sprintf("%vs", seconds))
+	if err != nil {
+		return "unknown"
+	}
+	modSeconds := math.Mod(float64(seconds), float64(60))
+	return fmt.Sprintf("%v min %v sec", int(dur.Minutes()), modSeconds)
+}
+
+// DurationDisplayTimes formats the duration between two times. It
+// returns an empty string when either time is not set.
+func DurationDisplayTimes(start, end time.Time) string {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return ""
+	}
+	return DurationDisplay(int64(end.Sub(start).Seconds()))
+}
+
+var rxGitSSH = regexp.MustCompile(`^(?:ssh://)?git@([^:/]+)[:/](.+)$`)
+
+// RepositoryWebURL converts a git remote URL such as
+// `git@github.com:org/repo.git` to its `https` web URL.
+func RepositoryWebURL(repoURL string) string {
+	repoURL = strings.TrimSpace(repoURL)
+	if m := rxGitSSH.FindStringSubmatch(repoURL); len(m) > 0 {
+		repoURL = fmt.Sprintf("https://%s/%s", m[1], m[2])
+	}
+	if !strings.HasPrefix(repoURL, "https://") && !strings.HasPrefix(repoURL, "http://") {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimRight(repoURL, "/"), ".git")
+}
+
+// CommitURL returns the web URL for a commit in a repository.
+func CommitURL(repoURL, commit string) string {
+	webURL := RepositoryWebURL(repoURL)
+	if len(webURL) == 0 || len(commit) == 0 {
+		return ""
+	}
+	if strings.Contains(webURL, "bitbucket.org/") {
+		return fmt.Sprintf("%s/commits/%s", webURL, commit)
+	}
+	return fmt.Sprintf("%s/commit/%s", webURL, commit)
+}
+
+// CommitMarkdown returns the short commit linked to its commit URL
+// when one can be built.
+func CommitMarkdown(repoURL, commit string) string {
+	commitURL := CommitURL(repoURL, commit)
+	if len(commitURL) == 0 {
+		return ShortCommit(commit)
+	}
+	return fmt.Sprintf("[%s](%s)", ShortCommit(commit), commitURL)
+}
@@ -0,0 +1,46 @@
+package util
+
+import (
+	"testing"
+)
+
+var DurationDisplayTests = []struct {
+	v    int64
+	want string
+}{
+	{0, "0 sec"},
+	{45, "0 min 45 sec"},
+	{127, "2 min 7 sec"}}
+
+func TestDurationDisplay(t *testing.T) {
+	for _, tt := range DurationDisplayTests {
+		got := DurationDisplay(tt.v)
+		if got != tt.want {
+			t.Errorf("DurationDisplay(%v): want %v, got %v", tt.v, tt.want, got)
+		}
+	}
+}
+
+var CommitMarkdownTests = []struct {
+	repoURL string
+	commit  string
+	want    string
+}{
+	{"https://github.com/grokify/chathooks", "acb5820ced9479c074f688cc328bf03f341a511d",
+		"[acb5820](https://github.com/grokify/chathooks/commit/acb5820ced9479c074f688cc328bf03f341a511d)"},
+	{"https://github.com/grokify/chathooks.git", "acb5820ced94",
+		"[acb5820](https://github.com/grokify/chathooks/commit/acb5820ced94)"},
+	{"git@github.com:grokify/chathooks.git", "acb5820ced94",
+		"[acb5820](https://github.com/grokify/chathooks/commit/acb5820ced94)"},
+	{"git@bitbucket.org:grokify/chathooks.git", "acb5820ced94",
+		"[acb5820](https://bitbucket.org/grokify/chathooks/commits/acb5820ced94)"},
+	{"/var/git/chathooks", "acb5820ced94", "acb5820"}}
+
+func TestCommitMarkdown(t *testing.T) {
+	for _, tt := range CommitMarkdownTests {
+		got := CommitMarkdown(tt.repoURL, tt.commit)
+		if got != tt.want {
+			t.Errorf("CommitMarkdown(%v,%v): want %v, got %v", tt.repoURL, tt.commit, tt.want, got)
+		}
+	}
+}